@@ -0,0 +1,159 @@
+package gateway
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// pathParams возвращает имена параметров пути из шаблона маршрута
+func pathParams(pattern string) []string {
+	var params []string
+	for _, segment := range strings.Split(pattern, "/") {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			params = append(params, strings.Trim(segment, "{}"))
+		}
+	}
+	return params
+}
+
+// bindPath заполняет поля запроса значениями параметров пути
+func bindPath(msg proto.Message, r *http.Request, pattern string) error {
+	for _, name := range pathParams(pattern) {
+		if err := setField(msg, name, r.PathValue(name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// bindQuery заполняет поля запроса значениями параметров строки запроса;
+// поля вложенных сообщений задаются путем через точку, например filter.media_id
+func bindQuery(msg proto.Message, query url.Values) error {
+	for name, values := range query {
+		if len(values) == 0 {
+			continue
+		}
+		if queryField(msg.ProtoReflect().Descriptor(), name) == nil {
+			continue
+		}
+		if err := setField(msg, name, values[len(values)-1]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// findField ищет скалярное поле сообщения по имени в proto или JSON формате
+func findField(msg proto.Message, name string) protoreflect.FieldDescriptor {
+	return scalarField(msg.ProtoReflect().Descriptor(), name)
+}
+
+// scalarField ищет скалярное поле описания сообщения по имени в proto или JSON формате
+func scalarField(md protoreflect.MessageDescriptor, name string) protoreflect.FieldDescriptor {
+	fd := fieldByName(md, name)
+	if fd == nil || fd.IsList() || fd.IsMap() || fd.Message() != nil {
+		return nil
+	}
+	return fd
+}
+
+func fieldByName(md protoreflect.MessageDescriptor, name string) protoreflect.FieldDescriptor {
+	fields := md.Fields()
+	fd := fields.ByName(protoreflect.Name(name))
+	if fd == nil {
+		fd = fields.ByJSONName(name)
+	}
+	return fd
+}
+
+// queryField возвращает цепочку полей от корня сообщения до скалярного поля,
+// заданного именем или путем через вложенные сообщения; nil, если поле не найдено
+func queryField(md protoreflect.MessageDescriptor, path string) []protoreflect.FieldDescriptor {
+	var chain []protoreflect.FieldDescriptor
+	for {
+		name, rest, nested := strings.Cut(path, ".")
+		if !nested {
+			fd := scalarField(md, name)
+			if fd == nil {
+				return nil
+			}
+			return append(chain, fd)
+		}
+
+		fd := fieldByName(md, name)
+		if fd == nil || fd.IsList() || fd.IsMap() || fd.Message() == nil {
+			return nil
+		}
+		if _, ok := wellKnownSchemas[string(fd.Message().FullName())]; ok {
+			return nil
+		}
+		chain = append(chain, fd)
+		md, path = fd.Message(), rest
+	}
+}
+
+// setField присваивает строковое значение скалярному полю сообщения
+func setField(msg proto.Message, name, raw string) error {
+	chain := queryField(msg.ProtoReflect().Descriptor(), name)
+	if chain == nil {
+		return status.Errorf(codes.InvalidArgument, "unknown parameter %q", name)
+	}
+
+	target := msg.ProtoReflect()
+	for _, parent := range chain[:len(chain)-1] {
+		target = target.Mutable(parent).Message()
+	}
+	fd := chain[len(chain)-1]
+
+	var value protoreflect.Value
+	switch fd.Kind() {
+	case protoreflect.StringKind:
+		value = protoreflect.ValueOfString(raw)
+	case protoreflect.BoolKind:
+		v, err := strconv.ParseBool(raw)
+		if err != nil {
+			return invalidParam(name, raw)
+		}
+		value = protoreflect.ValueOfBool(v)
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		v, err := strconv.ParseInt(raw, 10, 32)
+		if err != nil {
+			return invalidParam(name, raw)
+		}
+		value = protoreflect.ValueOfInt32(int32(v))
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		v, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return invalidParam(name, raw)
+		}
+		value = protoreflect.ValueOfInt64(v)
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		v, err := strconv.ParseUint(raw, 10, 32)
+		if err != nil {
+			return invalidParam(name, raw)
+		}
+		value = protoreflect.ValueOfUint32(uint32(v))
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		v, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil {
+			return invalidParam(name, raw)
+		}
+		value = protoreflect.ValueOfUint64(v)
+	default:
+		return status.Errorf(codes.InvalidArgument, "parameter %q cannot be set from URL", name)
+	}
+
+	target.Set(fd, value)
+	return nil
+}
+
+func invalidParam(name, raw string) error {
+	return status.Errorf(codes.InvalidArgument, "invalid value %q for parameter %q", raw, name)
+}
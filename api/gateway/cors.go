@@ -0,0 +1,88 @@
+package gateway
+
+import (
+	"net/http"
+	"strings"
+	"sync/atomic"
+)
+
+const (
+	corsAllowedMethods = "GET, POST, PATCH, DELETE, OPTIONS"
+	corsAllowedHeaders = "Authorization, Content-Type, X-Request-Id, X-User-Id"
+	corsMaxAge         = "600"
+)
+
+// cors обрабатывает CORS заголовки для разрешенных источников
+type cors struct {
+	allowAll bool
+	origins  map[string]struct{}
+}
+
+// newCORS создает обработчик CORS; "*" разрешает любой источник
+func newCORS(allowedOrigins []string) *cors {
+	c := &cors{origins: make(map[string]struct{})}
+	for _, origin := range allowedOrigins {
+		origin = strings.TrimSpace(origin)
+		switch origin {
+		case "":
+		case "*":
+			c.allowAll = true
+		default:
+			c.origins[origin] = struct{}{}
+		}
+	}
+	return c
+}
+
+func (c *cors) allowed(origin string) bool {
+	if c.allowAll {
+		return true
+	}
+	_, ok := c.origins[origin]
+	return ok
+}
+
+// handle добавляет CORS заголовки и возвращает true, если запрос был preflight и уже обработан
+func (c *cors) handle(w http.ResponseWriter, r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" || !c.allowed(origin) {
+		return false
+	}
+
+	h := w.Header()
+	h.Add("Vary", "Origin")
+	if c.allowAll {
+		h.Set("Access-Control-Allow-Origin", "*")
+	} else {
+		h.Set("Access-Control-Allow-Origin", origin)
+	}
+
+	if r.Method != http.MethodOptions || r.Header.Get("Access-Control-Request-Method") == "" {
+		return false
+	}
+
+	h.Set("Access-Control-Allow-Methods", corsAllowedMethods)
+	h.Set("Access-Control-Allow-Headers", corsAllowedHeaders)
+	h.Set("Access-Control-Max-Age", corsMaxAge)
+	w.WriteHeader(http.StatusNoContent)
+	return true
+}
+
+// corsPolicy позволяет заменять список разрешенных источников на лету
+type corsPolicy struct {
+	current atomic.Pointer[cors]
+}
+
+func newCORSPolicy(allowedOrigins []string) *corsPolicy {
+	p := &corsPolicy{}
+	p.set(allowedOrigins)
+	return p
+}
+
+func (p *corsPolicy) set(allowedOrigins []string) {
+	p.current.Store(newCORS(allowedOrigins))
+}
+
+func (p *corsPolicy) handle(w http.ResponseWriter, r *http.Request) bool {
+	return p.current.Load().handle(w, r)
+}
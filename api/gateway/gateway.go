@@ -0,0 +1,404 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/watchlist-kata/protos/review"
+	reviewv2 "github.com/watchlist-kata/review/api/proto/review/v2"
+	"github.com/watchlist-kata/review/internal/config"
+	"github.com/watchlist-kata/review/internal/featureflag"
+	"github.com/watchlist-kata/review/internal/metrics"
+	"github.com/watchlist-kata/review/internal/service"
+	"github.com/watchlist-kata/review/internal/tracing"
+	"github.com/watchlist-kata/review/pkg/logger"
+)
+
+const (
+	maxBodySize = 1 << 20 // Ограничение размера тела запроса
+
+	updateMaskParam = "update_mask"  // Параметр маски обновления
+	pageSizeParam   = "page_size"    // Параметр размера страницы
+	pageTokenParam  = "page_token"   // Параметр токена страницы
+	userIDHeader    = "X-User-Id"    // Заголовок с ID пользователя для флагов функциональности
+	requestIDHeader = "X-Request-Id" // Заголовок с ID запроса; при отсутствии ID генерируется
+)
+
+var (
+	marshalOptions   = protojson.MarshalOptions{UseProtoNames: true, EmitUnpopulated: true}
+	unmarshalOptions = protojson.UnmarshalOptions{}
+)
+
+// route описывает REST-метод, отображаемый на метод ReviewService
+type route struct {
+	method      string               // HTTP метод
+	pattern     string               // Шаблон пути в формате http.ServeMux
+	service     string               // Полное имя gRPC сервиса
+	rpc         string               // Имя метода сервиса
+	hasBody     bool                 // Поля запроса передаются в теле
+	status      int                  // HTTP статус успешного ответа
+	updateMask  bool                 // Передает в сервис маску обновления
+	paginated   bool                 // Возвращает страницу с next_page_token
+	description string               // Описание операции в OpenAPI документе
+	newRequest  func() proto.Message // Создает пустое сообщение запроса
+	newReply    func() proto.Message // Создает пустое сообщение ответа
+	call        func(context.Context, *Gateway, proto.Message) (proto.Message, error)
+}
+
+// newRoute создает описание маршрута для метода review.ReviewService
+func newRoute[Req, Resp proto.Message](method, pattern, rpc string, hasBody bool, status int,
+	call func(review.ReviewServiceServer, context.Context, Req) (Resp, error)) route {
+	return makeRoute(method, pattern, review.ReviewService_ServiceDesc.ServiceName, rpc, hasBody, status,
+		func(ctx context.Context, g *Gateway, req Req) (Resp, error) {
+			return call(g.srv, ctx, req)
+		})
+}
+
+// newRouteV2 создает описание маршрута для метода review.v2.ReviewService
+func newRouteV2[Req, Resp proto.Message](method, pattern, rpc string, hasBody bool, status int,
+	call func(reviewv2.ReviewServiceServer, context.Context, Req) (Resp, error)) route {
+	return makeRoute(method, pattern, reviewv2.ReviewService_ServiceDesc.ServiceName, rpc, hasBody, status,
+		func(ctx context.Context, g *Gateway, req Req) (Resp, error) {
+			return call(g.srvV2, ctx, req)
+		})
+}
+
+func makeRoute[Req, Resp proto.Message](method, pattern, service, rpc string, hasBody bool, status int,
+	call func(context.Context, *Gateway, Req) (Resp, error)) route {
+	return route{
+		method:  method,
+		pattern: pattern,
+		service: service,
+		rpc:     rpc,
+		hasBody: hasBody,
+		status:  status,
+		newRequest: func() proto.Message {
+			var req Req
+			return req.ProtoReflect().Type().New().Interface()
+		},
+		newReply: func() proto.Message {
+			var resp Resp
+			return resp.ProtoReflect().Type().New().Interface()
+		},
+		call: func(ctx context.Context, g *Gateway, req proto.Message) (proto.Message, error) {
+			return call(ctx, g, req.(Req))
+		},
+	}
+}
+
+// withUpdateMask включает передачу маски обновления: из параметра update_mask
+// или, если он не задан, из полей, присутствующих в теле запроса
+func (rt route) withUpdateMask() route {
+	rt.updateMask = true
+	return rt
+}
+
+// withPagination отмечает маршрут, возвращающий страницу: page_size и page_token
+// передаются в запросе, токен следующей страницы - в поле next_page_token ответа
+func (rt route) withPagination() route {
+	rt.paginated = true
+	return rt
+}
+
+// withDescription задает описание операции в OpenAPI документе
+func (rt route) withDescription(description string) route {
+	rt.description = description
+	return rt
+}
+
+// routes содержит все REST-маршруты сервиса
+var routes = []route{
+	newRoute(http.MethodPost, "/v1/reviews", "Create", true, http.StatusCreated, review.ReviewServiceServer.Create),
+	newRoute(http.MethodGet, "/v1/reviews", "GetAll", false, http.StatusOK, review.ReviewServiceServer.GetAll),
+	newRoute(http.MethodGet, "/v1/reviews/{id}", "GetByID", false, http.StatusOK, review.ReviewServiceServer.GetByID),
+	newRoute(http.MethodPatch, "/v1/reviews/{id}", "Update", true, http.StatusOK, review.ReviewServiceServer.Update).withUpdateMask(),
+	newRoute(http.MethodDelete, "/v1/reviews/{id}", "Delete", false, http.StatusOK, review.ReviewServiceServer.Delete).
+		withDescription("Soft delete: the review is no longer returned, but stays in the database as a deletion marker for the v2 change feed (SyncChanges, WatchReviews)."),
+	newRoute(http.MethodGet, "/v1/ratings/{rating}/reviews", "GetByRating", false, http.StatusOK, review.ReviewServiceServer.GetByRating),
+	newRoute(http.MethodGet, "/v1/users/{user_id}/reviews", "GetByUser", false, http.StatusOK, review.ReviewServiceServer.GetByUser),
+	newRoute(http.MethodGet, "/v1/media/{media_id}/reviews", "GetByMedia", false, http.StatusOK, review.ReviewServiceServer.GetByMedia),
+	newRouteV2(http.MethodGet, "/v2/reviews", "ListReviews", false, http.StatusOK, reviewv2.ReviewServiceServer.ListReviews).withPagination().
+		withDescription("Keyset-paginated list of reviews matching the filter, ordered by id. " +
+			"Pass next_page_token from the response as " + pageTokenParam + " to fetch the next page; it is empty on the last page. " +
+			"Use this route instead of the unpaginated v1 list routes for large result sets."),
+}
+
+// Gateway обслуживает REST/JSON API поверх ReviewService
+type Gateway struct {
+	srv    review.ReviewServiceServer
+	srvV2  reviewv2.ReviewServiceServer
+	logger *slog.Logger
+	mux    *http.ServeMux
+	cors   *corsPolicy
+}
+
+// New создает REST шлюз для указанных реализаций ReviewService v1 и v2
+func New(srv review.ReviewServiceServer, srvV2 reviewv2.ReviewServiceServer, cfg *config.Config, logger *slog.Logger) (*Gateway, error) {
+	g := &Gateway{
+		srv:    srv,
+		srvV2:  srvV2,
+		logger: logger,
+		mux:    http.NewServeMux(),
+		cors:   newCORSPolicy(cfg.CORSAllowedOrigins),
+	}
+
+	allowed := make(map[string][]string)
+	for _, rt := range routes {
+		g.mux.Handle(rt.method+" "+rt.pattern, g.handler(rt))
+		allowed[rt.pattern] = append(allowed[rt.pattern], rt.method)
+	}
+
+	openAPI, err := openAPIHandler()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build OpenAPI document: %w", err)
+	}
+	g.mux.Handle(http.MethodGet+" "+OpenAPIPath, openAPI)
+	allowed[OpenAPIPath] = append(allowed[OpenAPIPath], http.MethodGet)
+
+	// Известный путь с другим методом: шаблон без метода менее специфичен, чем маршруты,
+	// и перехватывает только запросы, которые не подошли ни одному из них
+	for pattern, methods := range allowed {
+		g.mux.Handle(pattern, methodNotAllowed(methods))
+	}
+	g.mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, status.Error(codes.NotFound, "route not found"))
+	})
+
+	return g, nil
+}
+
+// SetAllowedOrigins заменяет список источников, которым разрешены CORS запросы
+func (g *Gateway) SetAllowedOrigins(origins []string) {
+	g.cors.set(origins)
+}
+
+// ServeHTTP реализует http.Handler
+func (g *Gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if g.cors.handle(w, r) {
+		return
+	}
+	g.mux.ServeHTTP(w, r)
+}
+
+// handler создает обработчик HTTP запроса для маршрута
+func (g *Gateway) handler(rt route) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		w := &statusRecorder{ResponseWriter: rw, code: http.StatusOK}
+		defer func(start time.Time) {
+			metrics.HTTPHandlingSeconds.WithLabelValues(rt.rpc, strconv.Itoa(w.code)).Observe(time.Since(start).Seconds())
+		}(time.Now())
+
+		// Серверный span с родительским контекстом из HTTP заголовков
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx, span := tracing.Tracer().Start(ctx, rt.method+" "+rt.pattern, trace.WithSpanKind(trace.SpanKindServer))
+		defer span.End()
+		r = r.WithContext(ctx)
+
+		// ID пользователя передается сервису так же, как его передают gRPC клиенты
+		if userID := r.Header.Get(userIDHeader); userID != "" {
+			ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(featureflag.UserIDMetadataKey, userID))
+			ctx = logger.WithUserID(ctx, userID)
+		}
+
+		// Поля логов запроса, как у gRPC вызовов
+		requestID := r.Header.Get(requestIDHeader)
+		if requestID == "" {
+			requestID = logger.NewRequestID()
+		}
+		w.Header().Set(requestIDHeader, requestID)
+		ctx = logger.WithRequestID(ctx, requestID)
+		ctx = logger.WithMethod(ctx, "/"+rt.service+"/"+rt.rpc)
+
+		req := rt.newRequest()
+
+		var body []byte
+		if rt.hasBody {
+			var err error
+			body, err = io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodySize))
+			if err != nil {
+				writeError(w, status.Errorf(codes.InvalidArgument, "failed to read request body: %v", err))
+				return
+			}
+			if len(body) > 0 {
+				if err := unmarshalOptions.Unmarshal(body, req); err != nil {
+					writeError(w, status.Errorf(codes.InvalidArgument, "invalid request body: %v", err))
+					return
+				}
+			}
+		}
+
+		if rt.updateMask {
+			paths, err := updateMaskPaths(req, r, body, rt.pattern)
+			if err != nil {
+				writeError(w, err)
+				return
+			}
+			md, _ := metadata.FromIncomingContext(ctx)
+			ctx = metadata.NewIncomingContext(ctx, metadata.Join(md, metadata.Pairs(service.UpdateMaskMetadataKey, strings.Join(paths, ","))))
+		}
+
+		if err := bindQuery(req, r.URL.Query()); err != nil {
+			writeError(w, err)
+			return
+		}
+		if err := bindPath(req, r, rt.pattern); err != nil {
+			writeError(w, err)
+			return
+		}
+
+		resp, err := rt.call(ctx, g, req)
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(otelcodes.Error, err.Error())
+			g.logger.WarnContext(ctx, "REST request failed", slog.String("path", r.URL.Path), slog.Any("error", err))
+			writeError(w, err)
+			return
+		}
+
+		writeMessage(w, rt.status, resp)
+	})
+}
+
+// methodNotAllowed отвечает 405 с заголовком Allow для пути, у которого нет маршрута с методом запроса
+func methodNotAllowed(methods []string) http.Handler {
+	allow := strings.Join(methods, ", ")
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Allow", allow)
+		writeStatus(w, http.StatusMethodNotAllowed,
+			status.Newf(codes.Unimplemented, "method %s is not allowed for %s; allowed: %s", r.Method, r.URL.Path, allow))
+	})
+}
+
+// updateMaskPaths возвращает пути маски обновления из параметра update_mask
+// или имена полей верхнего уровня, присутствующих в теле запроса
+func updateMaskPaths(req proto.Message, r *http.Request, body []byte, pattern string) ([]string, error) {
+	if raw := r.URL.Query().Get(updateMaskParam); raw != "" {
+		return strings.Split(raw, ","), nil
+	}
+
+	var fields map[string]json.RawMessage
+	if len(body) > 0 {
+		if err := json.Unmarshal(body, &fields); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid request body: %v", err)
+		}
+	}
+
+	pathFields := make(map[string]bool)
+	for _, name := range pathParams(pattern) {
+		pathFields[name] = true
+	}
+
+	paths := make([]string, 0, len(fields))
+	for key := range fields {
+		fd := findField(req, key)
+		if fd == nil || pathFields[string(fd.Name())] {
+			continue
+		}
+		paths = append(paths, string(fd.Name()))
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// statusRecorder запоминает HTTP статус ответа для метрик
+type statusRecorder struct {
+	http.ResponseWriter
+	code int
+}
+
+func (r *statusRecorder) WriteHeader(code int) {
+	r.code = code
+	r.ResponseWriter.WriteHeader(code)
+}
+
+// writeMessage сериализует сообщение в JSON и записывает его в ответ
+func writeMessage(w http.ResponseWriter, code int, msg proto.Message) {
+	payload, err := marshalOptions.Marshal(msg)
+	if err != nil {
+		writeError(w, status.Errorf(codes.Internal, "failed to marshal response: %v", err))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(payload)
+}
+
+// writeError записывает ошибку в формате google.rpc.Status с HTTP статусом, соответствующим gRPC коду
+func writeError(w http.ResponseWriter, err error) {
+	st, ok := status.FromError(err)
+	if !ok {
+		st = status.New(codes.Internal, "internal error")
+	}
+	writeStatus(w, HTTPStatusFromCode(st.Code()), st)
+}
+
+// writeStatus записывает статус в формате google.rpc.Status с указанным HTTP статусом
+func writeStatus(w http.ResponseWriter, code int, st *status.Status) {
+	payload, mErr := marshalOptions.Marshal(st.Proto())
+	if mErr != nil {
+		payload = []byte(`{"code":13,"message":"internal error","details":[]}`)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(payload)
+}
+
+// HTTPStatusFromCode возвращает HTTP статус, соответствующий gRPC коду
+func HTTPStatusFromCode(code codes.Code) int {
+	switch code {
+	case codes.OK:
+		return http.StatusOK
+	case codes.Canceled:
+		return 499 // Client Closed Request
+	case codes.Unknown:
+		return http.StatusInternalServerError
+	case codes.InvalidArgument:
+		return http.StatusBadRequest
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.AlreadyExists:
+		return http.StatusConflict
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.FailedPrecondition:
+		return http.StatusBadRequest
+	case codes.Aborted:
+		return http.StatusConflict
+	case codes.OutOfRange:
+		return http.StatusBadRequest
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	case codes.Internal:
+		return http.StatusInternalServerError
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	case codes.DataLoss:
+		return http.StatusInternalServerError
+	default:
+		return http.StatusInternalServerError
+	}
+}
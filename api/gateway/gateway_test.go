@@ -0,0 +1,338 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/watchlist-kata/protos/review"
+	reviewv2 "github.com/watchlist-kata/review/api/proto/review/v2"
+	"github.com/watchlist-kata/review/internal/config"
+	"github.com/watchlist-kata/review/internal/service"
+)
+
+// fakeService запоминает последний запрос и метаданные вызова и возвращает err, если он задан
+type fakeService struct {
+	review.UnimplementedReviewServiceServer
+
+	req proto.Message
+	md  metadata.MD
+	err error
+}
+
+// fakeServiceV2 обслуживает маршруты review.v2 тем же fakeService
+type fakeServiceV2 struct {
+	reviewv2.UnimplementedReviewServiceServer
+	*fakeService
+}
+
+func (f *fakeService) record(ctx context.Context, req proto.Message) error {
+	f.req = req
+	f.md, _ = metadata.FromIncomingContext(ctx)
+	return f.err
+}
+
+func (f *fakeService) Create(ctx context.Context, req *review.CreateReviewRequest) (*review.CreateReviewResponse, error) {
+	if err := f.record(ctx, req); err != nil {
+		return nil, err
+	}
+	return &review.CreateReviewResponse{Review: &review.Review{Id: 1, MediaId: req.MediaId, UserId: req.UserId, Content: req.Content, Rating: req.Rating}}, nil
+}
+
+func (f *fakeService) GetByID(ctx context.Context, req *review.GetReviewRequest) (*review.GetReviewResponse, error) {
+	if err := f.record(ctx, req); err != nil {
+		return nil, err
+	}
+	return &review.GetReviewResponse{Review: &review.Review{Id: req.Id}}, nil
+}
+
+func (f *fakeService) Update(ctx context.Context, req *review.UpdateReviewRequest) (*review.UpdateReviewResponse, error) {
+	if err := f.record(ctx, req); err != nil {
+		return nil, err
+	}
+	return &review.UpdateReviewResponse{Review: &review.Review{Id: req.Id}}, nil
+}
+
+func (f *fakeService) GetByUser(ctx context.Context, req *review.GetByUserRequest) (*review.GetByUserResponse, error) {
+	if err := f.record(ctx, req); err != nil {
+		return nil, err
+	}
+	return &review.GetByUserResponse{}, nil
+}
+
+func (f fakeServiceV2) ListReviews(ctx context.Context, req *reviewv2.ListReviewsRequest) (*reviewv2.ListReviewsResponse, error) {
+	if err := f.record(ctx, req); err != nil {
+		return nil, err
+	}
+	return &reviewv2.ListReviewsResponse{NextPageToken: "next"}, nil
+}
+
+func newTestGateway(t *testing.T, srv *fakeService, origins ...string) *Gateway {
+	t.Helper()
+	g, err := New(srv, fakeServiceV2{fakeService: srv}, &config.Config{CORSAllowedOrigins: origins}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return g
+}
+
+func serve(g *Gateway, method, target, body string, header http.Header) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	for key, values := range header {
+		r.Header[key] = values
+	}
+	w := httptest.NewRecorder()
+	g.ServeHTTP(w, r)
+	return w
+}
+
+func TestRouteBinding(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		target     string
+		body       string
+		wantStatus int
+		want       proto.Message
+	}{
+		{
+			name:       "path parameter",
+			method:     http.MethodGet,
+			target:     "/v1/users/7/reviews",
+			wantStatus: http.StatusOK,
+			want:       &review.GetByUserRequest{UserId: 7},
+		},
+		{
+			name:       "body on create",
+			method:     http.MethodPost,
+			target:     "/v1/reviews",
+			body:       `{"media_id":"3","userId":4,"content":"good","rating":8}`,
+			wantStatus: http.StatusCreated,
+			want:       &review.CreateReviewRequest{MediaId: 3, UserId: 4, Content: "good", Rating: 8},
+		},
+		{
+			name:       "path parameter overrides body",
+			method:     http.MethodPatch,
+			target:     "/v1/reviews/9",
+			body:       `{"id":"5","rating":6}`,
+			wantStatus: http.StatusOK,
+			want:       &review.UpdateReviewRequest{Id: 9, Rating: 6},
+		},
+		{
+			name:       "nested query parameters",
+			method:     http.MethodGet,
+			target:     "/v2/reviews?filter.media_id=5&filter.rating=7&page_size=2&page_token=abc&unknown=1",
+			wantStatus: http.StatusOK,
+			want: &reviewv2.ListReviewsRequest{
+				Filter:    &reviewv2.ReviewFilter{MediaId: proto.Int64(5), Rating: proto.Int32(7)},
+				PageSize:  2,
+				PageToken: "abc",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := &fakeService{}
+			w := serve(newTestGateway(t, srv), tt.method, tt.target, tt.body, nil)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d; body %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if !proto.Equal(srv.req, tt.want) {
+				t.Errorf("request = %v, want %v", srv.req, tt.want)
+			}
+			if w.Header().Get(requestIDHeader) == "" {
+				t.Errorf("response has no %s header", requestIDHeader)
+			}
+		})
+	}
+}
+
+func TestListReviewsReturnsNextPageToken(t *testing.T) {
+	w := serve(newTestGateway(t, &fakeService{}), http.MethodGet, "/v2/reviews?page_size=1", "", nil)
+
+	var body map[string]any
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("invalid JSON response: %v", err)
+	}
+	if body["next_page_token"] != "next" {
+		t.Errorf("next_page_token = %v, want next", body["next_page_token"])
+	}
+}
+
+func TestRouteRejectsInvalidParameters(t *testing.T) {
+	for _, target := range []string{"/v1/users/abc/reviews", "/v2/reviews?page_size=x", "/v2/reviews?filter.rating=high"} {
+		srv := &fakeService{}
+		w := serve(newTestGateway(t, srv), http.MethodGet, target, "", nil)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("GET %s: status = %d, want 400", target, w.Code)
+		}
+		if srv.req != nil {
+			t.Errorf("GET %s: service was called", target)
+		}
+	}
+}
+
+func TestUpdateMaskFromBody(t *testing.T) {
+	tests := []struct {
+		name   string
+		target string
+		body   string
+		want   string
+	}{
+		{name: "body keys", target: "/v1/reviews/1", body: `{"rating":3,"content":""}`, want: "content,rating"},
+		{name: "JSON names and path field skipped", target: "/v1/reviews/1", body: `{"id":"1","content":"x"}`, want: "content"},
+		{name: "explicit update_mask", target: "/v1/reviews/1?update_mask=rating", body: `{"rating":3,"content":"x"}`, want: "rating"},
+		{name: "empty body", target: "/v1/reviews/1", body: ``, want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := &fakeService{}
+			w := serve(newTestGateway(t, srv), http.MethodPatch, tt.target, tt.body, nil)
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d, body %s", w.Code, w.Body)
+			}
+			if got := srv.md.Get(service.UpdateMaskMetadataKey); len(got) != 1 || got[0] != tt.want {
+				t.Errorf("%s = %q, want %q", service.UpdateMaskMetadataKey, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHTTPStatusFromCode(t *testing.T) {
+	tests := map[codes.Code]int{
+		codes.OK:                 http.StatusOK,
+		codes.Canceled:           499,
+		codes.InvalidArgument:    http.StatusBadRequest,
+		codes.FailedPrecondition: http.StatusBadRequest,
+		codes.OutOfRange:         http.StatusBadRequest,
+		codes.DeadlineExceeded:   http.StatusGatewayTimeout,
+		codes.NotFound:           http.StatusNotFound,
+		codes.AlreadyExists:      http.StatusConflict,
+		codes.Aborted:            http.StatusConflict,
+		codes.PermissionDenied:   http.StatusForbidden,
+		codes.Unauthenticated:    http.StatusUnauthorized,
+		codes.ResourceExhausted:  http.StatusTooManyRequests,
+		codes.Unimplemented:      http.StatusNotImplemented,
+		codes.Unavailable:        http.StatusServiceUnavailable,
+		codes.Internal:           http.StatusInternalServerError,
+		codes.Unknown:            http.StatusInternalServerError,
+		codes.DataLoss:           http.StatusInternalServerError,
+	}
+	for code, want := range tests {
+		if got := HTTPStatusFromCode(code); got != want {
+			t.Errorf("HTTPStatusFromCode(%s) = %d, want %d", code, got, want)
+		}
+	}
+}
+
+func TestErrorBody(t *testing.T) {
+	for _, code := range []codes.Code{codes.InvalidArgument, codes.NotFound, codes.Unavailable, codes.Internal} {
+		srv := &fakeService{err: status.Error(code, "review failed")}
+		w := serve(newTestGateway(t, srv), http.MethodGet, "/v1/reviews/1", "", nil)
+
+		if w.Code != HTTPStatusFromCode(code) {
+			t.Errorf("%s: status = %d, want %d", code, w.Code, HTTPStatusFromCode(code))
+		}
+		if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+			t.Errorf("%s: Content-Type = %q", code, ct)
+		}
+
+		var body map[string]any
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatalf("%s: invalid JSON error body: %v", code, err)
+		}
+		if len(body) != 3 || body["code"] != float64(code) || body["message"] != "review failed" {
+			t.Errorf("%s: body = %v, want code, message and details", code, body)
+		}
+		if _, ok := body["details"].([]any); !ok {
+			t.Errorf("%s: details = %v, want an array", code, body["details"])
+		}
+	}
+}
+
+func TestErrorBodyHidesNonStatusErrors(t *testing.T) {
+	srv := &fakeService{err: io.ErrUnexpectedEOF}
+	w := serve(newTestGateway(t, srv), http.MethodGet, "/v1/reviews/1", "", nil)
+
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d, want 500", w.Code)
+	}
+	if strings.Contains(w.Body.String(), io.ErrUnexpectedEOF.Error()) {
+		t.Errorf("body leaks the error text: %s", w.Body)
+	}
+}
+
+func TestCORS(t *testing.T) {
+	g := newTestGateway(t, &fakeService{}, "https://app.example.com")
+	preflight := func(origin string) *httptest.ResponseRecorder {
+		return serve(g, http.MethodOptions, "/v1/reviews", "", http.Header{
+			"Origin":                        {origin},
+			"Access-Control-Request-Method": {http.MethodPost},
+		})
+	}
+
+	w := preflight("https://app.example.com")
+	if w.Code != http.StatusNoContent {
+		t.Errorf("allowed preflight: status = %d, want 204", w.Code)
+	}
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "https://app.example.com" {
+		t.Errorf("allowed preflight: Access-Control-Allow-Origin = %q", got)
+	}
+	if got := w.Header().Get("Access-Control-Allow-Methods"); !strings.Contains(got, http.MethodPatch) {
+		t.Errorf("allowed preflight: Access-Control-Allow-Methods = %q", got)
+	}
+
+	w = preflight("https://evil.example.com")
+	if w.Code == http.StatusNoContent || w.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Errorf("disallowed preflight: status = %d, Access-Control-Allow-Origin = %q", w.Code, w.Header().Get("Access-Control-Allow-Origin"))
+	}
+
+	// Простой запрос с разрешенного источника обрабатывается с CORS заголовком
+	w = serve(g, http.MethodGet, "/v1/reviews/1", "", http.Header{"Origin": {"https://app.example.com"}})
+	if w.Code != http.StatusOK || w.Header().Get("Access-Control-Allow-Origin") != "https://app.example.com" {
+		t.Errorf("allowed request: status = %d, Access-Control-Allow-Origin = %q", w.Code, w.Header().Get("Access-Control-Allow-Origin"))
+	}
+
+	// После замены списка источников прежний источник запрещен
+	g.SetAllowedOrigins([]string{"https://other.example.com"})
+	if w := preflight("https://app.example.com"); w.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Errorf("preflight after SetAllowedOrigins: Access-Control-Allow-Origin = %q", w.Header().Get("Access-Control-Allow-Origin"))
+	}
+}
+
+func TestMethodNotAllowed(t *testing.T) {
+	g := newTestGateway(t, &fakeService{})
+
+	w := serve(g, http.MethodPut, "/v1/reviews/1", "{}", nil)
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("status = %d, want 405", w.Code)
+	}
+	allow := strings.Split(w.Header().Get("Allow"), ", ")
+	sort.Strings(allow)
+	if strings.Join(allow, ",") != "DELETE,GET,PATCH" {
+		t.Errorf("Allow = %q, want DELETE, GET and PATCH", w.Header().Get("Allow"))
+	}
+
+	var body map[string]any
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil || body["code"] != float64(codes.Unimplemented) {
+		t.Errorf("body = %s, want a google.rpc.Status with UNIMPLEMENTED", w.Body)
+	}
+
+	if w := serve(g, http.MethodGet, "/v1/unknown", "", nil); w.Code != http.StatusNotFound {
+		t.Errorf("unknown path: status = %d, want 404", w.Code)
+	}
+}
@@ -0,0 +1,299 @@
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	spb "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/watchlist-kata/review/internal/service"
+)
+
+// OpenAPIPath путь, по которому отдается OpenAPI документ
+const OpenAPIPath = "/openapi.json"
+
+// errorSchema имя схемы ответа с ошибкой
+var errorSchema = string((&spb.Status{}).ProtoReflect().Descriptor().FullName())
+
+// openAPIDocument строит OpenAPI 3 документ по таблице маршрутов и описаниям proto сообщений
+func openAPIDocument() map[string]any {
+	schemas := make(map[string]any)
+	paths := make(map[string]map[string]any)
+
+	addSchema(schemas, (&spb.Status{}).ProtoReflect().Descriptor())
+
+	for _, rt := range routes {
+		req := rt.newRequest().ProtoReflect().Descriptor()
+		resp := rt.newReply().ProtoReflect().Descriptor()
+		addSchema(schemas, resp)
+
+		operation := map[string]any{
+			"operationId": rt.rpc,
+			"tags":        []string{rt.service},
+			"parameters":  operationParameters(rt, req),
+			"responses": map[string]any{
+				strconv.Itoa(rt.status): map[string]any{
+					"description": responseDescription(rt),
+					"headers":     responseHeaders(),
+					"content":     jsonContent(schemaRef(resp)),
+				},
+				"default": map[string]any{
+					"description": "Error response in google.rpc.Status format",
+					"content":     jsonContent(refTo(errorSchema)),
+				},
+			},
+		}
+		if rt.updateMask {
+			operation["description"] = updateMaskDescription
+		}
+		if rt.description != "" {
+			operation["description"] = rt.description
+		}
+		if rt.hasBody {
+			addSchema(schemas, req)
+			operation["requestBody"] = map[string]any{
+				"required": true,
+				"content":  jsonContent(schemaRef(req)),
+			}
+		}
+
+		if paths[rt.pattern] == nil {
+			paths[rt.pattern] = make(map[string]any)
+		}
+		paths[rt.pattern][strings.ToLower(rt.method)] = operation
+	}
+
+	return map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":   "Review API",
+			"version": "v1",
+		},
+		"paths": paths,
+		"components": map[string]any{
+			"schemas": schemas,
+		},
+	}
+}
+
+// updateMaskDescription описывает частичное обновление и передачу маски в gRPC API
+const updateMaskDescription = "Partial update: only the fields listed in " + updateMaskParam + " are written, " +
+	"so a listed field with an empty value is cleared. Without " + updateMaskParam + " the fields present in the request body are written. " +
+	"Unknown or immutable paths are rejected with INVALID_ARGUMENT. " +
+	"gRPC clients of review.ReviewService pass the same comma-separated paths in the " + service.UpdateMaskMetadataKey + " request metadata " +
+	"(without it only non-empty fields are written); review.v2.ReviewService accepts them in UpdateReviewRequest.update_mask."
+
+// operationParameters описывает параметры пути и строки запроса маршрута
+func operationParameters(rt route, req protoreflect.MessageDescriptor) []any {
+	params := make([]any, 0)
+	inPath := make(map[string]bool)
+
+	for _, name := range pathParams(rt.pattern) {
+		inPath[name] = true
+		fd := req.Fields().ByName(protoreflect.Name(name))
+		params = append(params, map[string]any{
+			"name":     name,
+			"in":       "path",
+			"required": true,
+			"schema":   fieldSchema(fd),
+		})
+	}
+
+	if !rt.hasBody {
+		params = appendQueryParameters(params, rt, req, "", inPath)
+	}
+
+	if rt.updateMask {
+		params = append(params, map[string]any{
+			"name":        updateMaskParam,
+			"in":          "query",
+			"description": "Comma-separated field paths to update; defaults to the fields present in the request body",
+			"schema":      map[string]any{"type": "string"},
+		})
+	}
+
+	return params
+}
+
+// appendQueryParameters добавляет параметры строки запроса для скалярных полей сообщения;
+// поля вложенных сообщений описываются путем через точку, например filter.media_id
+func appendQueryParameters(params []any, rt route, md protoreflect.MessageDescriptor, prefix string, inPath map[string]bool) []any {
+	fields := md.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		name := prefix + string(fd.Name())
+		if inPath[name] || fd.IsList() || fd.IsMap() {
+			continue
+		}
+		if fd.Message() != nil {
+			if _, ok := wellKnownSchemas[string(fd.Message().FullName())]; ok || fd.Message().FullName() == md.FullName() {
+				continue
+			}
+			params = appendQueryParameters(params, rt, fd.Message(), name+".", inPath)
+			continue
+		}
+
+		param := map[string]any{
+			"name":   name,
+			"in":     "query",
+			"schema": fieldSchema(fd),
+		}
+		if rt.paginated {
+			switch name {
+			case pageSizeParam:
+				param["description"] = fmt.Sprintf("Maximum number of items to return; defaults to %d, at most %d", service.DefaultPageSize, service.MaxPageSize)
+				param["schema"] = map[string]any{"type": "integer", "format": "int32", "minimum": 0, "maximum": service.MaxPageSize}
+			case pageTokenParam:
+				param["description"] = "next_page_token from the previous response; empty for the first page"
+			}
+		}
+		params = append(params, param)
+	}
+	return params
+}
+
+// responseDescription описывает успешный ответ маршрута
+func responseDescription(rt route) string {
+	if rt.paginated {
+		return "Page of results; next_page_token is the " + pageTokenParam + " of the next page and is empty on the last page"
+	}
+	return "Successful response"
+}
+
+// responseHeaders описывает заголовки успешного ответа
+func responseHeaders() map[string]any {
+	return map[string]any{
+		requestIDHeader: map[string]any{
+			"description": "ID of the request from the " + requestIDHeader + " request header, or a generated one",
+			"schema":      map[string]any{"type": "string"},
+		},
+	}
+}
+
+// addSchema добавляет схему сообщения и всех вложенных сообщений
+func addSchema(schemas map[string]any, md protoreflect.MessageDescriptor) {
+	name := string(md.FullName())
+	if _, ok := schemas[name]; ok {
+		return
+	}
+	if _, ok := wellKnownSchemas[name]; ok {
+		return
+	}
+
+	properties := make(map[string]any)
+	schemas[name] = map[string]any{
+		"type":       "object",
+		"properties": properties,
+	}
+
+	fields := md.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		properties[string(fd.Name())] = fieldSchema(fd)
+
+		if fd.IsMap() {
+			fd = fd.MapValue()
+		}
+		if fd.Message() != nil {
+			addSchema(schemas, fd.Message())
+		}
+	}
+}
+
+// fieldSchema возвращает схему поля в соответствии с правилами protojson
+func fieldSchema(fd protoreflect.FieldDescriptor) map[string]any {
+	switch {
+	case fd.IsMap():
+		return map[string]any{
+			"type":                 "object",
+			"additionalProperties": singularSchema(fd.MapValue()),
+		}
+	case fd.IsList():
+		return map[string]any{
+			"type":  "array",
+			"items": singularSchema(fd),
+		}
+	default:
+		return singularSchema(fd)
+	}
+}
+
+// wellKnownSchemas содержит схемы стандартных типов protobuf в JSON представлении
+var wellKnownSchemas = map[string]map[string]any{
+	"google.protobuf.Timestamp": {"type": "string", "format": "date-time"},
+	"google.protobuf.Duration":  {"type": "string"},
+	"google.protobuf.FieldMask": {"type": "string"},
+	"google.protobuf.Any": {
+		"type":                 "object",
+		"properties":           map[string]any{"@type": map[string]any{"type": "string"}},
+		"additionalProperties": true,
+	},
+}
+
+func singularSchema(fd protoreflect.FieldDescriptor) map[string]any {
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		return map[string]any{"type": "boolean"}
+	case protoreflect.StringKind:
+		return map[string]any{"type": "string"}
+	case protoreflect.BytesKind:
+		return map[string]any{"type": "string", "format": "byte"}
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		return map[string]any{"type": "integer", "format": "int32"}
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		return map[string]any{"type": "integer", "format": "int64", "minimum": 0}
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		return map[string]any{"type": "string", "format": "int64"}
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return map[string]any{"type": "string", "format": "uint64"}
+	case protoreflect.FloatKind:
+		return map[string]any{"type": "number", "format": "float"}
+	case protoreflect.DoubleKind:
+		return map[string]any{"type": "number", "format": "double"}
+	case protoreflect.EnumKind:
+		values := fd.Enum().Values()
+		names := make([]string, 0, values.Len())
+		for i := 0; i < values.Len(); i++ {
+			names = append(names, string(values.Get(i).Name()))
+		}
+		return map[string]any{"type": "string", "enum": names}
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return schemaRef(fd.Message())
+	default:
+		return map[string]any{}
+	}
+}
+
+// schemaRef возвращает ссылку на схему сообщения или встроенную схему стандартного типа
+func schemaRef(md protoreflect.MessageDescriptor) map[string]any {
+	if schema, ok := wellKnownSchemas[string(md.FullName())]; ok {
+		return schema
+	}
+	return refTo(string(md.FullName()))
+}
+
+func refTo(name string) map[string]any {
+	return map[string]any{"$ref": "#/components/schemas/" + name}
+}
+
+func jsonContent(schema map[string]any) map[string]any {
+	return map[string]any{
+		"application/json": map[string]any{"schema": schema},
+	}
+}
+
+// openAPIHandler отдает заранее сериализованный OpenAPI документ
+func openAPIHandler() (http.Handler, error) {
+	payload, err := json.MarshalIndent(openAPIDocument(), "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(payload)
+	}), nil
+}
@@ -0,0 +1,248 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/watchlist-kata/review/internal/service"
+)
+
+// fetchOpenAPI возвращает OpenAPI документ в том виде, в котором его отдает шлюз
+func fetchOpenAPI(t *testing.T) map[string]any {
+	t.Helper()
+	w := serve(newTestGateway(t, &fakeService{}), http.MethodGet, OpenAPIPath, "", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET %s: status = %d", OpenAPIPath, w.Code)
+	}
+	var doc map[string]any
+	if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("invalid OpenAPI JSON: %v", err)
+	}
+	return doc
+}
+
+// object возвращает вложенный объект документа по пути из ключей или завершает тест
+func object(t *testing.T, v any, keys ...string) map[string]any {
+	t.Helper()
+	for _, key := range keys {
+		m, ok := v.(map[string]any)
+		if !ok {
+			t.Fatalf("%s: parent is %T, not an object", strings.Join(keys, "."), v)
+		}
+		v = m[key]
+	}
+	m, ok := v.(map[string]any)
+	if !ok {
+		t.Fatalf("%s is %T, not an object", strings.Join(keys, "."), v)
+	}
+	return m
+}
+
+// resolve возвращает схему, на которую ссылается $ref
+func resolve(t *testing.T, doc, schema map[string]any) map[string]any {
+	t.Helper()
+	ref, ok := schema["$ref"].(string)
+	if !ok {
+		t.Fatalf("schema %v has no $ref", schema)
+	}
+	name, ok := strings.CutPrefix(ref, "#/components/schemas/")
+	if !ok {
+		t.Fatalf("$ref %q does not point to components.schemas", ref)
+	}
+	return object(t, doc, "components", "schemas", name)
+}
+
+// propertyNames возвращает отсортированные имена свойств схемы
+func propertyNames(t *testing.T, schema map[string]any) []string {
+	t.Helper()
+	var names []string
+	for name := range object(t, schema, "properties") {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// fieldNames возвращает отсортированные имена полей proto сообщения
+func fieldNames(md protoreflect.MessageDescriptor) []string {
+	var names []string
+	for i := 0; i < md.Fields().Len(); i++ {
+		names = append(names, string(md.Fields().Get(i).Name()))
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestOpenAPIDocumentListsEveryRoute(t *testing.T) {
+	doc := fetchOpenAPI(t)
+	paths := object(t, doc, "paths")
+
+	operations := 0
+	for _, item := range paths {
+		operations += len(item.(map[string]any))
+	}
+	if operations != len(routes) {
+		t.Errorf("document has %d operations, want %d routes", operations, len(routes))
+	}
+
+	for _, rt := range routes {
+		name := rt.method + " " + rt.pattern
+		op := object(t, paths, rt.pattern, strings.ToLower(rt.method))
+		if op["operationId"] != rt.rpc {
+			t.Errorf("%s: operationId = %v, want %s", name, op["operationId"], rt.rpc)
+		}
+		if tags, _ := op["tags"].([]any); len(tags) != 1 || tags[0] != rt.service {
+			t.Errorf("%s: tags = %v, want [%s]", name, op["tags"], rt.service)
+		}
+
+		// Каждый параметр пути объявлен обязательным параметром in: path
+		declared := make(map[string]bool)
+		for _, p := range op["parameters"].([]any) {
+			param := p.(map[string]any)
+			if param["in"] == "path" && param["required"] == true {
+				declared[param["name"].(string)] = true
+			}
+		}
+		for _, param := range pathParams(rt.pattern) {
+			if !declared[param] {
+				t.Errorf("%s: path parameter %s is not declared", name, param)
+			}
+		}
+	}
+}
+
+func TestOpenAPISchemasResolve(t *testing.T) {
+	doc := fetchOpenAPI(t)
+	paths := object(t, doc, "paths")
+
+	for _, rt := range routes {
+		name := rt.method + " " + rt.pattern
+		op := object(t, paths, rt.pattern, strings.ToLower(rt.method))
+
+		// Схема успешного ответа описывает все поля ответа RPC
+		reply := object(t, op, "responses", strconv.Itoa(rt.status), "content", "application/json", "schema")
+		md := rt.newReply().ProtoReflect().Descriptor()
+		if got, want := propertyNames(t, resolve(t, doc, reply)), fieldNames(md); strings.Join(got, ",") != strings.Join(want, ",") {
+			t.Errorf("%s: response properties = %v, want %v", name, got, want)
+		}
+
+		body, hasBody := op["requestBody"]
+		if hasBody != rt.hasBody {
+			t.Errorf("%s: requestBody present = %v, want %v", name, hasBody, rt.hasBody)
+			continue
+		}
+		if hasBody {
+			req := object(t, body, "content", "application/json", "schema")
+			md := rt.newRequest().ProtoReflect().Descriptor()
+			if got, want := propertyNames(t, resolve(t, doc, req)), fieldNames(md); strings.Join(got, ",") != strings.Join(want, ",") {
+				t.Errorf("%s: request properties = %v, want %v", name, got, want)
+			}
+		}
+	}
+
+	// Все ссылки документа, включая ссылки вложенных схем, указывают на существующие схемы
+	var walk func(v any)
+	walk = func(v any) {
+		switch v := v.(type) {
+		case map[string]any:
+			if _, ok := v["$ref"]; ok {
+				resolve(t, doc, v)
+			}
+			for _, child := range v {
+				walk(child)
+			}
+		case []any:
+			for _, child := range v {
+				walk(child)
+			}
+		}
+	}
+	walk(doc)
+}
+
+func TestOpenAPIErrorSchemaMatchesErrorBody(t *testing.T) {
+	doc := fetchOpenAPI(t)
+
+	schema := object(t, doc, "components", "schemas", errorSchema)
+	for _, rt := range routes {
+		op := object(t, doc, "paths", rt.pattern, strings.ToLower(rt.method))
+		ref := object(t, op, "responses", "default", "content", "application/json", "schema")
+		if ref["$ref"] != "#/components/schemas/"+errorSchema {
+			t.Errorf("%s %s: default response schema = %v, want %s", rt.method, rt.pattern, ref["$ref"], errorSchema)
+		}
+	}
+
+	// Тело ошибки шлюза содержит ровно свойства схемы ошибки
+	srv := &fakeService{err: status.Error(codes.NotFound, "review not found")}
+	w := serve(newTestGateway(t, srv), http.MethodGet, "/v1/reviews/1", "", nil)
+	var body map[string]any
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("invalid JSON error body: %v", err)
+	}
+	var keys []string
+	for key := range body {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	if got, want := strings.Join(keys, ","), strings.Join(propertyNames(t, schema), ","); got != want {
+		t.Errorf("error body keys = %s, want schema properties %s", got, want)
+	}
+
+	// Типы свойств соответствуют значениям тела
+	properties := object(t, schema, "properties")
+	if object(t, properties, "code")["type"] != "integer" || object(t, properties, "message")["type"] != "string" ||
+		object(t, properties, "details")["type"] != "array" {
+		t.Errorf("error schema properties = %v, want integer code, string message and array details", properties)
+	}
+	if _, ok := body["code"].(float64); !ok {
+		t.Errorf("error body code = %v, want a number", body["code"])
+	}
+}
+
+func TestOpenAPIListReviewsPagination(t *testing.T) {
+	doc := fetchOpenAPI(t)
+	op := object(t, doc, "paths", "/v2/reviews", "get")
+
+	params := make(map[string]map[string]any)
+	for _, p := range op["parameters"].([]any) {
+		param := p.(map[string]any)
+		if param["in"] == "query" {
+			params[param["name"].(string)] = param
+		}
+	}
+
+	pageSize, ok := params[pageSizeParam]
+	if !ok {
+		t.Fatalf("GET /v2/reviews has no %s query parameter", pageSizeParam)
+	}
+	schema := object(t, pageSize, "schema")
+	if schema["type"] != "integer" || schema["minimum"] != float64(0) || schema["maximum"] != float64(service.MaxPageSize) {
+		t.Errorf("%s schema = %v, want an integer between 0 and %d", pageSizeParam, schema, service.MaxPageSize)
+	}
+	if desc, _ := pageSize["description"].(string); !strings.Contains(desc, strconv.Itoa(service.DefaultPageSize)) {
+		t.Errorf("%s description = %q, want the default page size", pageSizeParam, desc)
+	}
+
+	pageToken, ok := params[pageTokenParam]
+	if !ok {
+		t.Fatalf("GET /v2/reviews has no %s query parameter", pageTokenParam)
+	}
+	if object(t, pageToken, "schema")["type"] != "string" {
+		t.Errorf("%s schema = %v, want a string", pageTokenParam, pageToken["schema"])
+	}
+
+	// Фильтр передается вложенными параметрами строки запроса
+	for _, name := range []string{"filter.media_id", "filter.user_id"} {
+		if _, ok := params[name]; !ok {
+			t.Errorf("GET /v2/reviews has no %s query parameter", name)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package server
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/watchlist-kata/review/internal/featureflag"
+	"github.com/watchlist-kata/review/pkg/logger"
+)
+
+// requestIDMetadataKey ключ метаданных с ID запроса; при отсутствии ID генерируется
+// и возвращается клиенту в заголовках ответа
+const requestIDMetadataKey = "x-request-id"
+
+// logContext добавляет в контекст поля логов запроса: ID запроса, метод и ID пользователя
+func logContext(ctx context.Context, method string) (context.Context, string) {
+	requestID := ""
+	if values := metadata.ValueFromIncomingContext(ctx, requestIDMetadataKey); len(values) > 0 {
+		requestID = values[0]
+	}
+	if requestID == "" {
+		requestID = logger.NewRequestID()
+	}
+
+	ctx = logger.WithRequestID(ctx, requestID)
+	ctx = logger.WithMethod(ctx, method)
+	if userID, ok := featureflag.UserID(ctx); ok {
+		ctx = logger.WithUserID(ctx, userID)
+	}
+	return ctx, requestID
+}
+
+// logContextUnaryInterceptor добавляет поля логов в контекст unary запросов
+func logContextUnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, requestID := logContext(ctx, info.FullMethod)
+		_ = grpc.SetHeader(ctx, metadata.Pairs(requestIDMetadataKey, requestID))
+		return handler(ctx, req)
+	}
+}
+
+// logContextStreamInterceptor добавляет поля логов в контекст потоковых запросов
+func logContextStreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, requestID := logContext(ss.Context(), info.FullMethod)
+		_ = ss.SetHeader(metadata.Pairs(requestIDMetadataKey, requestID))
+		return handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// contextStream подменяет контекст потока
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
@@ -2,25 +2,45 @@ package server
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net"
+	"net/http"
+	"time"
 
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 
 	"github.com/watchlist-kata/protos/review"
+	"github.com/watchlist-kata/review/api/gateway"
+	adminv1 "github.com/watchlist-kata/review/api/proto/admin/v1"
+	reviewv2 "github.com/watchlist-kata/review/api/proto/review/v2"
+	"github.com/watchlist-kata/review/internal/admin"
 	"github.com/watchlist-kata/review/internal/config"
+	"github.com/watchlist-kata/review/internal/featureflag"
+	"github.com/watchlist-kata/review/internal/metrics"
 	"github.com/watchlist-kata/review/internal/repository"
 	"github.com/watchlist-kata/review/internal/service"
 )
 
-// RunServer запускает gRPC сервер
-func RunServer(ctx context.Context, cfg *config.Config, logger *slog.Logger) error {
+// RunServer запускает gRPC сервер и, если задан HTTP_PORT, REST шлюз. Параметры
+// запуска берутся из активной конфигурации store, перезагружаемые параметры
+// применяются к работающим компонентам при ее изменении. levels позволяет менять
+// уровни приемников логов через служебный API, который обслуживается отдельным
+// gRPC сервером на ADMIN_PORT и недоступен через публичный порт.
+// После отмены ctx серверы останавливаются в пределах SHUTDOWN_TIMEOUT, и RunServer
+// возвращает nil; ошибка возвращается, только если серверы не удалось запустить.
+func RunServer(ctx context.Context, store *config.Store, logger *slog.Logger, levels admin.LogLevels) error {
+	cfg := store.Current()
+
 	// Проверка отмены контекста
 	select {
 	case <-ctx.Done():
-		logger.Error("server initialization canceled", slog.Any("error", ctx.Err()))
-		return ctx.Err()
+		logger.Info("server initialization canceled", slog.Any("reason", ctx.Err()))
+		return nil
 	default:
 	}
 
@@ -31,14 +51,61 @@ func RunServer(ctx context.Context, cfg *config.Config, logger *slog.Logger) err
 		return fmt.Errorf("failed to create repository: %w", err)
 	}
 
+	// Флаги функциональности обновляются при перезагрузке конфигурации и изменении файла флагов
+	flags, err := featureflag.New(cfg)
+	if err != nil {
+		logger.Error("failed to load feature flags", slog.Any("error", err))
+		return fmt.Errorf("failed to load feature flags: %w", err)
+	}
+	store.Subscribe(func(cfg *config.Config) {
+		if err := flags.Apply(cfg); err != nil {
+			logger.Error("failed to apply feature flags", slog.Any("error", err))
+		}
+	})
+	go flags.Watch(ctx, logger)
+
 	// Создание сервиса
-	srv := service.NewReviewService(repo, logger)
+	srv := service.NewReviewService(repo, logger, flags)
+
+	// Очистка меток удаления старше TOMBSTONE_RETENTION
+	if cfg.TombstoneRetention > 0 {
+		go srv.CompactChanges(ctx, cfg.TombstoneRetention)
+	}
+
+	// Настройка TLS, если заданы сертификат и ключ
+	opts := []grpc.ServerOption{
+		grpc.StatsHandler(otelgrpc.NewServerHandler()),
+		grpc.ChainUnaryInterceptor(metrics.UnaryServerInterceptor(), logContextUnaryInterceptor()),
+		grpc.ChainStreamInterceptor(metrics.StreamServerInterceptor(), logContextStreamInterceptor()),
+	}
+	// REST шлюз получает отдельную конфигурацию TLS: клиентский сертификат, обязательный
+	// для gRPC при заданном CA, для HTTP проверяется согласно HTTP_CLIENT_AUTH
+	var httpTLSConfig *tls.Config
+	if cfg.TLSCertFile != "" {
+		httpClientAuth, err := parseClientAuth(cfg.HTTPClientAuth)
+		if err != nil {
+			return err
+		}
+		reloader, err := newCertReloader(cfg, logger)
+		if err != nil {
+			logger.Error("failed to load TLS certificates", slog.Any("error", err))
+			return fmt.Errorf("failed to load TLS certificates: %w", err)
+		}
+		go reloader.Watch(ctx)
+
+		httpTLSConfig = reloader.TLSConfig(httpClientAuth)
+		opts = append(opts, grpc.Creds(credentials.NewTLS(reloader.TLSConfig(tls.RequireAndVerifyClientCert))))
+		logger.Info("TLS enabled for gRPC server", slog.Bool("mtls", cfg.TLSClientCAFile != ""), slog.String("min_version", cfg.TLSMinVersion),
+			slog.String("http_client_auth", cfg.HTTPClientAuth))
+	}
 
 	// Создание gRPC сервера
-	grpcServer := grpc.NewServer()
+	grpcServer := grpc.NewServer(opts...)
 
-	// Регистрация сервиса
+	// Регистрация сервиса: v1 и v2 обслуживаются одним ядром
+	srvV2 := service.NewReviewServiceV2(srv)
 	review.RegisterReviewServiceServer(grpcServer, srv)
+	reviewv2.RegisterReviewServiceServer(grpcServer, srvV2)
 
 	// Запуск сервера
 	lis, err := net.Listen("tcp", cfg.GRPCPort)
@@ -57,8 +124,126 @@ func RunServer(ctx context.Context, cfg *config.Config, logger *slog.Logger) err
 		}
 	}()
 
+	// Запуск служебного API: отдельный сервер на ADMIN_PORT, по умолчанию только localhost.
+	// Вызывающий не проверяется, поэтому доступ ограничивается адресом прослушивания,
+	// а при mTLS - клиентским сертификатом
+	var adminServer *grpc.Server
+	if cfg.AdminPort != "" {
+		adminLis, err := net.Listen("tcp", cfg.AdminPort)
+		if err != nil {
+			logger.Error("failed to listen on admin port", slog.String("port", cfg.AdminPort), slog.Any("error", err))
+			return fmt.Errorf("failed to listen on admin port %s: %w", cfg.AdminPort, err)
+		}
+		if !isLoopback(adminLis.Addr()) && cfg.TLSClientCAFile == "" {
+			logger.Warn("admin API is reachable beyond localhost without client certificates", slog.String("port", cfg.AdminPort))
+		}
+
+		adminServer = grpc.NewServer(opts...)
+		adminv1.RegisterAdminServiceServer(adminServer, admin.NewServer(store, levels, logger))
+
+		logger.Info("admin API listening on port", slog.String("port", cfg.AdminPort))
+		go func() {
+			if err := adminServer.Serve(adminLis); err != nil {
+				logger.Error("failed to serve admin API", slog.Any("error", err))
+			}
+		}()
+	}
+
+	// Запуск REST шлюза
+	var httpServer *http.Server
+	if cfg.HTTPPort != "" {
+		gw, err := gateway.New(srv, srvV2, cfg, logger)
+		if err != nil {
+			logger.Error("failed to create REST gateway", slog.Any("error", err))
+			return fmt.Errorf("failed to create REST gateway: %w", err)
+		}
+		store.Subscribe(func(cfg *config.Config) {
+			gw.SetAllowedOrigins(cfg.CORSAllowedOrigins)
+		})
+
+		httpServer = &http.Server{
+			Addr:              cfg.HTTPPort,
+			Handler:           gw,
+			TLSConfig:         httpTLSConfig,
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+
+		logger.Info("REST gateway listening on port", slog.String("port", cfg.HTTPPort))
+		go func() {
+			var err error
+			if httpTLSConfig != nil {
+				err = httpServer.ListenAndServeTLS("", "")
+			} else {
+				err = httpServer.ListenAndServe()
+			}
+			if err != nil && !errors.Is(err, http.ErrServerClosed) {
+				logger.Error("failed to serve REST gateway", slog.Any("error", err))
+			}
+		}()
+	}
+
+	// Запуск эндпоинта метрик
+	var metricsServer *http.Server
+	if cfg.MetricsPort != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", metrics.Handler())
+		metricsServer = &http.Server{
+			Addr:              cfg.MetricsPort,
+			Handler:           mux,
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+
+		logger.Info("metrics endpoint listening on port", slog.String("port", cfg.MetricsPort))
+		go func() {
+			if err := metricsServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				logger.Error("failed to serve metrics endpoint", slog.Any("error", err))
+			}
+		}()
+	}
+
 	// Ожидание завершения контекста
 	<-ctx.Done()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), store.Current().ShutdownTimeout)
+	defer cancel()
+	if metricsServer != nil {
+		if err := metricsServer.Shutdown(shutdownCtx); err != nil {
+			logger.Error("failed to shut down metrics endpoint", slog.Any("error", err))
+		}
+	}
+	if httpServer != nil {
+		if err := httpServer.Shutdown(shutdownCtx); err != nil {
+			logger.Error("failed to shut down REST gateway", slog.Any("error", err))
+		}
+	}
+	if adminServer != nil {
+		gracefulStop(shutdownCtx, adminServer, logger)
+	}
+	gracefulStop(shutdownCtx, grpcServer, logger)
+
 	logger.Info("server stopped due to context cancellation")
-	return ctx.Err()
+	return nil
+}
+
+// gracefulStop дожидается завершения активных вызовов до истечения shutdownCtx,
+// после чего закрывает оставшиеся соединения, например открытые потоки WatchReviews
+func gracefulStop(shutdownCtx context.Context, srv *grpc.Server, logger *slog.Logger) {
+	stopped := make(chan struct{})
+	go func() {
+		srv.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-shutdownCtx.Done():
+		logger.Warn("graceful stop timed out, closing remaining connections")
+		srv.Stop()
+		<-stopped
+	}
+}
+
+// isLoopback сообщает, принимает ли адрес прослушивания только локальные соединения
+func isLoopback(addr net.Addr) bool {
+	tcpAddr, ok := addr.(*net.TCPAddr)
+	return ok && tcpAddr.IP.IsLoopback()
 }
@@ -0,0 +1,200 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/watchlist-kata/review/internal/config"
+)
+
+// certReloadInterval задает период проверки файлов сертификатов на изменения
+const certReloadInterval = 10 * time.Second
+
+// certReloader хранит актуальные сертификат сервера и CA клиентов
+// и перечитывает их при изменении файлов на диске
+type certReloader struct {
+	certFile     string
+	keyFile      string
+	clientCAFile string
+	minVersion   uint16
+	logger       *slog.Logger
+
+	mu        sync.RWMutex
+	cert      *tls.Certificate
+	clientCAs *x509.CertPool
+	modTimes  map[string]time.Time
+}
+
+// newCertReloader загружает сертификаты из файлов, указанных в конфигурации
+func newCertReloader(cfg *config.Config, logger *slog.Logger) (*certReloader, error) {
+	minVersion, err := parseTLSVersion(cfg.TLSMinVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &certReloader{
+		certFile:     cfg.TLSCertFile,
+		keyFile:      cfg.TLSKeyFile,
+		clientCAFile: cfg.TLSClientCAFile,
+		minVersion:   minVersion,
+		logger:       logger,
+	}
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// TLSConfig возвращает конфигурацию TLS, которая при каждом рукопожатии использует актуальные
+// сертификаты. clientAuth задает проверку клиентских сертификатов и применяется, только если
+// задан CA клиентов; без него клиентские сертификаты не запрашиваются.
+func (r *certReloader) TLSConfig(clientAuth tls.ClientAuthType) *tls.Config {
+	return &tls.Config{
+		MinVersion: r.minVersion,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			return r.configForClient(clientAuth), nil
+		},
+	}
+}
+
+func (r *certReloader) configForClient(clientAuth tls.ClientAuthType) *tls.Config {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	cfg := &tls.Config{
+		MinVersion:   r.minVersion,
+		Certificates: []tls.Certificate{*r.cert},
+		NextProtos:   []string{"h2", "http/1.1"},
+	}
+	if r.clientCAs != nil {
+		cfg.ClientCAs = r.clientCAs
+		cfg.ClientAuth = clientAuth
+	}
+	return cfg
+}
+
+// Watch периодически проверяет файлы сертификатов и перечитывает их при изменении
+func (r *certReloader) Watch(ctx context.Context) {
+	ticker := time.NewTicker(certReloadInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.check()
+		}
+	}
+}
+
+// check перечитывает сертификаты, если файлы изменились; при ошибке остаются прежние
+func (r *certReloader) check() {
+	changed, err := r.changed()
+	if err != nil {
+		r.logger.Error("failed to check TLS certificate files", slog.Any("error", err))
+		return
+	}
+	if !changed {
+		return
+	}
+	if err := r.reload(); err != nil {
+		r.logger.Error("failed to reload TLS certificates, keeping previous ones", slog.Any("error", err))
+		return
+	}
+	r.logger.Info("TLS certificates reloaded")
+}
+
+// files возвращает список отслеживаемых файлов
+func (r *certReloader) files() []string {
+	files := []string{r.certFile, r.keyFile}
+	if r.clientCAFile != "" {
+		files = append(files, r.clientCAFile)
+	}
+	return files
+}
+
+// changed сообщает, изменилось ли время модификации хотя бы одного файла
+func (r *certReloader) changed() (bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, file := range r.files() {
+		info, err := os.Stat(file)
+		if err != nil {
+			return false, err
+		}
+		if !info.ModTime().Equal(r.modTimes[file]) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// reload читает сертификат, ключ и CA клиентов и атомарно заменяет текущие
+func (r *certReloader) reload() error {
+	modTimes := make(map[string]time.Time)
+	for _, file := range r.files() {
+		info, err := os.Stat(file)
+		if err != nil {
+			return fmt.Errorf("failed to stat %s: %w", file, err)
+		}
+		modTimes[file] = info.ModTime()
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load TLS key pair: %w", err)
+	}
+
+	var clientCAs *x509.CertPool
+	if r.clientCAFile != "" {
+		pem, err := os.ReadFile(r.clientCAFile)
+		if err != nil {
+			return fmt.Errorf("failed to read client CA file: %w", err)
+		}
+		clientCAs = x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no valid certificates found in client CA file %s", r.clientCAFile)
+		}
+	}
+
+	r.mu.Lock()
+	r.cert = &cert
+	r.clientCAs = clientCAs
+	r.modTimes = modTimes
+	r.mu.Unlock()
+	return nil
+}
+
+// parseTLSVersion преобразует строковое значение версии TLS в константу crypto/tls
+func parseTLSVersion(version string) (uint16, error) {
+	switch version {
+	case "", "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	default:
+		return 0, fmt.Errorf("unsupported TLS version %q: must be 1.2 or 1.3", version)
+	}
+}
+
+// parseClientAuth преобразует значение HTTP_CLIENT_AUTH в режим проверки клиентских сертификатов
+func parseClientAuth(mode string) (tls.ClientAuthType, error) {
+	switch mode {
+	case "", "none":
+		return tls.NoClientCert, nil
+	case "verify-if-given":
+		return tls.VerifyClientCertIfGiven, nil
+	case "require":
+		return tls.RequireAndVerifyClientCert, nil
+	default:
+		return 0, fmt.Errorf("unsupported client auth mode %q: must be none, verify-if-given or require", mode)
+	}
+}
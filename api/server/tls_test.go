@@ -0,0 +1,285 @@
+package server
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"log/slog"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/watchlist-kata/review/internal/config"
+)
+
+// writeCert создает самоподписанный сертификат с именем commonName и его ключ в dir
+func writeCert(t *testing.T, dir, name, commonName string) (certFile, keyFile string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("CreateCertificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("MarshalECPrivateKey: %v", err)
+	}
+
+	certFile = filepath.Join(dir, name+".crt")
+	keyFile = filepath.Join(dir, name+".key")
+	writePEM(t, certFile, "CERTIFICATE", der)
+	writePEM(t, keyFile, "EC PRIVATE KEY", keyDER)
+	return certFile, keyFile
+}
+
+func writePEM(t *testing.T, path, blockType string, der []byte) {
+	t.Helper()
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+}
+
+func newTestReloader(t *testing.T, cfg *config.Config) *certReloader {
+	t.Helper()
+	r, err := newCertReloader(cfg, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatalf("newCertReloader: %v", err)
+	}
+	return r
+}
+
+// handshakeConfig возвращает конфигурацию, которую сервер применит к рукопожатию
+func handshakeConfig(t *testing.T, cfg *tls.Config) *tls.Config {
+	t.Helper()
+	hsCfg, err := cfg.GetConfigForClient(&tls.ClientHelloInfo{})
+	if err != nil {
+		t.Fatalf("GetConfigForClient: %v", err)
+	}
+	return hsCfg
+}
+
+func TestTLSConfigClientAuthPerListener(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeCert(t, dir, "server", "server")
+	caFile, _ := writeCert(t, dir, "ca", "clients")
+	r := newTestReloader(t, &config.Config{TLSCertFile: certFile, TLSKeyFile: keyFile, TLSClientCAFile: caFile})
+
+	tests := []struct {
+		mode string
+		want tls.ClientAuthType
+	}{
+		{mode: "none", want: tls.NoClientCert},
+		{mode: "verify-if-given", want: tls.VerifyClientCertIfGiven},
+		{mode: "require", want: tls.RequireAndVerifyClientCert},
+	}
+	for _, tt := range tests {
+		clientAuth, err := parseClientAuth(tt.mode)
+		if err != nil {
+			t.Fatalf("parseClientAuth(%q): %v", tt.mode, err)
+		}
+		if got := handshakeConfig(t, r.TLSConfig(clientAuth)).ClientAuth; got != tt.want {
+			t.Errorf("HTTP client auth %q: ClientAuth = %v, want %v", tt.mode, got, tt.want)
+		}
+	}
+
+	// Для gRPC клиентский сертификат по-прежнему обязателен
+	if got := handshakeConfig(t, r.TLSConfig(tls.RequireAndVerifyClientCert)).ClientAuth; got != tls.RequireAndVerifyClientCert {
+		t.Errorf("gRPC ClientAuth = %v, want RequireAndVerifyClientCert", got)
+	}
+
+	if _, err := parseClientAuth("optional"); err == nil {
+		t.Error("parseClientAuth accepted an unknown mode")
+	}
+}
+
+// servedCommonName возвращает CommonName сертификата, который сервер предъявит клиенту
+func servedCommonName(t *testing.T, r *certReloader) string {
+	t.Helper()
+	cert := handshakeConfig(t, r.TLSConfig(tls.NoClientCert)).Certificates[0]
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatalf("ParseCertificate: %v", err)
+	}
+	return leaf.Subject.CommonName
+}
+
+// touch сдвигает время модификации файлов, чтобы изменение было заметно при любой
+// точности времени файловой системы
+func touch(t *testing.T, files ...string) {
+	t.Helper()
+	later := time.Now().Add(time.Minute)
+	for _, file := range files {
+		if err := os.Chtimes(file, later, later); err != nil {
+			t.Fatalf("Chtimes: %v", err)
+		}
+	}
+}
+
+func TestCertReloaderPicksUpNewPair(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeCert(t, dir, "server", "first")
+	r := newTestReloader(t, &config.Config{TLSCertFile: certFile, TLSKeyFile: keyFile})
+
+	if changed, err := r.changed(); err != nil || changed {
+		t.Fatalf("changed() = %v, %v right after loading, want false", changed, err)
+	}
+
+	writeCert(t, dir, "server", "second")
+	touch(t, certFile, keyFile)
+
+	if changed, err := r.changed(); err != nil || !changed {
+		t.Fatalf("changed() = %v, %v after replacing the pair, want true", changed, err)
+	}
+	// До перезагрузки сервер предъявляет прежний сертификат
+	if cn := servedCommonName(t, r); cn != "first" {
+		t.Errorf("served certificate %q before reload, want first", cn)
+	}
+	if err := r.reload(); err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if cn := servedCommonName(t, r); cn != "second" {
+		t.Errorf("served certificate %q after reload, want second", cn)
+	}
+	if changed, err := r.changed(); err != nil || changed {
+		t.Errorf("changed() = %v, %v after reload, want false", changed, err)
+	}
+}
+
+func TestCertReloaderKeepsPreviousPairOnError(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeCert(t, dir, "server", "first")
+	r := newTestReloader(t, &config.Config{TLSCertFile: certFile, TLSKeyFile: keyFile})
+	var logs bytes.Buffer
+	r.logger = slog.New(slog.NewTextHandler(&logs, nil))
+
+	// Сертификат заменен, а ключ еще старый: пара не совпадает
+	otherCert, _ := writeCert(t, t.TempDir(), "other", "second")
+	data, err := os.ReadFile(otherCert)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	if err := os.WriteFile(certFile, data, 0600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	touch(t, certFile)
+
+	r.check()
+
+	if cn := servedCommonName(t, r); cn != "first" {
+		t.Errorf("served certificate %q after a failed reload, want the previous one", cn)
+	}
+	if !strings.Contains(logs.String(), "level=ERROR") || !strings.Contains(logs.String(), "keeping previous ones") {
+		t.Errorf("log = %q, want an error about the failed reload", logs.String())
+	}
+
+	// Поврежденный файл также не заменяет рабочую пару
+	logs.Reset()
+	if err := os.WriteFile(keyFile, []byte("not a key"), 0600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	touch(t, keyFile)
+	r.check()
+	if cn := servedCommonName(t, r); cn != "first" {
+		t.Errorf("served certificate %q after a corrupt key, want the previous one", cn)
+	}
+	if !strings.Contains(logs.String(), "level=ERROR") {
+		t.Errorf("log = %q, want an error about the corrupt key", logs.String())
+	}
+
+	// После исправления пары сертификат перечитывается при следующей проверке
+	logs.Reset()
+	writeCert(t, dir, "server", "third")
+	touch(t, certFile, keyFile)
+	r.check()
+	if cn := servedCommonName(t, r); cn != "third" {
+		t.Errorf("served certificate %q after fixing the pair, want third", cn)
+	}
+	if !strings.Contains(logs.String(), "TLS certificates reloaded") {
+		t.Errorf("log = %q, want a reload message", logs.String())
+	}
+}
+
+func TestTLSMinVersion(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeCert(t, dir, "server", "server")
+
+	tests := []struct {
+		version string
+		want    uint16
+		wantErr bool
+	}{
+		{version: "", want: tls.VersionTLS12},
+		{version: "1.2", want: tls.VersionTLS12},
+		{version: "1.3", want: tls.VersionTLS13},
+		{version: "1.1", wantErr: true},
+		{version: "tls1.3", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.version, func(t *testing.T) {
+			got, err := parseTLSVersion(tt.version)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("parseTLSVersion(%q) = %d, want error", tt.version, got)
+				}
+				// Неверная версия отклоняется при создании сервера
+				cfg := &config.Config{TLSCertFile: certFile, TLSKeyFile: keyFile, TLSMinVersion: tt.version}
+				if _, err := newCertReloader(cfg, slog.New(slog.NewTextHandler(io.Discard, nil))); err == nil {
+					t.Errorf("newCertReloader accepted TLS_MIN_VERSION %q", tt.version)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Fatalf("parseTLSVersion(%q) = %d, %v, want %d", tt.version, got, err, tt.want)
+			}
+
+			r := newTestReloader(t, &config.Config{TLSCertFile: certFile, TLSKeyFile: keyFile, TLSMinVersion: tt.version})
+			cfg := r.TLSConfig(tls.NoClientCert)
+			if cfg.MinVersion != tt.want || handshakeConfig(t, cfg).MinVersion != tt.want {
+				t.Errorf("MinVersion = %d, handshake MinVersion = %d, want %d", cfg.MinVersion, handshakeConfig(t, cfg).MinVersion, tt.want)
+			}
+		})
+	}
+}
+
+func TestTLSConfigClientAuthRequiresCA(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeCert(t, dir, "server", "server")
+	caFile, _ := writeCert(t, dir, "ca", "clients")
+
+	withoutCA := handshakeConfig(t, newTestReloader(t, &config.Config{TLSCertFile: certFile, TLSKeyFile: keyFile}).
+		TLSConfig(tls.RequireAndVerifyClientCert))
+	if withoutCA.ClientAuth != tls.NoClientCert || withoutCA.ClientCAs != nil {
+		t.Errorf("without a CA: ClientAuth = %v, ClientCAs set = %v, want NoClientCert and no CAs",
+			withoutCA.ClientAuth, withoutCA.ClientCAs != nil)
+	}
+
+	withCA := handshakeConfig(t, newTestReloader(t, &config.Config{TLSCertFile: certFile, TLSKeyFile: keyFile, TLSClientCAFile: caFile}).
+		TLSConfig(tls.RequireAndVerifyClientCert))
+	if withCA.ClientAuth != tls.RequireAndVerifyClientCert || withCA.ClientCAs == nil {
+		t.Errorf("with a CA: ClientAuth = %v, ClientCAs set = %v, want RequireAndVerifyClientCert and CAs",
+			withCA.ClientAuth, withCA.ClientCAs != nil)
+	}
+}
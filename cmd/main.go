@@ -2,32 +2,147 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
 	"github.com/watchlist-kata/review/api/server"
 	"github.com/watchlist-kata/review/internal/config"
+	"github.com/watchlist-kata/review/internal/metrics"
+	"github.com/watchlist-kata/review/internal/tracing"
 	"github.com/watchlist-kata/review/pkg/logger"
 	"log"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
 )
 
 func main() {
+	// Подкоманда "config print" выводит действующую конфигурацию и завершает работу
+	if len(os.Args) > 2 && os.Args[1] == "config" && os.Args[2] == "print" {
+		printConfig(os.Args[3:])
+		return
+	}
+
 	// Загрузка конфигурации
 	cfg, err := config.LoadConfig()
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	}
 	if err != nil {
 		log.Fatal(err)
 	}
 
 	// Инициализация кастомного логгера
-	customLogger, err := logger.NewLogger(cfg.KafkaBrokers, cfg.KafkaTopic, cfg.ServiceName, cfg.LogBufferSize)
+	levels := cfg.LogLevels()
+	customLogger, err := logger.NewLogger(logger.Options{
+		Brokers:     cfg.KafkaBrokers,
+		KafkaTopic:  cfg.KafkaTopic,
+		ServiceName: cfg.ServiceName,
+		BufferSize:  cfg.LogBufferSize,
+		KafkaLevel:  levels[logger.SinkKafka],
+		FileLevel:   levels[logger.SinkFile],
+		StdoutLevel: levels[logger.SinkStdout],
+
+		Overflow:     logger.OverflowPolicy(cfg.LogOverflowPolicy),
+		BlockTimeout: cfg.LogBlockTimeout,
+		DrainTimeout: cfg.LogDrainTimeout,
+		FileRotation: cfg.LogFileRotation(),
+		SpoolMaxSize: int64(cfg.LogSpoolMaxSizeMB) << 20,
+		Kafka:        cfg.KafkaOptions(),
+		StdoutFormat: logger.Format(cfg.LogFormatStdout),
+		FileFormat:   logger.Format(cfg.LogFormatFile),
+		Color:        logger.ColorMode(cfg.LogColor),
+		AddSource:    cfg.LogSource,
+
+		RedactKeys:     cfg.LogRedactKeys,
+		RedactPatterns: cfg.LogRedactPatterns,
+		KafkaRedact:    logger.RedactMode(cfg.LogRedactKafka),
+		FileRedact:     logger.RedactMode(cfg.LogRedactFile),
+		StdoutRedact:   logger.RedactMode(cfg.LogRedactStdout),
+
+		Sampling: logger.SampleOptions{
+			Interval:   cfg.LogSampleInterval,
+			First:      cfg.LogSampleFirst,
+			Thereafter: cfg.LogSampleThereafter,
+		},
+	})
 	if err != nil {
 		log.Fatal(err)
 	}
-	defer func() {
-		if multiHandler, ok := customLogger.Handler().(*logger.MultiHandler); ok {
-			multiHandler.CloseAll()
+	multiHandler := logger.UnwrapHandler(customLogger.Handler()).(*logger.MultiHandler)
+
+	// Метрики очередей обработчиков логов
+	if err = metrics.RegisterLogQueues(multiHandler.Stats); err != nil {
+		log.Fatal(err)
+	}
+
+	customLogger.Info("effective configuration", slog.Any("config", cfg))
+
+	// Инициализация трассировки
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer shutdownTracing(context.Background())
+
+	// Перезагрузка конфигурации при изменении файла и по SIGHUP
+	store := config.NewStore(cfg, os.Args[1:])
+	store.Subscribe(func(cfg *config.Config) {
+		tracing.SetSampleRatio(cfg.TraceSampleRatio)
+	})
+	store.Subscribe(func(cfg *config.Config) {
+		// Применяются только уровни, измененные в конфигурации, чтобы перезагрузка
+		// не отменяла уровни, установленные через служебный API
+		next := cfg.LogLevels()
+		for sink, level := range next {
+			if levels[sink] == level {
+				continue
+			}
+			if err := multiHandler.SetLogLevel(sink, level); err != nil {
+				customLogger.Error("failed to apply log level", slog.String("sink", sink), slog.Any("error", err))
+			}
 		}
-	}()
+		levels = next
+	})
 
-	// Запуск сервера
-	if err = server.RunServer(context.Background(), cfg, customLogger); err != nil {
+	// SIGTERM (остановка контейнера) и SIGINT запускают корректную остановку
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+	go store.Watch(ctx, customLogger)
+
+	// Запуск сервера; буферы логов сбрасываются до выхода и при ошибке, так как log.Fatal не выполняет defer
+	err = run(ctx, multiHandler, func(ctx context.Context) error {
+		err := server.RunServer(ctx, store, customLogger, multiHandler)
+		if err != nil {
+			customLogger.Error("server stopped", slog.Any("error", err))
+		}
+		return err
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// run выполняет serve до его завершения, например после отмены ctx, и затем закрывает
+// обработчики логов: записи, оставшиеся в очередях, доставляются до LOG_DRAIN_TIMEOUT
+func run(ctx context.Context, handlers *logger.MultiHandler, serve func(context.Context) error) error {
+	err := serve(ctx)
+	if closeErr := handlers.CloseAll(); closeErr != nil {
+		log.Printf("failed to close log handlers: %v", closeErr)
+	}
+	return err
+}
+
+// printConfig выводит конфигурацию, собранную из всех источников, со скрытыми секретами
+func printConfig(args []string) {
+	cfg, err := config.Load(args)
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err = cfg.WriteRedacted(os.Stdout); err != nil {
 		log.Fatal(err)
 	}
 }
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/watchlist-kata/review/pkg/logger"
+)
+
+func TestRunDrainsLogQueuesAfterCancel(t *testing.T) {
+	// FileHandler пишет в logs/<service> относительно рабочей директории
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Getwd: %v", err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatalf("Chdir: %v", err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	const total = 1000
+	fileHandler, err := logger.NewFileHandler("review", logger.FormatOptions{Format: logger.FormatJSON},
+		logger.QueueOptions{Size: total, Overflow: logger.Block, BlockTimeout: time.Second, DrainTimeout: 5 * time.Second},
+		logger.RotationOptions{})
+	if err != nil {
+		t.Fatalf("NewFileHandler: %v", err)
+	}
+	handlers := logger.NewMultiHandler(fileHandler)
+	log := slog.New(handlers)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		cancel() // Как при получении SIGTERM
+	}()
+
+	err = run(ctx, handlers, func(ctx context.Context) error {
+		<-ctx.Done()
+		// Записи, поставленные в очередь во время остановки, тоже должны быть доставлены
+		for i := 0; i < total; i++ {
+			log.Info("shutting down", slog.Int("i", i))
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join("logs", "review", "app.log"))
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	if lines := strings.Count(string(data), "\n"); lines != total {
+		t.Errorf("log file has %d records, want %d", lines, total)
+	}
+	if stats := fileHandler.Stats(); stats.Dropped != 0 || stats.Lost != 0 {
+		t.Errorf("dropped = %d, lost = %d, want 0, 0", stats.Dropped, stats.Lost)
+	}
+}
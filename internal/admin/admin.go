@@ -0,0 +1,83 @@
+package admin
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	adminv1 "github.com/watchlist-kata/review/api/proto/admin/v1"
+	"github.com/watchlist-kata/review/internal/config"
+)
+
+// LogLevels управляет минимальными уровнями приемников логов
+type LogLevels interface {
+	LogLevels() map[string]slog.Level
+	SetLogLevel(sink string, level slog.Level) error
+}
+
+// Server реализует служебный API admin.v1
+type Server struct {
+	adminv1.UnimplementedAdminServiceServer
+	store  *config.Store
+	levels LogLevels
+	logger *slog.Logger
+}
+
+// NewServer создает обработчик служебного API
+func NewServer(store *config.Store, levels LogLevels, logger *slog.Logger) *Server {
+	return &Server{store: store, levels: levels, logger: logger}
+}
+
+func (s *Server) GetConfigVersion(ctx context.Context, req *adminv1.GetConfigVersionRequest) (*adminv1.GetConfigVersionResponse, error) {
+	version := s.store.Version()
+	return &adminv1.GetConfigVersionResponse{
+		Version:            int64(version.Number),
+		Checksum:           version.Checksum,
+		LoadTime:           timestamppb.New(version.LoadedAt),
+		Source:             version.Source,
+		PendingRestartKeys: version.Pending,
+	}, nil
+}
+
+func (s *Server) GetLogLevels(ctx context.Context, req *adminv1.GetLogLevelsRequest) (*adminv1.GetLogLevelsResponse, error) {
+	levels := s.levels.LogLevels()
+	sinks := make([]string, 0, len(levels))
+	for sink := range levels {
+		sinks = append(sinks, sink)
+	}
+	sort.Strings(sinks)
+
+	resp := &adminv1.GetLogLevelsResponse{}
+	for _, sink := range sinks {
+		resp.Levels = append(resp.Levels, &adminv1.LogLevel{Sink: sink, Level: levels[sink].String()})
+	}
+	return resp, nil
+}
+
+// SetLogLevel меняет минимальный уровень приемника логов. Метод не проверяет вызывающего:
+// Server регистрируется только на служебном сервере ADMIN_PORT (см. server.RunServer),
+// доступ к которому ограничен адресом прослушивания и при mTLS клиентским сертификатом.
+// Каждое изменение журналируется с адресом клиента.
+func (s *Server) SetLogLevel(ctx context.Context, req *adminv1.SetLogLevelRequest) (*adminv1.SetLogLevelResponse, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(req.Level)); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid log level %q: must be one of debug, info, warn, error", req.Level)
+	}
+	if err := s.levels.SetLogLevel(req.Sink, level); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	var caller string
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		caller = p.Addr.String()
+	}
+	s.logger.InfoContext(ctx, "log level changed", slog.String("sink", req.Sink), slog.String("level", level.String()), slog.String("peer", caller))
+	return &adminv1.SetLogLevelResponse{
+		Level: &adminv1.LogLevel{Sink: req.Sink, Level: level.String()},
+	}, nil
+}
@@ -1,74 +1,227 @@
 package config
 
 import (
+	"errors"
 	"fmt"
+	"log/slog"
 	"os"
-	"strconv"
-	"strings"
+	"time"
 
-	"github.com/joho/godotenv"
+	"github.com/watchlist-kata/review/pkg/logger"
 )
 
-// Config содержит параметры конфигурации приложения
+// Config содержит параметры конфигурации приложения.
+//
+// Теги полей описывают источники значения: key - ключ в конфигурационном файле
+// (вложенные секции соединяются через "_", флаг командной строки получается заменой
+// "_" на "-"), env - переменная окружения, default - значение по умолчанию,
+// required - параметр обязателен ("unless:<key>" - обязателен, если не задан key),
+// validate - правила проверки значения (см. rules), reload - параметр применяется
+// без перезапуска при перезагрузке конфигурации (см. Store),
+// secret - значение секретно: оно скрывается при выводе конфигурации и может быть
+// прочитано из файла, указанного в <key>_file, <ENV>_FILE или --<flag>-file.
+// Списки в переменных окружения и флагах задаются через запятую.
 type Config struct {
-	DBHost        string   // Хост базы данных
-	DBPort        string   // Порт базы данных
-	DBUser        string   // Пользователь базы данных
-	DBPassword    string   // Пароль базы данных
-	DBName        string   // Имя базы данных
-	DBSSLMode     string   // Режим SSL для базы данных
-	KafkaBrokers  []string // Список брокеров Kafka
-	KafkaTopic    string   // Тема Kafka
-	GRPCPort      string   // Порт для gRPC сервиса
-	ServiceName   string   // Имя сервиса
-	LogBufferSize int      // Размер буфера для логов
+	DatabaseURL     string        `key:"database_url" env:"DATABASE_URL" secret:"true" validate:"dsn"`                                                     // DSN базы данных; если задан, заменяет параметры DB_*
+	DBHost          string        `key:"db_host" env:"DB_HOST" required:"unless:database_url"`                                                             // Хост базы данных
+	DBPort          string        `key:"db_port" env:"DB_PORT" default:"5432" validate:"port"`                                                             // Порт базы данных
+	DBUser          string        `key:"db_user" env:"DB_USER" required:"unless:database_url"`                                                             // Пользователь базы данных
+	DBPassword      string        `key:"db_password" env:"DB_PASSWORD" required:"unless:database_url" secret:"true"`                                       // Пароль базы данных
+	DBName          string        `key:"db_name" env:"DB_NAME" required:"unless:database_url"`                                                             // Имя базы данных
+	DBSSLMode       string        `key:"db_sslmode" env:"DB_SSLMODE" default:"prefer" validate:"oneof=disable|allow|prefer|require|verify-ca|verify-full"` // Режим SSL для базы данных
+	DBMigrate       bool          `key:"db_migrate" env:"DB_MIGRATE"`                                                                                      // Применять миграции схемы базы данных при запуске
+	KafkaBrokers    []string      `key:"kafka_brokers" env:"KAFKA_BROKERS" required:"true" validate:"hostport"`                                            // Список брокеров Kafka
+	KafkaTopic      string        `key:"kafka_topic" env:"KAFKA_TOPIC" required:"true"`                                                                    // Тема Kafka
+	GRPCPort        string        `key:"grpc_port" env:"GRPC_PORT" default:":50053" validate:"addr"`                                                       // Порт для gRPC сервиса
+	ServiceName     string        `key:"service_name" env:"SERVICE_NAME" default:"review"`                                                                 // Имя сервиса
+	LogBufferSize   int           `key:"log_buffer_size" env:"LOG_BUFFER_SIZE" default:"100" validate:"min=1,max=1000000"`                                 // Размер буфера для логов
+	ShutdownTimeout time.Duration `key:"shutdown_timeout" env:"SHUTDOWN_TIMEOUT" default:"10s" validate:"min=1s,max=5m" reload:"true"`                     // Время на корректную остановку серверов
+
+	TombstoneRetention time.Duration `key:"tombstone_retention" env:"TOMBSTONE_RETENTION" default:"720h" validate:"min=0s"` // Срок хранения меток удаления для SyncChanges и WatchReviews (0 - хранить всегда)
+
+	LogLevelKafka  string `key:"log_level_kafka" env:"LOG_LEVEL_KAFKA" default:"info" validate:"level" reload:"true"`   // Минимальный уровень логов, отправляемых в Kafka
+	LogLevelFile   string `key:"log_level_file" env:"LOG_LEVEL_FILE" default:"debug" validate:"level" reload:"true"`    // Минимальный уровень логов, записываемых в файл
+	LogLevelStdout string `key:"log_level_stdout" env:"LOG_LEVEL_STDOUT" default:"info" validate:"level" reload:"true"` // Минимальный уровень логов, выводимых в stdout
+
+	LogFormatStdout string `key:"log_format_stdout" env:"LOG_FORMAT_STDOUT" default:"text" validate:"oneof=text|logfmt|json"` // Формат логов в stdout
+	LogFormatFile   string `key:"log_format_file" env:"LOG_FORMAT_FILE" default:"json" validate:"oneof=text|logfmt|json"`     // Формат логов в файле
+	LogColor        string `key:"log_color" env:"LOG_COLOR" default:"auto" validate:"oneof=auto|always|never"`                // Цвет текстовых логов в stdout (auto - только в терминале и без NO_COLOR)
+	LogSource       bool   `key:"log_source" env:"LOG_SOURCE"`                                                                // Добавлять в логи файл и строку вызова
+
+	LogRedactKeys     []string `key:"log_redact_keys" env:"LOG_REDACT_KEYS" default:"password,passwd,secret,token,authorization,api_key,dsn,database_url"` // Ключи атрибутов логов, значения которых маскируются (также с префиксом, например db_password)
+	LogRedactPatterns []string `key:"log_redact_patterns" env:"LOG_REDACT_PATTERNS" default:"email,bearer,jwt,dsn" validate:"redactpattern"`               // Маскируемые в сообщениях и значениях шаблоны: email, bearer, jwt, dsn или регулярные выражения без запятых
+	LogRedactKafka    string   `key:"log_redact_kafka" env:"LOG_REDACT_KAFKA" default:"full" validate:"oneof=off|keys|full"`                               // Маскирование логов перед Kafka: off - нет, keys - только ключи, full - ключи и шаблоны
+	LogRedactFile     string   `key:"log_redact_file" env:"LOG_REDACT_FILE" default:"keys" validate:"oneof=off|keys|full"`                                 // Маскирование логов перед файлом
+	LogRedactStdout   string   `key:"log_redact_stdout" env:"LOG_REDACT_STDOUT" default:"keys" validate:"oneof=off|keys|full"`                             // Маскирование логов перед stdout
+
+	LogSampleInterval   time.Duration `key:"log_sample_interval" env:"LOG_SAMPLE_INTERVAL" default:"1s" validate:"min=0s,max=1h"` // Интервал выборки повторяющихся логов (0 - без выборки)
+	LogSampleFirst      int           `key:"log_sample_first" env:"LOG_SAMPLE_FIRST" default:"100" validate:"min=0"`              // Число записей с одним сообщением за интервал, которые пишутся всегда
+	LogSampleThereafter int           `key:"log_sample_thereafter" env:"LOG_SAMPLE_THEREAFTER" default:"100" validate:"min=0"`    // После LOG_SAMPLE_FIRST пишется каждая N-я запись (0 - остальные отбрасываются)
+
+	LogOverflowPolicy string        `key:"log_overflow_policy" env:"LOG_OVERFLOW_POLICY" default:"drop-newest" validate:"oneof=drop-newest|drop-oldest|block"` // Поведение при заполненном буфере логов
+	LogBlockTimeout   time.Duration `key:"log_block_timeout" env:"LOG_BLOCK_TIMEOUT" default:"100ms" validate:"min=1ms,max=1m"`                                // Максимальное ожидание места в буфере при политике block
+	LogDrainTimeout   time.Duration `key:"log_drain_timeout" env:"LOG_DRAIN_TIMEOUT" default:"5s" validate:"min=0s,max=5m"`                                    // Время на доставку оставшихся в буфере логов при остановке
+	LogSpoolMaxSizeMB int           `key:"log_spool_max_size_mb" env:"LOG_SPOOL_MAX_SIZE_MB" default:"256" validate:"min=1,max=102400"`                        // Размер дискового буфера логов на время недоступности Kafka в мегабайтах
+
+	LogFileMaxSizeMB      int           `key:"log_file_max_size_mb" env:"LOG_FILE_MAX_SIZE_MB" default:"100" validate:"min=0,max=102400"` // Размер файла логов в мегабайтах, после которого он ротируется (0 - без ограничения)
+	LogFileRotateInterval time.Duration `key:"log_file_rotate_interval" env:"LOG_FILE_ROTATE_INTERVAL" default:"0s" validate:"min=0s"`    // Период ротации файла логов, например 24h (0 - без ротации по времени)
+	LogFileCompress       bool          `key:"log_file_compress" env:"LOG_FILE_COMPRESS" default:"true"`                                  // Сжимать ротированные файлы логов gzip
+	LogFileMaxBackups     int           `key:"log_file_max_backups" env:"LOG_FILE_MAX_BACKUPS" default:"10" validate:"min=0"`             // Максимальное число хранимых ротированных файлов (0 - без ограничения)
+	LogFileMaxAge         time.Duration `key:"log_file_max_age" env:"LOG_FILE_MAX_AGE" default:"168h" validate:"min=0s"`                  // Максимальный возраст хранимых ротированных файлов (0 - без ограничения)
+
+	KafkaTLSEnabled            bool          `key:"kafka_tls_enabled" env:"KAFKA_TLS_ENABLED"`                                                                    // Подключаться к брокерам Kafka по TLS
+	KafkaTLSCAFile             string        `key:"kafka_tls_ca_file" env:"KAFKA_TLS_CA_FILE" validate:"file"`                                                    // CA для проверки брокеров Kafka (по умолчанию системные)
+	KafkaTLSCertFile           string        `key:"kafka_tls_cert_file" env:"KAFKA_TLS_CERT_FILE" validate:"file"`                                                // Клиентский сертификат для mTLS с Kafka
+	KafkaTLSKeyFile            string        `key:"kafka_tls_key_file" env:"KAFKA_TLS_KEY_FILE" validate:"file"`                                                  // Клиентский ключ для mTLS с Kafka
+	KafkaTLSInsecureSkipVerify bool          `key:"kafka_tls_insecure_skip_verify" env:"KAFKA_TLS_INSECURE_SKIP_VERIFY"`                                          // Не проверять сертификаты брокеров Kafka
+	KafkaSASLMechanism         string        `key:"kafka_sasl_mechanism" env:"KAFKA_SASL_MECHANISM" validate:"oneof=PLAIN|SCRAM-SHA-256|SCRAM-SHA-512"`           // Механизм SASL (пусто - без SASL)
+	KafkaSASLUser              string        `key:"kafka_sasl_user" env:"KAFKA_SASL_USER"`                                                                        // Пользователь SASL
+	KafkaSASLPassword          string        `key:"kafka_sasl_password" env:"KAFKA_SASL_PASSWORD" secret:"true"`                                                  // Пароль SASL
+	KafkaCompression           string        `key:"kafka_compression" env:"KAFKA_COMPRESSION" default:"none" validate:"oneof=none|gzip|snappy|lz4|zstd"`          // Кодек сжатия сообщений Kafka
+	KafkaIdempotent            bool          `key:"kafka_idempotent" env:"KAFKA_IDEMPOTENT"`                                                                      // Идемпотентный продюсер Kafka
+	KafkaBatchSize             int           `key:"kafka_batch_size" env:"KAFKA_BATCH_SIZE" default:"0" validate:"min=0,max=104857600"`                           // Размер пакета сообщений Kafka в байтах (0 - отправлять сразу)
+	KafkaLinger                time.Duration `key:"kafka_linger" env:"KAFKA_LINGER" default:"0s" validate:"min=0s,max=1m"`                                        // Максимальное время накопления пакета сообщений Kafka (0 - отправлять сразу)
+	KafkaPartitionKey          string        `key:"kafka_partition_key" env:"KAFKA_PARTITION_KEY" default:"service" validate:"oneof=service|trace_id|request_id"` // Ключ сообщений Kafka, определяющий партицию
+
+	TLSCertFile     string `key:"tls_cert_file" env:"TLS_CERT_FILE" validate:"file"`                            // Путь к сертификату gRPC сервера
+	TLSKeyFile      string `key:"tls_key_file" env:"TLS_KEY_FILE" validate:"file"`                              // Путь к приватному ключу gRPC сервера
+	TLSClientCAFile string `key:"tls_client_ca_file" env:"TLS_CLIENT_CA_FILE" validate:"file"`                  // Путь к CA для проверки клиентских сертификатов (mTLS)
+	TLSMinVersion   string `key:"tls_min_version" env:"TLS_MIN_VERSION" default:"1.2" validate:"oneof=1.2|1.3"` // Минимальная версия TLS (1.2 или 1.3)
+
+	HTTPPort           string   `key:"http_port" env:"HTTP_PORT" validate:"addr"`                                                            // Порт для REST/JSON шлюза (пусто - шлюз отключен)
+	HTTPClientAuth     string   `key:"http_client_auth" env:"HTTP_CLIENT_AUTH" default:"none" validate:"oneof=none|verify-if-given|require"` // Проверка клиентских сертификатов REST шлюзом по TLS_CLIENT_CA_FILE; не зависит от mTLS gRPC
+	CORSAllowedOrigins []string `key:"cors_allowed_origins" env:"CORS_ALLOWED_ORIGINS" validate:"origin" reload:"true"`                      // Источники, которым разрешены CORS запросы к REST шлюзу
+
+	MetricsPort string `key:"metrics_port" env:"METRICS_PORT" validate:"addr"` // Порт для эндпоинта /metrics (пусто - метрики не публикуются)
+
+	AdminPort string `key:"admin_port" env:"ADMIN_PORT" default:"127.0.0.1:50054" validate:"addr"` // Адрес служебного gRPC API admin.v1, по умолчанию только localhost (пусто - API отключен)
+
+	OTLPEndpoint     string  `key:"otlp_endpoint" env:"OTEL_EXPORTER_OTLP_ENDPOINT" validate:"hostport"`                            // Адрес OTLP коллектора трассировок (пусто - экспорт отключен)
+	OTLPInsecure     bool    `key:"otlp_insecure" env:"OTEL_EXPORTER_OTLP_INSECURE"`                                                // Подключаться к OTLP коллектору без TLS
+	TraceSampleRatio float64 `key:"trace_sample_ratio" env:"TRACE_SAMPLE_RATIO" default:"1.0" validate:"min=0,max=1" reload:"true"` // Доля трассируемых запросов от 0 до 1
+
+	FeatureFlags     []string `key:"feature_flags" env:"FEATURE_FLAGS" validate:"featureflag" reload:"true"`    // Флаги функциональности: name, name=true|false или name=N%
+	FeatureFlagsFile string   `key:"feature_flags_file" env:"FEATURE_FLAGS_FILE" validate:"file" reload:"true"` // JSON файл флагов функциональности (пусто - без файла)
+	File             string   // Конфигурационный файл, из которого загружена конфигурация (пусто - без файла)
 }
 
-// LoadConfig загружает конфигурацию из .env файла
+// LoadConfig загружает конфигурацию с флагами командной строки из os.Args
 func LoadConfig() (*Config, error) {
-	// Загружаем переменные окружения из .env файла
-	err := godotenv.Load()
+	return Load(os.Args[1:])
+}
+
+// Load собирает конфигурацию из источников в порядке возрастания приоритета:
+//
+//  1. значения по умолчанию из тегов default;
+//  2. конфигурационный файл YAML (.yaml, .yml) или TOML (.toml), путь к которому
+//     задается флагом --config или переменной CONFIG_FILE (необязателен);
+//  3. переменные окружения, в том числе из файла .env в рабочей директории, если он есть
+//     (переменные, уже заданные в окружении, .env не переопределяет, а секрет из .env
+//     не применяется, если в окружении задан <ENV> или <ENV>_FILE этого секрета);
+//  4. флаги командной строки, например --db-host или --grpc-port.
+//
+// Каждый следующий источник переопределяет значения предыдущих.
+func Load(args []string) (*Config, error) {
+	flags, configFile, err := parseFlags(args)
 	if err != nil {
-		return nil, fmt.Errorf("failed to load .env file: %w", err)
+		return nil, err
+	}
+
+	if err := loadDotEnv(); err != nil {
+		return nil, err
+	}
+	if configFile == "" {
+		configFile = os.Getenv(configFileEnv)
 	}
 
-	// Проверяем обязательные переменные окружения
-	requiredEnvVars := []string{
-		"DB_HOST", "DB_PORT", "DB_USER", "DB_PASSWORD",
-		"DB_NAME", "DB_SSLMODE", "KAFKA_BROKERS", "KAFKA_TOPIC",
-		"GRPC_PORT", "SERVICE_NAME", "LOG_BUFFER_SIZE",
+	// Ошибки всех источников и проверок собираются вместе, чтобы сообщить обо всех сразу
+	cfg := &Config{File: configFile}
+	errs := []error{
+		applyDefaults(cfg),
+		applyFile(cfg, configFile),
+		applyEnv(cfg),
+		applyFlags(cfg, flags),
+		cfg.validate(),
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, fmt.Errorf("invalid configuration:\n%w", err)
 	}
+	return cfg, nil
+}
+
+// validate проверяет обязательные параметры, значения по правилам validate
+// и согласованность параметров между собой
+func (cfg *Config) validate() error {
+	errs := []error{checkRequired(cfg), checkRules(cfg)}
 
-	for _, envVar := range requiredEnvVars {
-		if value := os.Getenv(envVar); value == "" {
-			return nil, fmt.Errorf("missing required environment variable: %s", envVar)
-		}
+	// Сертификат и ключ задаются только вместе
+	if (cfg.TLSCertFile == "") != (cfg.TLSKeyFile == "") {
+		errs = append(errs, fmt.Errorf("TLS_CERT_FILE and TLS_KEY_FILE must be set together"))
+	}
+	if cfg.TLSClientCAFile != "" && cfg.TLSCertFile == "" {
+		errs = append(errs, fmt.Errorf("TLS_CLIENT_CA_FILE requires TLS_CERT_FILE and TLS_KEY_FILE"))
+	}
+	if cfg.HTTPClientAuth != "" && cfg.HTTPClientAuth != "none" && cfg.TLSClientCAFile == "" {
+		errs = append(errs, fmt.Errorf("HTTP_CLIENT_AUTH=%s requires TLS_CLIENT_CA_FILE", cfg.HTTPClientAuth))
 	}
 
-	// Преобразуем KAFKA_BROKERS в []string
-	kafkaBrokers := strings.Split(os.Getenv("KAFKA_BROKERS"), ",")
-	if len(kafkaBrokers) == 0 || (len(kafkaBrokers) == 1 && kafkaBrokers[0] == "") {
-		return nil, fmt.Errorf("invalid KAFKA_BROKERS value")
+	// Файлы TLS для Kafka используются только при включенном TLS
+	if (cfg.KafkaTLSCertFile == "") != (cfg.KafkaTLSKeyFile == "") {
+		errs = append(errs, fmt.Errorf("KAFKA_TLS_CERT_FILE and KAFKA_TLS_KEY_FILE must be set together"))
+	}
+	if !cfg.KafkaTLSEnabled && (cfg.KafkaTLSCAFile != "" || cfg.KafkaTLSCertFile != "" || cfg.KafkaTLSInsecureSkipVerify) {
+		errs = append(errs, fmt.Errorf("KAFKA_TLS_CA_FILE, KAFKA_TLS_CERT_FILE and KAFKA_TLS_INSECURE_SKIP_VERIFY require KAFKA_TLS_ENABLED"))
+	}
+	if cfg.KafkaSASLMechanism != "" && (cfg.KafkaSASLUser == "" || cfg.KafkaSASLPassword == "") {
+		errs = append(errs, fmt.Errorf("KAFKA_SASL_MECHANISM requires KAFKA_SASL_USER and KAFKA_SASL_PASSWORD"))
 	}
 
-	// Преобразуем LOG_BUFFER_SIZE в int с дефолтным значением 100, если не задано корректно
-	logBufferSize, err := strconv.Atoi(os.Getenv("LOG_BUFFER_SIZE"))
-	if err != nil || logBufferSize <= 0 {
-		logBufferSize = 100 // Значение по умолчанию
+	return errors.Join(errs...)
+}
+
+// LogLevels возвращает минимальные уровни логов по приемникам
+func (cfg *Config) LogLevels() map[string]slog.Level {
+	levels := map[string]string{
+		logger.SinkKafka:  cfg.LogLevelKafka,
+		logger.SinkFile:   cfg.LogLevelFile,
+		logger.SinkStdout: cfg.LogLevelStdout,
+	}
+	result := make(map[string]slog.Level, len(levels))
+	for sink, value := range levels {
+		var level slog.Level
+		// Значения проверены при загрузке конфигурации
+		_ = level.UnmarshalText([]byte(value))
+		result[sink] = level
+	}
+	return result
+}
+
+// LogFileRotation возвращает параметры ротации файла логов
+func (cfg *Config) LogFileRotation() logger.RotationOptions {
+	return logger.RotationOptions{
+		MaxSize:    int64(cfg.LogFileMaxSizeMB) << 20,
+		Interval:   cfg.LogFileRotateInterval,
+		Compress:   cfg.LogFileCompress,
+		MaxBackups: cfg.LogFileMaxBackups,
+		MaxAge:     cfg.LogFileMaxAge,
 	}
+}
 
-	// Возвращаем конфигурацию
-	return &Config{
-		DBHost:        os.Getenv("DB_HOST"),
-		DBPort:        os.Getenv("DB_PORT"),
-		DBUser:        os.Getenv("DB_USER"),
-		DBPassword:    os.Getenv("DB_PASSWORD"),
-		DBName:        os.Getenv("DB_NAME"),
-		DBSSLMode:     os.Getenv("DB_SSLMODE"),
-		KafkaBrokers:  kafkaBrokers,
-		KafkaTopic:    os.Getenv("KAFKA_TOPIC"),
-		GRPCPort:      os.Getenv("GRPC_PORT"),
-		ServiceName:   os.Getenv("SERVICE_NAME"),
-		LogBufferSize: logBufferSize,
-	}, nil
+// KafkaOptions возвращает параметры безопасности и производительности продюсера Kafka
+func (cfg *Config) KafkaOptions() logger.KafkaOptions {
+	return logger.KafkaOptions{
+		TLS:                   cfg.KafkaTLSEnabled,
+		TLSCAFile:             cfg.KafkaTLSCAFile,
+		TLSCertFile:           cfg.KafkaTLSCertFile,
+		TLSKeyFile:            cfg.KafkaTLSKeyFile,
+		TLSInsecureSkipVerify: cfg.KafkaTLSInsecureSkipVerify,
+		SASLMechanism:         cfg.KafkaSASLMechanism,
+		SASLUser:              cfg.KafkaSASLUser,
+		SASLPassword:          cfg.KafkaSASLPassword,
+		Compression:           cfg.KafkaCompression,
+		Idempotent:            cfg.KafkaIdempotent,
+		BatchSize:             cfg.KafkaBatchSize,
+		Linger:                cfg.KafkaLinger,
+		PartitionKey:          logger.PartitionKey(cfg.KafkaPartitionKey),
+	}
 }
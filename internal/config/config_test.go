@@ -0,0 +1,164 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// isolate переходит в пустую временную директорию и убирает из окружения все
+// переменные конфигурации; исходные значения восстанавливаются после теста
+func isolate(t *testing.T) string {
+	t.Helper()
+
+	for _, f := range fields() {
+		unsetEnv(t, f.env)
+		if f.secret {
+			unsetEnv(t, f.env+"_FILE")
+		}
+	}
+	unsetEnv(t, configFileEnv)
+
+	dir := t.TempDir()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Getwd: %v", err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatalf("Chdir: %v", err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+	return dir
+}
+
+// unsetEnv удаляет переменную окружения до конца теста
+func unsetEnv(t *testing.T, name string) {
+	t.Helper()
+	t.Setenv(name, "")
+	os.Unsetenv(name)
+}
+
+// setRequiredEnv задает обязательные параметры, не участвующие в проверке приоритетов
+func setRequiredEnv(t *testing.T) {
+	t.Helper()
+	t.Setenv("DB_HOST", "db")
+	t.Setenv("DB_USER", "review")
+	t.Setenv("DB_PASSWORD", "secret")
+	t.Setenv("DB_NAME", "review")
+	t.Setenv("KAFKA_BROKERS", "kafka:9092")
+	t.Setenv("KAFKA_TOPIC", "logs")
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	tests := []struct {
+		name   string
+		file   string // Значение grpc.port в конфигурационном файле
+		dotEnv string // Значение GRPC_PORT в .env
+		env    string // Значение переменной окружения GRPC_PORT
+		flag   string // Значение флага --grpc-port
+		want   string
+	}{
+		{name: "default", want: ":50053"},
+		{name: "file over default", file: ":1001", want: ":1001"},
+		{name: "dotenv over file", file: ":1001", dotEnv: ":1002", want: ":1002"},
+		{name: "env over dotenv", file: ":1001", dotEnv: ":1002", env: ":1003", want: ":1003"},
+		{name: "flag over env", file: ":1001", dotEnv: ":1002", env: ":1003", flag: ":1004", want: ":1004"},
+		{name: "flag over file", file: ":1001", flag: ":1004", want: ":1004"},
+		{name: "env without file", env: ":1003", want: ":1003"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := isolate(t)
+			setRequiredEnv(t)
+
+			var args []string
+			if tt.file != "" {
+				path := filepath.Join(dir, "config.yaml")
+				// Вложенная секция grpc соответствует ключу grpc_port
+				writeFile(t, path, "grpc:\n  port: \""+tt.file+"\"\n")
+				args = append(args, "--config", path)
+			}
+			if tt.dotEnv != "" {
+				writeFile(t, filepath.Join(dir, dotEnvFile), "GRPC_PORT="+tt.dotEnv+"\n")
+			}
+			if tt.env != "" {
+				t.Setenv("GRPC_PORT", tt.env)
+			}
+			if tt.flag != "" {
+				args = append(args, "--grpc-port", tt.flag)
+			}
+
+			cfg, err := Load(args)
+			if err != nil {
+				t.Fatalf("Load: %v", err)
+			}
+			if cfg.GRPCPort != tt.want {
+				t.Errorf("GRPCPort = %q, want %q", cfg.GRPCPort, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadFileListsAndSections(t *testing.T) {
+	dir := isolate(t)
+	setRequiredEnv(t)
+	unsetEnv(t, "KAFKA_BROKERS")
+
+	path := filepath.Join(dir, "config.toml")
+	writeFile(t, path, "kafka_brokers = [\"kafka-1:9092\", \"kafka-2:9092\"]\n\n[db]\nsslmode = \"require\"\n")
+
+	cfg, err := Load([]string{"--config", path})
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if got := strings.Join(cfg.KafkaBrokers, ","); got != "kafka-1:9092,kafka-2:9092" {
+		t.Errorf("KafkaBrokers = %q, want kafka-1:9092,kafka-2:9092", got)
+	}
+	if cfg.DBSSLMode != "require" {
+		t.Errorf("DBSSLMode = %q, want require", cfg.DBSSLMode)
+	}
+}
+
+// Без .env конфигурация загружается из окружения, как в Kubernetes
+func TestLoadWithoutDotEnv(t *testing.T) {
+	dir := isolate(t)
+	setRequiredEnv(t)
+
+	if _, err := os.Stat(filepath.Join(dir, dotEnvFile)); !os.IsNotExist(err) {
+		t.Fatalf("%s exists in the test directory", dotEnvFile)
+	}
+
+	cfg, err := Load(nil)
+	if err != nil {
+		t.Fatalf("Load without %s: %v", dotEnvFile, err)
+	}
+	if cfg.DBHost != "db" || cfg.KafkaTopic != "logs" {
+		t.Errorf("DBHost = %q, KafkaTopic = %q, want values from the environment", cfg.DBHost, cfg.KafkaTopic)
+	}
+	if cfg.File != "" {
+		t.Errorf("File = %q, want no config file", cfg.File)
+	}
+}
+
+func TestLoadReportsMissingRequired(t *testing.T) {
+	isolate(t)
+
+	_, err := Load(nil)
+	if err == nil {
+		t.Fatal("Load without required values succeeded")
+	}
+	for _, key := range []string{"db_host", "kafka_brokers", "kafka_topic"} {
+		if !strings.Contains(err.Error(), "missing required config value: "+key) {
+			t.Errorf("error does not report %s:\n%v", key, err)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"net/url"
+	"reflect"
+	"regexp"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// redacted заменяет значения секретных параметров при выводе
+const redacted = "******"
+
+// dsnPassword находит пароль в DSN формата key=value
+var dsnPassword = regexp.MustCompile(`(?i)(password\s*=\s*)('(?:[^'\\]|\\.)*'|\S+)`)
+
+// redactDSN скрывает пароль в DSN формата URL или key=value
+func redactDSN(dsn string) string {
+	if u, err := url.Parse(dsn); err == nil && u.Scheme != "" {
+		return u.Redacted()
+	}
+	return dsnPassword.ReplaceAllString(dsn, "${1}"+redacted)
+}
+
+// redactedValue возвращает значение параметра, безопасное для вывода
+func (f field) redactedValue(cfg *Config) any {
+	value := reflect.ValueOf(cfg).Elem().Field(f.index).Interface()
+	if d, ok := value.(time.Duration); ok {
+		// В файле конфигурации длительность задается строкой
+		return d.String()
+	}
+	if !f.secret || f.isZero(cfg) {
+		return value
+	}
+	if f.key == "database_url" {
+		return redactDSN(value.(string))
+	}
+	return redacted
+}
+
+// LogValue представляет конфигурацию в логах со скрытыми секретами
+func (cfg *Config) LogValue() slog.Value {
+	all := fields()
+	attrs := make([]slog.Attr, 0, len(all))
+	for _, f := range all {
+		attrs = append(attrs, slog.Any(f.key, f.redactedValue(cfg)))
+	}
+	return slog.GroupValue(attrs...)
+}
+
+// WriteRedacted выводит действующую конфигурацию в формате YAML со скрытыми секретами.
+// Результат можно использовать как конфигурационный файл после подстановки секретов.
+func (cfg *Config) WriteRedacted(w io.Writer) error {
+	document := &yaml.Node{Kind: yaml.MappingNode}
+	for _, f := range fields() {
+		value := &yaml.Node{}
+		if err := value.Encode(f.redactedValue(cfg)); err != nil {
+			return fmt.Errorf("failed to encode config value %s: %w", f.key, err)
+		}
+		document.Content = append(document.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: f.key}, value)
+	}
+
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(document); err != nil {
+		return fmt.Errorf("failed to write config: %w", err)
+	}
+	return encoder.Close()
+}
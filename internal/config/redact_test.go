@@ -0,0 +1,149 @@
+package config
+
+import (
+	"bytes"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testSecret = "Sup3r-s3cret"
+
+func TestRedactDSN(t *testing.T) {
+	tests := []struct {
+		name string
+		dsn  string
+		want string
+	}{
+		{
+			name: "url",
+			dsn:  "postgres://review:" + testSecret + "@db:5432/review?sslmode=require",
+			want: "postgres://review:xxxxx@db:5432/review?sslmode=require",
+		},
+		{
+			name: "url without password",
+			dsn:  "postgres://review@db:5432/review",
+			want: "postgres://review@db:5432/review",
+		},
+		{
+			name: "key value",
+			dsn:  "host=db user=review password=" + testSecret + " dbname=review",
+			want: "host=db user=review password=" + redacted + " dbname=review",
+		},
+		{
+			name: "quoted with spaces",
+			dsn:  "host=db password='" + testSecret + " with \\' quote' dbname=review",
+			want: "host=db password=" + redacted + " dbname=review",
+		},
+		{
+			name: "spaces around equals and upper case",
+			dsn:  "host=db PASSWORD = " + testSecret,
+			want: "host=db PASSWORD = " + redacted,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := redactDSN(tt.dsn)
+			if got != tt.want {
+				t.Errorf("redactDSN(%q) = %q, want %q", tt.dsn, got, tt.want)
+			}
+			if strings.Contains(got, testSecret) {
+				t.Errorf("redactDSN(%q) leaks the password: %q", tt.dsn, got)
+			}
+		})
+	}
+}
+
+// secretConfig загружает конфигурацию, в которой секреты заданы всеми способами:
+// DATABASE_URL с паролем, DB_PASSWORD_FILE и KAFKA_SASL_PASSWORD
+func secretConfig(t *testing.T, databaseURL string) *Config {
+	t.Helper()
+	dir := isolate(t)
+	setRequiredEnv(t)
+	unsetEnv(t, "DB_PASSWORD")
+
+	secretPath := filepath.Join(dir, "db_password")
+	writeFile(t, secretPath, testSecret+"\n")
+	t.Setenv("DB_PASSWORD_FILE", secretPath)
+	t.Setenv("DATABASE_URL", databaseURL)
+	t.Setenv("KAFKA_SASL_MECHANISM", "PLAIN")
+	t.Setenv("KAFKA_SASL_USER", "review")
+	t.Setenv("KAFKA_SASL_PASSWORD", testSecret)
+
+	cfg, err := Load(nil)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.DBPassword != testSecret || cfg.KafkaSASLPassword != testSecret {
+		t.Fatalf("secrets were not loaded")
+	}
+	return cfg
+}
+
+// secretDSNs строки подключения с паролем в обоих поддерживаемых форматах
+var secretDSNs = map[string]string{
+	"url":       "postgres://review:" + testSecret + "@db:5432/review",
+	"key value": "host=db user=review password=" + testSecret + " dbname=review",
+}
+
+func TestWriteRedactedHidesSecrets(t *testing.T) {
+	for name, dsn := range secretDSNs {
+		t.Run(name, func(t *testing.T) {
+			cfg := secretConfig(t, dsn)
+
+			var out bytes.Buffer
+			if err := cfg.WriteRedacted(&out); err != nil {
+				t.Fatalf("WriteRedacted: %v", err)
+			}
+			if strings.Contains(out.String(), testSecret) {
+				t.Errorf("config print leaks a secret:\n%s", out.String())
+			}
+			for _, line := range []string{"db_password: '" + redacted + "'", "kafka_sasl_password: '" + redacted + "'"} {
+				if !strings.Contains(out.String(), line) {
+					t.Errorf("config print has no line %q:\n%s", line, out.String())
+				}
+			}
+			if want := "database_url: " + redactDSN(dsn); !strings.Contains(out.String(), want) {
+				t.Errorf("config print has no line %q:\n%s", want, out.String())
+			}
+		})
+	}
+}
+
+func TestLogValueHidesSecrets(t *testing.T) {
+	for name, dsn := range secretDSNs {
+		t.Run(name, func(t *testing.T) {
+			cfg := secretConfig(t, dsn)
+
+			for _, handler := range []func(*bytes.Buffer) slog.Handler{
+				func(b *bytes.Buffer) slog.Handler { return slog.NewJSONHandler(b, nil) },
+				func(b *bytes.Buffer) slog.Handler { return slog.NewTextHandler(b, nil) },
+			} {
+				var out bytes.Buffer
+				slog.New(handler(&out)).Info("effective configuration", slog.Any("config", cfg))
+				if strings.Contains(out.String(), testSecret) {
+					t.Errorf("log leaks a secret:\n%s", out.String())
+				}
+				if !strings.Contains(out.String(), redacted) {
+					t.Errorf("log has no redacted values:\n%s", out.String())
+				}
+			}
+		})
+	}
+}
+
+func TestValidationErrorsHideSecrets(t *testing.T) {
+	isolate(t)
+	setRequiredEnv(t)
+	t.Setenv("DATABASE_URL", "mysql://review:"+testSecret+"@db/review")
+
+	_, err := Load(nil)
+	if err == nil || !strings.Contains(err.Error(), "invalid DATABASE_URL value "+redacted) {
+		t.Fatalf("Load = %v, want an error about DATABASE_URL", err)
+	}
+	if strings.Contains(err.Error(), testSecret) {
+		t.Errorf("error leaks the secret: %v", err)
+	}
+}
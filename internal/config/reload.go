@@ -0,0 +1,192 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"log/slog"
+	"os"
+	"os/signal"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// reloadInterval задает период проверки конфигурационного файла на изменения
+const reloadInterval = 5 * time.Second
+
+// Version описывает активную версию конфигурации
+type Version struct {
+	Number   uint64    // Номер версии; увеличивается при каждом применении изменений
+	Checksum string    // Контрольная сумма конфигурации со скрытыми секретами
+	LoadedAt time.Time // Время применения версии
+	Source   string    // Конфигурационный файл; пусто, если файл не используется
+	Pending  []string  // Измененные параметры, которые вступят в силу только после перезапуска
+}
+
+// snapshot связывает конфигурацию с ее версией, чтобы они заменялись одной операцией
+type snapshot struct {
+	cfg     *Config
+	version Version
+}
+
+// Store хранит активную конфигурацию и перечитывает ее при изменении файла или по SIGHUP.
+// Параметры с тегом reload:"true" применяются на лету; изменения остальных параметров
+// отклоняются с предупреждением до перезапуска сервиса.
+type Store struct {
+	args    []string
+	current atomic.Pointer[snapshot]
+
+	mu          sync.Mutex // Сериализует перезагрузки и изменение подписчиков
+	subscribers []func(*Config)
+}
+
+// NewStore создает хранилище с загруженной конфигурацией; args - флаги командной строки,
+// с которыми конфигурация перечитывается
+func NewStore(cfg *Config, args []string) *Store {
+	s := &Store{args: args}
+	s.current.Store(&snapshot{cfg: cfg, version: Version{
+		Number:   1,
+		Checksum: checksum(cfg),
+		LoadedAt: time.Now(),
+		Source:   cfg.File,
+	}})
+	return s
+}
+
+// Current возвращает активную конфигурацию. Конфигурация не изменяется после публикации,
+// поэтому значения, прочитанные из одного снимка, всегда согласованы между собой.
+func (s *Store) Current() *Config {
+	return s.current.Load().cfg
+}
+
+// Version возвращает активную версию конфигурации
+func (s *Store) Version() Version {
+	return s.current.Load().version
+}
+
+// Subscribe регистрирует функцию, применяющую новую конфигурацию к работающему компоненту
+func (s *Store) Subscribe(apply func(*Config)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.subscribers = append(s.subscribers, apply)
+}
+
+// Reload перечитывает конфигурацию из всех источников и применяет изменения
+// перезагружаемых параметров. Возвращает true, если активная конфигурация изменилась.
+func (s *Store) Reload(logger *slog.Logger) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	next, err := Load(s.args)
+	if err != nil {
+		return false, err
+	}
+
+	prev := s.current.Load()
+	pending := restartOnly(prev.cfg, next)
+	if len(pending) > 0 {
+		logger.Warn("config changes require restart and were not applied", slog.String("keys", strings.Join(pending, ", ")))
+	}
+
+	if reflect.DeepEqual(prev.cfg, next) {
+		if !reflect.DeepEqual(prev.version.Pending, pending) {
+			version := prev.version
+			version.Pending = pending
+			s.current.Store(&snapshot{cfg: prev.cfg, version: version})
+		}
+		return false, nil
+	}
+
+	s.current.Store(&snapshot{cfg: next, version: Version{
+		Number:   prev.version.Number + 1,
+		Checksum: checksum(next),
+		LoadedAt: time.Now(),
+		Source:   next.File,
+		Pending:  pending,
+	}})
+	for _, apply := range s.subscribers {
+		apply(next)
+	}
+
+	logger.Info("config reloaded", slog.Uint64("version", prev.version.Number+1))
+	return true, nil
+}
+
+// Watch перечитывает конфигурацию при изменении файла и по сигналу SIGHUP,
+// пока не будет отменен контекст. Ошибки перезагрузки оставляют активной прежнюю версию.
+func (s *Store) Watch(ctx context.Context, logger *slog.Logger) {
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	defer signal.Stop(hangup)
+
+	ticker := time.NewTicker(reloadInterval)
+	defer ticker.Stop()
+
+	modTime := fileModTime(s.Current().File)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hangup:
+			logger.Info("SIGHUP received, reloading config")
+		case <-ticker.C:
+			current := fileModTime(s.Current().File)
+			if current.Equal(modTime) {
+				continue
+			}
+			modTime = current
+			logger.Info("config file changed, reloading config", slog.String("file", s.Current().File))
+		}
+
+		if _, err := s.Reload(logger); err != nil {
+			logger.Error("failed to reload config", slog.Any("error", err))
+		}
+	}
+}
+
+// restartOnly возвращает ключи измененных параметров без тега reload и
+// восстанавливает в next их прежние значения
+func restartOnly(prev, next *Config) []string {
+	var keys []string
+	prevValue, nextValue := reflect.ValueOf(prev).Elem(), reflect.ValueOf(next).Elem()
+	for i := 0; i < prevValue.NumField(); i++ {
+		if reflect.TypeOf(Config{}).Field(i).Tag.Get("reload") == "true" {
+			continue
+		}
+		if reflect.DeepEqual(prevValue.Field(i).Interface(), nextValue.Field(i).Interface()) {
+			continue
+		}
+		if key := reflect.TypeOf(Config{}).Field(i).Tag.Get("key"); key != "" {
+			keys = append(keys, key)
+		}
+		nextValue.Field(i).Set(prevValue.Field(i))
+	}
+	return keys
+}
+
+// checksum вычисляет контрольную сумму конфигурации без секретов
+func checksum(cfg *Config) string {
+	var buf bytes.Buffer
+	if err := cfg.WriteRedacted(&buf); err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(buf.Bytes())
+	return hex.EncodeToString(sum[:8])
+}
+
+// fileModTime возвращает время изменения файла; пустой путь или ошибка дают нулевое время
+func fileModTime(path string) time.Time {
+	if path == "" {
+		return time.Time{}
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
@@ -0,0 +1,167 @@
+package config
+
+import (
+	"io"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// reloadFixture хранилище с конфигурационным файлом и записью вызовов подписчика
+type reloadFixture struct {
+	path    string
+	store   *Store
+	applied []*Config
+}
+
+// newReloadFixture создает хранилище из файла с content и подписывается на изменения
+func newReloadFixture(t *testing.T, content string) *reloadFixture {
+	t.Helper()
+	dir := isolate(t)
+	setRequiredEnv(t)
+
+	f := &reloadFixture{path: filepath.Join(dir, "config.yaml")}
+	writeFile(t, f.path, content)
+	args := []string{"--config", f.path}
+	cfg, err := Load(args)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	f.store = NewStore(cfg, args)
+	f.store.Subscribe(func(cfg *Config) { f.applied = append(f.applied, cfg) })
+	return f
+}
+
+// reload перезаписывает файл и перечитывает конфигурацию
+func (f *reloadFixture) reload(t *testing.T, content string) (bool, error) {
+	t.Helper()
+	writeFile(t, f.path, content)
+	return f.store.Reload(slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+const reloadBase = "grpc_port: \":1001\"\nlog_level_stdout: info\ntrace_sample_ratio: 0.5\n"
+
+func TestReloadAppliesReloadableKeys(t *testing.T) {
+	f := newReloadFixture(t, reloadBase)
+	before := f.store.Version()
+
+	changed, err := f.reload(t, "grpc_port: \":1001\"\nlog_level_stdout: debug\ntrace_sample_ratio: 0.25\n")
+	if err != nil || !changed {
+		t.Fatalf("Reload = %v, %v, want true, nil", changed, err)
+	}
+
+	cfg := f.store.Current()
+	if cfg.LogLevelStdout != "debug" || cfg.TraceSampleRatio != 0.25 {
+		t.Errorf("LogLevelStdout = %q, TraceSampleRatio = %v, want debug, 0.25", cfg.LogLevelStdout, cfg.TraceSampleRatio)
+	}
+	if len(f.applied) != 1 || f.applied[0] != cfg {
+		t.Errorf("subscriber called %d times, want once with the active config", len(f.applied))
+	}
+
+	after := f.store.Version()
+	if after.Number != before.Number+1 {
+		t.Errorf("version = %d, want %d", after.Number, before.Number+1)
+	}
+	if after.Checksum == before.Checksum || after.Checksum != checksum(cfg) {
+		t.Errorf("checksum = %s (was %s), want the checksum of the new config", after.Checksum, before.Checksum)
+	}
+	if after.LoadedAt.Before(before.LoadedAt) || after.Source != f.path || len(after.Pending) != 0 {
+		t.Errorf("version = %+v, want a later load from %s without pending keys", after, f.path)
+	}
+}
+
+func TestReloadReportsRestartOnlyKeysAsPending(t *testing.T) {
+	f := newReloadFixture(t, reloadBase)
+	before := f.store.Version()
+	prev := f.store.Current()
+
+	changed, err := f.reload(t, "grpc_port: \":2002\"\nlog_level_stdout: info\ntrace_sample_ratio: 0.5\n")
+	if err != nil || changed {
+		t.Fatalf("Reload = %v, %v, want false, nil", changed, err)
+	}
+	if cfg := f.store.Current(); cfg != prev || cfg.GRPCPort != ":1001" {
+		t.Errorf("GRPCPort = %q, want the running :1001", cfg.GRPCPort)
+	}
+	if len(f.applied) != 0 {
+		t.Errorf("subscriber called %d times, want none", len(f.applied))
+	}
+	version := f.store.Version()
+	if version.Number != before.Number || strings.Join(version.Pending, ",") != "grpc_port" {
+		t.Errorf("version = %d, pending = %v, want %d, [grpc_port]", version.Number, version.Pending, before.Number)
+	}
+
+	// Вместе с перезагружаемым параметром применяется только он
+	changed, err = f.reload(t, "grpc_port: \":2002\"\nlog_level_stdout: warn\ntrace_sample_ratio: 0.5\n")
+	if err != nil || !changed {
+		t.Fatalf("Reload = %v, %v, want true, nil", changed, err)
+	}
+	cfg := f.store.Current()
+	if cfg.LogLevelStdout != "warn" || cfg.GRPCPort != ":1001" {
+		t.Errorf("LogLevelStdout = %q, GRPCPort = %q, want warn, :1001", cfg.LogLevelStdout, cfg.GRPCPort)
+	}
+	version = f.store.Version()
+	if version.Number != before.Number+1 || strings.Join(version.Pending, ",") != "grpc_port" {
+		t.Errorf("version = %d, pending = %v, want %d, [grpc_port]", version.Number, version.Pending, before.Number+1)
+	}
+
+	// Возврат прежнего значения снимает ожидание перезапуска без новой версии
+	changed, err = f.reload(t, "grpc_port: \":1001\"\nlog_level_stdout: warn\ntrace_sample_ratio: 0.5\n")
+	if err != nil || changed {
+		t.Fatalf("Reload = %v, %v, want false, nil", changed, err)
+	}
+	if version := f.store.Version(); version.Number != before.Number+1 || len(version.Pending) != 0 {
+		t.Errorf("version = %d, pending = %v, want %d without pending keys", version.Number, version.Pending, before.Number+1)
+	}
+}
+
+func TestReloadRejectsInvalidFile(t *testing.T) {
+	tests := map[string]string{
+		"malformed yaml": "grpc_port: [\n",
+		"invalid value":  "grpc_port: \":1001\"\nlog_level_stdout: loud\n",
+		"unknown key":    reloadBase + "log_levle_file: debug\n",
+	}
+
+	for name, content := range tests {
+		t.Run(name, func(t *testing.T) {
+			f := newReloadFixture(t, reloadBase)
+			before := f.store.Version()
+			prev := f.store.Current()
+
+			changed, err := f.reload(t, content)
+			if err == nil || changed {
+				t.Fatalf("Reload = %v, %v, want an error", changed, err)
+			}
+			if f.store.Current() != prev {
+				t.Error("active config replaced after a failed reload")
+			}
+			if version := f.store.Version(); version.Number != before.Number || version.Checksum != before.Checksum {
+				t.Errorf("version = %+v, want %+v", version, before)
+			}
+			if len(f.applied) != 0 {
+				t.Errorf("subscriber called %d times, want none", len(f.applied))
+			}
+		})
+	}
+}
+
+func TestRestartOnly(t *testing.T) {
+	prev := &Config{GRPCPort: ":1001", DBHost: "db", LogLevelStdout: "info", CORSAllowedOrigins: []string{"https://a.example.com"}}
+	next := &Config{GRPCPort: ":2002", DBHost: "db-2", LogLevelStdout: "debug", CORSAllowedOrigins: []string{"https://b.example.com"}}
+
+	keys := restartOnly(prev, next)
+
+	if strings.Join(keys, ",") != "db_host,grpc_port" {
+		t.Errorf("restartOnly = %v, want [db_host grpc_port]", keys)
+	}
+	// Значения параметров без reload восстановлены, перезагружаемые сохранены
+	if next.GRPCPort != ":1001" || next.DBHost != "db" {
+		t.Errorf("GRPCPort = %q, DBHost = %q, want the previous values", next.GRPCPort, next.DBHost)
+	}
+	if next.LogLevelStdout != "debug" || next.CORSAllowedOrigins[0] != "https://b.example.com" {
+		t.Errorf("LogLevelStdout = %q, CORSAllowedOrigins = %v, want the new values", next.LogLevelStdout, next.CORSAllowedOrigins)
+	}
+	if keys := restartOnly(prev, &Config{GRPCPort: ":1001", DBHost: "db", LogLevelStdout: "warn"}); len(keys) != 0 {
+		t.Errorf("restartOnly with only reloadable changes = %v, want none", keys)
+	}
+}
@@ -0,0 +1,361 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	configFileFlag = "config"      // Флаг пути к конфигурационному файлу
+	configFileEnv  = "CONFIG_FILE" // Переменная окружения пути к конфигурационному файлу
+	dotEnvFile     = ".env"        // Необязательный файл переменных окружения для локального запуска
+)
+
+// field описывает параметр конфигурации, полученный из тегов поля Config
+type field struct {
+	index    int
+	key      string
+	env      string
+	flag     string
+	def      string
+	required string
+	secret   bool
+	rules    string
+}
+
+// fields возвращает параметры конфигурации в порядке объявления полей Config
+func fields() []field {
+	t := reflect.TypeOf(Config{})
+	result := make([]field, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		tag := t.Field(i).Tag
+		key := tag.Get("key")
+		if key == "" {
+			continue
+		}
+		result = append(result, field{
+			index:    i,
+			key:      key,
+			env:      tag.Get("env"),
+			flag:     strings.ReplaceAll(key, "_", "-"),
+			def:      tag.Get("default"),
+			required: tag.Get("required"),
+			secret:   tag.Get("secret") == "true",
+			rules:    tag.Get("validate"),
+		})
+	}
+	return result
+}
+
+// set разбирает строковое значение параметра и записывает его в поле
+func (f field) set(cfg *Config, raw string, source string) error {
+	v := reflect.ValueOf(cfg).Elem().Field(f.index)
+	if v.Type() == reflect.TypeOf(time.Duration(0)) {
+		d, err := time.ParseDuration(strings.TrimSpace(raw))
+		if err != nil {
+			return fmt.Errorf("invalid %s value %q: must be a duration such as 500ms, 10s or 1m", source, raw)
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Int:
+		n, err := strconv.Atoi(strings.TrimSpace(raw))
+		if err != nil {
+			return fmt.Errorf("invalid %s value %q: must be an integer", source, raw)
+		}
+		v.SetInt(int64(n))
+	case reflect.Bool:
+		b, err := strconv.ParseBool(strings.TrimSpace(raw))
+		if err != nil {
+			return fmt.Errorf("invalid %s value %q: must be a boolean", source, raw)
+		}
+		v.SetBool(b)
+	case reflect.Float64:
+		n, err := strconv.ParseFloat(strings.TrimSpace(raw), 64)
+		if err != nil {
+			return fmt.Errorf("invalid %s value %q: must be a number", source, raw)
+		}
+		v.SetFloat(n)
+	case reflect.Slice:
+		var items []string
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		v.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported type of %s", source)
+	}
+	return nil
+}
+
+// setSecret записывает значение секретного параметра, заданное в источнике напрямую
+// или через файл; одновременное задание обоих вариантов считается ошибкой
+func (f field) setSecret(cfg *Config, raw string, hasRaw bool, path string, hasPath bool, source, fileSource string) error {
+	switch {
+	case hasRaw && hasPath:
+		return fmt.Errorf("%s and %s are mutually exclusive", source, fileSource)
+	case hasPath:
+		value, err := readSecretFile(path)
+		if err != nil {
+			return fmt.Errorf("invalid %s: %w", fileSource, err)
+		}
+		return f.set(cfg, value, fileSource)
+	case hasRaw:
+		return f.set(cfg, raw, source)
+	}
+	return nil
+}
+
+// readSecretFile читает секрет из файла, отбрасывая завершающий перевод строки
+func readSecretFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret file: %w", err)
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// isZero сообщает, что параметр не задан ни одним источником
+func (f field) isZero(cfg *Config) bool {
+	return reflect.ValueOf(cfg).Elem().Field(f.index).IsZero()
+}
+
+// applyDefaults записывает значения по умолчанию
+func applyDefaults(cfg *Config) error {
+	var errs []error
+	for _, f := range fields() {
+		if f.def == "" {
+			continue
+		}
+		errs = append(errs, f.set(cfg, f.def, "default of "+f.key))
+	}
+	return errors.Join(errs...)
+}
+
+// loadDotEnv загружает .env из рабочей директории, если он есть. Переменные, уже заданные
+// в окружении, не переопределяются. Секрет, заданный в окружении одним из способов
+// (<ENV> или <ENV>_FILE), имеет приоритет над обоими способами в .env: иначе пароль из
+// .env оказался бы рядом с <ENV>_FILE смонтированного секрета
+func loadDotEnv() error {
+	values, err := godotenv.Read(dotEnvFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load %s file: %w", dotEnvFile, err)
+	}
+
+	counterparts := make(map[string]string)
+	for _, f := range fields() {
+		if f.secret {
+			counterparts[f.env] = f.env + "_FILE"
+			counterparts[f.env+"_FILE"] = f.env
+		}
+	}
+
+	for key, value := range values {
+		if _, ok := os.LookupEnv(key); ok {
+			continue
+		}
+		if counterpart, ok := counterparts[key]; ok {
+			if _, ok := os.LookupEnv(counterpart); ok {
+				continue
+			}
+		}
+		if err := os.Setenv(key, value); err != nil {
+			return fmt.Errorf("failed to load %s file: %w", dotEnvFile, err)
+		}
+	}
+	return nil
+}
+
+// applyFile применяет значения из конфигурационного файла; пустой путь пропускается
+func applyFile(cfg *Config, path string) error {
+	if path == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	document := make(map[string]any)
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &document)
+	case ".toml":
+		err = toml.Unmarshal(data, &document)
+	default:
+		return fmt.Errorf("unsupported config file format %q: expected .yaml, .yml or .toml", filepath.Ext(path))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	values := make(map[string]string)
+	flatten("", document, values)
+
+	var errs []error
+	known := make(map[string]bool)
+	for _, f := range fields() {
+		known[f.key] = true
+		raw, hasRaw := values[f.key]
+		if f.secret {
+			known[f.key+"_file"] = true
+			secretPath, hasPath := values[f.key+"_file"]
+			errs = append(errs, f.setSecret(cfg, raw, hasRaw, secretPath, hasPath, "config file key "+f.key, "config file key "+f.key+"_file"))
+			continue
+		}
+		if hasRaw {
+			errs = append(errs, f.set(cfg, raw, "config file key "+f.key))
+		}
+	}
+
+	var unknown []string
+	for key := range values {
+		if !known[key] {
+			unknown = append(unknown, key)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		errs = append(errs, fmt.Errorf("unknown keys in config file %s: %s", path, strings.Join(unknown, ", ")))
+	}
+	return errors.Join(errs...)
+}
+
+// flatten преобразует вложенные секции файла в плоские ключи вида section_key,
+// а списки - в строку через запятую
+func flatten(prefix string, document map[string]any, values map[string]string) {
+	for key, value := range document {
+		if prefix != "" {
+			key = prefix + "_" + key
+		}
+		switch v := value.(type) {
+		case map[string]any:
+			flatten(key, v, values)
+		case []any:
+			items := make([]string, 0, len(v))
+			for _, item := range v {
+				items = append(items, fmt.Sprint(item))
+			}
+			values[key] = strings.Join(items, ",")
+		case nil:
+			values[key] = ""
+		default:
+			values[key] = fmt.Sprint(v)
+		}
+	}
+}
+
+// applyEnv применяет заданные переменные окружения
+func applyEnv(cfg *Config) error {
+	var errs []error
+	for _, f := range fields() {
+		raw, ok := os.LookupEnv(f.env)
+		if f.secret {
+			path, hasPath := os.LookupEnv(f.env + "_FILE")
+			errs = append(errs, f.setSecret(cfg, raw, ok, path, hasPath, f.env, f.env+"_FILE"))
+			continue
+		}
+		if ok {
+			errs = append(errs, f.set(cfg, raw, f.env))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// flagValue запоминает значение флага; разбор откладывается до применения слоя флагов
+type flagValue struct {
+	value  *string
+	isBool bool
+}
+
+func (v flagValue) String() string {
+	if v.value == nil {
+		return ""
+	}
+	return *v.value
+}
+
+func (v flagValue) Set(raw string) error {
+	*v.value = raw
+	return nil
+}
+
+func (v flagValue) IsBoolFlag() bool {
+	return v.isBool
+}
+
+// parseFlags разбирает флаги командной строки и возвращает значения только явно заданных флагов
+func parseFlags(args []string) (map[string]string, string, error) {
+	set := flag.NewFlagSet("review", flag.ContinueOnError)
+
+	configFile := set.String(configFileFlag, "", "path to YAML or TOML config file (env "+configFileEnv+")")
+
+	raw := make(map[string]*string)
+	for _, f := range fields() {
+		value := new(string)
+		raw[f.flag] = value
+		isBool := reflect.TypeOf(Config{}).Field(f.index).Type.Kind() == reflect.Bool
+		usage := "env " + f.env
+		if f.def != "" {
+			usage += ", default " + f.def
+		}
+		set.Var(flagValue{value: value, isBool: isBool}, f.flag, usage)
+
+		if f.secret {
+			path := new(string)
+			raw[f.flag+"-file"] = path
+			set.Var(flagValue{value: path}, f.flag+"-file", "file containing "+f.flag+" (env "+f.env+"_FILE)")
+		}
+	}
+
+	if err := set.Parse(args); err != nil {
+		return nil, "", err
+	}
+
+	values := make(map[string]string)
+	set.Visit(func(fl *flag.Flag) {
+		if value, ok := raw[fl.Name]; ok {
+			values[fl.Name] = *value
+		}
+	})
+	return values, *configFile, nil
+}
+
+// applyFlags применяет явно заданные флаги командной строки
+func applyFlags(cfg *Config, values map[string]string) error {
+	var errs []error
+	for _, f := range fields() {
+		raw, ok := values[f.flag]
+		if f.secret {
+			path, hasPath := values[f.flag+"-file"]
+			errs = append(errs, f.setSecret(cfg, raw, ok, path, hasPath, "--"+f.flag, "--"+f.flag+"-file"))
+			continue
+		}
+		if ok {
+			errs = append(errs, f.set(cfg, raw, "--"+f.flag))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,176 @@
+package config
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadSecretFromFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string // Содержимое файла с паролем
+		source  string // Способ передачи пути к файлу: env, file или flag
+		want    string
+	}{
+		{name: "env", content: "s3cret", source: "env", want: "s3cret"},
+		{name: "env trailing newline", content: "s3cret\n", source: "env", want: "s3cret"},
+		{name: "env trailing CRLF", content: "s3cret\r\n", source: "env", want: "s3cret"},
+		{name: "env several newlines", content: "s3cret\n\n", source: "env", want: "s3cret"},
+		{name: "inner spaces kept", content: " s3 cret \n", source: "env", want: " s3 cret "},
+		{name: "config file key", content: "s3cret\n", source: "file", want: "s3cret"},
+		{name: "flag", content: "s3cret\n", source: "flag", want: "s3cret"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := isolate(t)
+			setRequiredEnv(t)
+			unsetEnv(t, "DB_PASSWORD")
+
+			secretPath := filepath.Join(dir, "db_password")
+			writeFile(t, secretPath, tt.content)
+
+			var args []string
+			switch tt.source {
+			case "env":
+				t.Setenv("DB_PASSWORD_FILE", secretPath)
+			case "file":
+				configPath := filepath.Join(dir, "config.yaml")
+				writeFile(t, configPath, "db:\n  password_file: "+secretPath+"\n")
+				args = []string{"--config", configPath}
+			case "flag":
+				args = []string{"--db-password-file", secretPath}
+			}
+
+			cfg, err := Load(args)
+			if err != nil {
+				t.Fatalf("Load: %v", err)
+			}
+			if cfg.DBPassword != tt.want {
+				t.Errorf("DBPassword = %q, want %q", cfg.DBPassword, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadRejectsSecretAndSecretFile(t *testing.T) {
+	tests := []struct {
+		name string
+		file string // Содержимое конфигурационного файла
+		env  map[string]string
+		args []string
+		want string
+	}{
+		{
+			name: "env",
+			env:  map[string]string{"DB_PASSWORD": "s3cret", "DB_PASSWORD_FILE": "db_password"},
+			want: "DB_PASSWORD and DB_PASSWORD_FILE are mutually exclusive",
+		},
+		{
+			name: "config file",
+			file: "db:\n  password: s3cret\n  password_file: db_password\n",
+			want: "config file key db_password and config file key db_password_file are mutually exclusive",
+		},
+		{
+			name: "flags",
+			args: []string{"--db-password", "s3cret", "--db-password-file", "db_password"},
+			want: "--db-password and --db-password-file are mutually exclusive",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := isolate(t)
+			setRequiredEnv(t)
+			unsetEnv(t, "DB_PASSWORD")
+			writeFile(t, filepath.Join(dir, "db_password"), "other\n")
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+			args := tt.args
+			if tt.file != "" {
+				configPath := filepath.Join(dir, "config.yaml")
+				writeFile(t, configPath, tt.file)
+				args = append(args, "--config", configPath)
+			}
+
+			_, err := Load(args)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("Load = %v, want %q", err, tt.want)
+			}
+			if strings.Contains(err.Error(), "s3cret") {
+				t.Errorf("error leaks the secret: %v", err)
+			}
+		})
+	}
+}
+
+func TestLoadReportsMissingSecretFile(t *testing.T) {
+	dir := isolate(t)
+	setRequiredEnv(t)
+	unsetEnv(t, "DB_PASSWORD")
+	t.Setenv("DB_PASSWORD_FILE", filepath.Join(dir, "missing"))
+
+	_, err := Load(nil)
+	if err == nil || !strings.Contains(err.Error(), "invalid DB_PASSWORD_FILE: failed to read secret file") {
+		t.Errorf("Load = %v, want an error about the unreadable DB_PASSWORD_FILE", err)
+	}
+}
+
+// Секрет из окружения в любом виде имеет приоритет над обоими вариантами из .env
+func TestLoadDotEnvDoesNotShadowSecretFile(t *testing.T) {
+	tests := []struct {
+		name   string
+		env    map[string]string
+		dotEnv string
+		want   string
+	}{
+		{
+			name:   "file in env, password in dotenv",
+			env:    map[string]string{"DB_PASSWORD_FILE": "db_password"},
+			dotEnv: "DB_PASSWORD=from-dotenv\n",
+			want:   "from-file",
+		},
+		{
+			name:   "password in env, file in dotenv",
+			env:    map[string]string{"DB_PASSWORD": "from-env"},
+			dotEnv: "DB_PASSWORD_FILE=db_password\n",
+			want:   "from-env",
+		},
+		{
+			name:   "password only in dotenv",
+			dotEnv: "DB_PASSWORD=from-dotenv\n",
+			want:   "from-dotenv",
+		},
+		{
+			name:   "file only in dotenv",
+			dotEnv: "DB_PASSWORD_FILE=db_password\n",
+			want:   "from-file",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := isolate(t)
+			setRequiredEnv(t)
+			unsetEnv(t, "DB_PASSWORD")
+			writeFile(t, filepath.Join(dir, "db_password"), "from-file\n")
+			writeFile(t, filepath.Join(dir, dotEnvFile), tt.dotEnv)
+			// .env записывает переменные в окружение процесса; они удаляются после теста
+			unsetEnv(t, "DB_PASSWORD")
+			unsetEnv(t, "DB_PASSWORD_FILE")
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+
+			cfg, err := Load(nil)
+			if err != nil {
+				t.Fatalf("Load: %v", err)
+			}
+			if cfg.DBPassword != tt.want {
+				t.Errorf("DBPassword = %q, want %q", cfg.DBPassword, tt.want)
+			}
+		})
+	}
+}
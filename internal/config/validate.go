@@ -0,0 +1,301 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/url"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/watchlist-kata/review/pkg/logger"
+)
+
+// rule проверяет значение параметра; arg - аргумент правила из тега validate
+type rule func(value reflect.Value, arg string) error
+
+// rules правила, доступные в теге validate. Правила перечисляются через запятую,
+// аргумент указывается после "=", например validate:"min=1,max=65536".
+// Для списков правило применяется к каждому элементу.
+var rules = map[string]rule{
+	"addr":          validateAddr,
+	"hostport":      validateHostPort,
+	"port":          validatePort,
+	"oneof":         validateOneOf,
+	"min":           validateMin,
+	"max":           validateMax,
+	"file":          validateFile,
+	"dsn":           validateDSN,
+	"origin":        validateOrigin,
+	"featureflag":   validateFeatureFlag,
+	"level":         validateLevel,
+	"redactpattern": validateRedactPattern,
+}
+
+// checkRequired проверяет, что обязательные параметры заданы хотя бы одним источником
+func checkRequired(cfg *Config) error {
+	all := fields()
+	byKey := make(map[string]field, len(all))
+	for _, f := range all {
+		byKey[f.key] = f
+	}
+
+	var errs []error
+	for _, f := range all {
+		if f.required == "" || !f.isZero(cfg) {
+			continue
+		}
+		if other, ok := strings.CutPrefix(f.required, "unless:"); ok && !byKey[other].isZero(cfg) {
+			continue
+		}
+		errs = append(errs, fmt.Errorf("missing required config value: %s (env %s, flag --%s)", f.key, f.env, f.flag))
+	}
+	return errors.Join(errs...)
+}
+
+// checkRules проверяет значения параметров по правилам из тегов validate
+func checkRules(cfg *Config) error {
+	var errs []error
+	for _, f := range fields() {
+		value := reflect.ValueOf(cfg).Elem().Field(f.index)
+		// Пустые строки и списки означают незаданный параметр; его проверяет required
+		empty := (value.Kind() == reflect.String || value.Kind() == reflect.Slice) && value.Len() == 0
+		if f.rules == "" || empty {
+			continue
+		}
+		for _, spec := range strings.Split(f.rules, ",") {
+			name, arg, _ := strings.Cut(spec, "=")
+			check, ok := rules[name]
+			if !ok {
+				errs = append(errs, fmt.Errorf("%s: unknown validation rule %q", f.env, name))
+				continue
+			}
+
+			if value.Kind() == reflect.Slice {
+				for i := 0; i < value.Len(); i++ {
+					if err := check(value.Index(i), arg); err != nil {
+						errs = append(errs, fmt.Errorf("invalid %s value %q (item %d): %w", f.env, value.Index(i).Interface(), i+1, err))
+					}
+				}
+				continue
+			}
+			if err := check(value, arg); err != nil {
+				errs = append(errs, fmt.Errorf("invalid %s value %v: %w", f.env, display(f, value), err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// display форматирует значение для сообщения об ошибке, не раскрывая секреты
+func display(f field, value reflect.Value) string {
+	if f.secret {
+		return redacted
+	}
+	if d, ok := value.Interface().(time.Duration); ok {
+		return strconv.Quote(d.String())
+	}
+	if value.Kind() == reflect.String {
+		return strconv.Quote(value.String())
+	}
+	return fmt.Sprint(value.Interface())
+}
+
+// parsePort проверяет номер порта
+func parsePort(port string) error {
+	n, err := strconv.Atoi(port)
+	if err != nil || n < 1 || n > 65535 {
+		return fmt.Errorf("port must be a number between 1 and 65535")
+	}
+	return nil
+}
+
+// validateAddr проверяет адрес прослушивания вида :port или host:port
+func validateAddr(value reflect.Value, _ string) error {
+	_, port, err := net.SplitHostPort(value.String())
+	if err != nil {
+		return fmt.Errorf("must be a listen address such as :50053 or 0.0.0.0:50053")
+	}
+	return parsePort(port)
+}
+
+// validateHostPort проверяет адрес вида host:port с непустым хостом
+func validateHostPort(value reflect.Value, _ string) error {
+	host, port, err := net.SplitHostPort(value.String())
+	if err != nil || host == "" {
+		return fmt.Errorf("must be an address such as kafka:9092")
+	}
+	return parsePort(port)
+}
+
+// validatePort проверяет номер порта
+func validatePort(value reflect.Value, _ string) error {
+	return parsePort(value.String())
+}
+
+// validateOneOf проверяет, что значение входит в список допустимых, разделенных "|"
+func validateOneOf(value reflect.Value, arg string) error {
+	allowed := strings.Split(arg, "|")
+	for _, option := range allowed {
+		if value.String() == option {
+			return nil
+		}
+	}
+	return fmt.Errorf("must be one of %s", strings.Join(allowed, ", "))
+}
+
+// compare сравнивает числовое значение или длительность с аргументом правила
+func compare(value reflect.Value, arg string) (int, error) {
+	if d, ok := value.Interface().(time.Duration); ok {
+		limit, err := time.ParseDuration(arg)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration bound %q", arg)
+		}
+		return compareOrdered(d, limit), nil
+	}
+
+	switch value.Kind() {
+	case reflect.Int:
+		limit, err := strconv.ParseInt(arg, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid integer bound %q", arg)
+		}
+		return compareOrdered(value.Int(), limit), nil
+	case reflect.Float64:
+		limit, err := strconv.ParseFloat(arg, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid number bound %q", arg)
+		}
+		return compareOrdered(value.Float(), limit), nil
+	default:
+		return 0, fmt.Errorf("range rules apply only to numbers and durations")
+	}
+}
+
+func compareOrdered[T int64 | float64 | time.Duration](a, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// validateMin проверяет нижнюю границу
+func validateMin(value reflect.Value, arg string) error {
+	c, err := compare(value, arg)
+	if err != nil {
+		return err
+	}
+	if c < 0 {
+		return fmt.Errorf("must be at least %s", arg)
+	}
+	return nil
+}
+
+// validateMax проверяет верхнюю границу
+func validateMax(value reflect.Value, arg string) error {
+	c, err := compare(value, arg)
+	if err != nil {
+		return err
+	}
+	if c > 0 {
+		return fmt.Errorf("must be at most %s", arg)
+	}
+	return nil
+}
+
+// validateFile проверяет, что путь указывает на существующий файл
+func validateFile(value reflect.Value, _ string) error {
+	info, err := os.Stat(value.String())
+	if err != nil {
+		return fmt.Errorf("file is not accessible: %w", err)
+	}
+	if info.IsDir() {
+		return fmt.Errorf("must be a file, not a directory")
+	}
+	return nil
+}
+
+// validateDSN проверяет DSN PostgreSQL в формате URL или key=value
+func validateDSN(value reflect.Value, _ string) error {
+	dsn := value.String()
+	if !strings.Contains(dsn, "://") {
+		if !strings.Contains(dsn, "=") {
+			return fmt.Errorf("must be a postgres:// URL or key=value connection string")
+		}
+		return nil
+	}
+
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return fmt.Errorf("malformed URL")
+	}
+	if u.Scheme != "postgres" && u.Scheme != "postgresql" {
+		return fmt.Errorf("URL scheme must be postgres or postgresql")
+	}
+	if u.Host == "" {
+		return fmt.Errorf("URL must contain a host")
+	}
+	return nil
+}
+
+// validateOrigin проверяет источник CORS: "*" или схема http(s) с хостом без пути
+func validateOrigin(value reflect.Value, _ string) error {
+	origin := value.String()
+	if origin == "*" {
+		return nil
+	}
+	u, err := url.Parse(origin)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || (u.Path != "" && u.Path != "/") {
+		return fmt.Errorf(`must be "*" or an origin such as https://example.com`)
+	}
+	return nil
+}
+
+// validateFeatureFlag проверяет флаг функциональности вида name, name=true|false или name=N%
+func validateFeatureFlag(value reflect.Value, _ string) error {
+	name, setting, hasSetting := strings.Cut(value.String(), "=")
+	if name == "" {
+		return fmt.Errorf("flag name must not be empty")
+	}
+	if !hasSetting {
+		return nil
+	}
+	if percent, ok := strings.CutSuffix(setting, "%"); ok {
+		n, err := strconv.ParseFloat(percent, 64)
+		if err != nil || n < 0 || n > 100 {
+			return fmt.Errorf("rollout must be between 0%% and 100%%")
+		}
+		return nil
+	}
+	if _, err := strconv.ParseBool(setting); err != nil {
+		return fmt.Errorf("must be name, name=true, name=false or name=N%%")
+	}
+	return nil
+}
+
+// validateLevel проверяет уровень логирования: debug, info, warn, error с необязательным
+// смещением, например warn+2
+func validateLevel(value reflect.Value, _ string) error {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(value.String())); err != nil {
+		return fmt.Errorf("must be one of debug, info, warn, error")
+	}
+	return nil
+}
+
+// validateRedactPattern проверяет шаблон маскирования логов: имя встроенного шаблона
+// или регулярное выражение
+func validateRedactPattern(value reflect.Value, _ string) error {
+	if _, err := logger.NewRedactor(nil, []string{value.String()}); err != nil {
+		return fmt.Errorf("must be one of %s or a valid regular expression", strings.Join(logger.BuiltinRedactPatterns(), ", "))
+	}
+	return nil
+}
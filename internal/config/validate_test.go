@@ -0,0 +1,60 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLoadReportsAllInvalidFieldsTogether(t *testing.T) {
+	isolate(t)
+	setRequiredEnv(t)
+	t.Setenv("DB_PORT", "54x32")
+	t.Setenv("DB_SSLMODE", "sometimes")
+	t.Setenv("KAFKA_BROKERS", "kafka-1:9092,kafka-2")
+	t.Setenv("SHUTDOWN_TIMEOUT", "ten seconds")
+
+	_, err := Load(nil)
+	if err == nil {
+		t.Fatal("Load with invalid values succeeded")
+	}
+
+	msg := err.Error()
+	if !strings.HasPrefix(msg, "invalid configuration:\n") {
+		t.Errorf("error does not start with a summary line:\n%s", msg)
+	}
+
+	// Каждая ошибка занимает отдельную строку и называет параметр, значение и ожидаемый формат
+	want := []string{
+		`invalid DB_PORT value "54x32": port must be a number between 1 and 65535`,
+		`invalid DB_SSLMODE value "sometimes": must be one of disable, allow, prefer, require, verify-ca, verify-full`,
+		`invalid KAFKA_BROKERS value "kafka-2" (item 2): must be an address such as kafka:9092`,
+		`invalid SHUTDOWN_TIMEOUT value "ten seconds": must be a duration such as 500ms, 10s or 1m`,
+	}
+	lines := strings.Split(msg, "\n")
+	for _, w := range want {
+		found := false
+		for _, line := range lines {
+			if line == w {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Errorf("error has no line %q:\n%s", w, msg)
+		}
+	}
+	if len(lines) != len(want)+1 {
+		t.Errorf("error has %d lines, want the summary and %d errors:\n%s", len(lines), len(want), msg)
+	}
+}
+
+func TestLoadRejectsHTTPClientAuthWithoutCA(t *testing.T) {
+	isolate(t)
+	setRequiredEnv(t)
+	t.Setenv("HTTP_CLIENT_AUTH", "require")
+
+	_, err := Load(nil)
+	if err == nil || !strings.Contains(err.Error(), "HTTP_CLIENT_AUTH=require requires TLS_CLIENT_CA_FILE") {
+		t.Errorf("Load = %v, want an error about the missing client CA", err)
+	}
+}
@@ -0,0 +1,249 @@
+package featureflag
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"log/slog"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/grpc/metadata"
+
+	"github.com/watchlist-kata/review/internal/config"
+)
+
+// UserIDMetadataKey ключ gRPC метаданных с ID пользователя, по которому
+// выбирается группа при частичном включении флага
+const UserIDMetadataKey = "x-user-id"
+
+// reloadInterval задает период проверки файла флагов на изменения
+const reloadInterval = 5 * time.Second
+
+// buckets число групп, на которые делятся пользователи; дает точность 0.01%
+const buckets = 10000
+
+// Flag описывает флаг функциональности
+type Flag struct {
+	Enabled bool    // Флаг включен
+	Rollout float64 // Процент пользователей от 0 до 100, для которых включен флаг
+}
+
+// file формат JSON файла флагов:
+//
+//	{"flags": {"moderation": {"enabled": true, "rollout": 25}}}
+//
+// Если rollout не указан, включенный флаг действует для всех пользователей.
+type file struct {
+	Flags map[string]*struct {
+		Enabled bool     `json:"enabled"`
+		Rollout *float64 `json:"rollout"`
+	} `json:"flags"`
+}
+
+// Registry хранит флаги из конфигурации и JSON файла и заменяет их целиком при перезагрузке
+type Registry struct {
+	current atomic.Pointer[map[string]Flag]
+
+	mu      sync.Mutex // Сериализует перезагрузки
+	entries []string
+	path    string
+	modTime time.Time
+}
+
+// New создает реестр флагов из параметров FEATURE_FLAGS и FEATURE_FLAGS_FILE
+func New(cfg *config.Config) (*Registry, error) {
+	r := &Registry{}
+	if err := r.Apply(cfg); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Apply перечитывает флаги из конфигурации; при ошибке остаются прежние флаги.
+// Флаги из FEATURE_FLAGS переопределяют одноименные флаги из файла.
+func (r *Registry) Apply(cfg *config.Config) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.entries, r.path = cfg.FeatureFlags, cfg.FeatureFlagsFile
+	return r.reload()
+}
+
+// reload собирает флаги из файла и конфигурации и публикует их одной операцией
+func (r *Registry) reload() error {
+	// Время изменения запоминается и при ошибке, чтобы не повторять разбор того же файла
+	r.modTime = fileModTime(r.path)
+
+	flags := make(map[string]Flag)
+	if r.path != "" {
+		fromFile, err := loadFile(r.path)
+		if err != nil {
+			return err
+		}
+		for name, flag := range fromFile {
+			flags[name] = flag
+		}
+	}
+
+	fromConfig, err := Parse(r.entries)
+	if err != nil {
+		return err
+	}
+	for name, flag := range fromConfig {
+		flags[name] = flag
+	}
+
+	r.current.Store(&flags)
+	return nil
+}
+
+// Watch перечитывает файл флагов при его изменении, пока не будет отменен контекст
+func (r *Registry) Watch(ctx context.Context, logger *slog.Logger) {
+	ticker := time.NewTicker(reloadInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		r.mu.Lock()
+		if r.path != "" && !fileModTime(r.path).Equal(r.modTime) {
+			if err := r.reload(); err != nil {
+				logger.Error("failed to reload feature flags", slog.String("file", r.path), slog.Any("error", err))
+			} else {
+				logger.Info("feature flags reloaded", slog.String("file", r.path), slog.Any("flags", r.Names()))
+			}
+		}
+		r.mu.Unlock()
+	}
+}
+
+// Names возвращает имена известных флагов в алфавитном порядке
+func (r *Registry) Names() []string {
+	flags := *r.current.Load()
+	names := make([]string, 0, len(flags))
+	for name := range flags {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Enabled сообщает, включен ли флаг для пользователя из контекста запроса.
+// Неизвестный флаг выключен. При частичном включении пользователь без ID
+// в контексте считается не попавшим в группу.
+func (r *Registry) Enabled(ctx context.Context, name string) bool {
+	flag, ok := (*r.current.Load())[name]
+	if !ok || !flag.Enabled {
+		return false
+	}
+	if flag.Rollout >= 100 {
+		return true
+	}
+
+	userID, ok := UserID(ctx)
+	if !ok {
+		return false
+	}
+	return float64(bucket(name, userID)) < flag.Rollout*buckets/100
+}
+
+// UserID возвращает ID пользователя из входящих gRPC метаданных
+func UserID(ctx context.Context) (string, bool) {
+	values := metadata.ValueFromIncomingContext(ctx, UserIDMetadataKey)
+	if len(values) == 0 || values[0] == "" {
+		return "", false
+	}
+	return values[0], true
+}
+
+// bucket детерминированно относит пользователя к одной из групп. Имя флага входит
+// в хеш, чтобы разные флаги включались для разных подмножеств пользователей.
+func bucket(name, userID string) uint32 {
+	h := fnv.New32a()
+	h.Write([]byte(name))
+	h.Write([]byte{0})
+	h.Write([]byte(userID))
+	return h.Sum32() % buckets
+}
+
+// Parse разбирает флаги из конфигурации в формате name, name=true|false или name=N%
+func Parse(entries []string) (map[string]Flag, error) {
+	flags := make(map[string]Flag, len(entries))
+	for _, entry := range entries {
+		name, value, hasValue := strings.Cut(strings.TrimSpace(entry), "=")
+		if name == "" {
+			return nil, fmt.Errorf("invalid feature flag %q: empty name", entry)
+		}
+
+		flag := Flag{Enabled: true, Rollout: 100}
+		switch {
+		case !hasValue:
+		case strings.HasSuffix(value, "%"):
+			percent, err := strconv.ParseFloat(strings.TrimSuffix(value, "%"), 64)
+			if err != nil || percent < 0 || percent > 100 {
+				return nil, fmt.Errorf("invalid feature flag %q: rollout must be between 0%% and 100%%", entry)
+			}
+			flag.Rollout = percent
+		default:
+			enabled, err := strconv.ParseBool(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid feature flag %q: value must be true, false or a percentage", entry)
+			}
+			flag.Enabled = enabled
+		}
+		flags[name] = flag
+	}
+	return flags, nil
+}
+
+// loadFile читает флаги из JSON файла
+func loadFile(path string) (map[string]Flag, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read feature flags file: %w", err)
+	}
+
+	var doc file
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse feature flags file %s: %w", path, err)
+	}
+
+	flags := make(map[string]Flag, len(doc.Flags))
+	for name, def := range doc.Flags {
+		if def == nil {
+			return nil, fmt.Errorf("invalid feature flag %q in %s: empty definition", name, path)
+		}
+		flag := Flag{Enabled: def.Enabled, Rollout: 100}
+		if def.Rollout != nil {
+			if *def.Rollout < 0 || *def.Rollout > 100 {
+				return nil, fmt.Errorf("invalid feature flag %q in %s: rollout must be between 0 and 100", name, path)
+			}
+			flag.Rollout = *def.Rollout
+		}
+		flags[name] = flag
+	}
+	return flags, nil
+}
+
+// fileModTime возвращает время изменения файла; пустой путь или ошибка дают нулевое время
+func fileModTime(path string) time.Time {
+	if path == "" {
+		return time.Time{}
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
@@ -0,0 +1,101 @@
+package featureflag
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"testing"
+
+	"google.golang.org/grpc/metadata"
+
+	"github.com/watchlist-kata/review/internal/config"
+)
+
+// userContext возвращает контекст входящего запроса пользователя userID
+func userContext(userID string) context.Context {
+	return metadata.NewIncomingContext(context.Background(), metadata.Pairs(UserIDMetadataKey, userID))
+}
+
+func newRegistry(t *testing.T, flags ...string) *Registry {
+	t.Helper()
+	r, err := New(&config.Config{FeatureFlags: flags})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return r
+}
+
+func TestBucketIsDeterministic(t *testing.T) {
+	for _, userID := range []string{"1", "42", "user-7", ""} {
+		first := bucket("moderation", userID)
+		for i := 0; i < 3; i++ {
+			if got := bucket("moderation", userID); got != first {
+				t.Fatalf("bucket(moderation, %q) = %d, then %d", userID, first, got)
+			}
+		}
+		if first >= buckets {
+			t.Errorf("bucket(moderation, %q) = %d, want < %d", userID, first, buckets)
+		}
+	}
+
+	r := newRegistry(t, "moderation=50%")
+	for id := 0; id < 100; id++ {
+		ctx := userContext(strconv.Itoa(id))
+		if r.Enabled(ctx, "moderation") != r.Enabled(ctx, "moderation") {
+			t.Fatalf("Enabled for user %d is not stable", id)
+		}
+	}
+}
+
+func TestRolloutMatchesPercentage(t *testing.T) {
+	const users = 20000
+	for _, rollout := range []float64{0, 1, 10, 25, 50, 90, 100} {
+		t.Run(strconv.FormatFloat(rollout, 'f', -1, 64), func(t *testing.T) {
+			r := newRegistry(t, "moderation="+strconv.FormatFloat(rollout, 'f', -1, 64)+"%")
+			enabled := 0
+			for id := 0; id < users; id++ {
+				if r.Enabled(userContext(strconv.Itoa(id)), "moderation") {
+					enabled++
+				}
+			}
+			got := float64(enabled) * 100 / users
+			if math.Abs(got-rollout) > 1 {
+				t.Errorf("enabled for %.2f%% of users, want %v%% ± 1%%", got, rollout)
+			}
+		})
+	}
+}
+
+func TestRolloutGrowsMonotonically(t *testing.T) {
+	// Увеличение процента только добавляет пользователей в группу
+	small := newRegistry(t, "moderation=10%")
+	large := newRegistry(t, "moderation=30%")
+	for id := 0; id < 5000; id++ {
+		ctx := userContext(strconv.Itoa(id))
+		if small.Enabled(ctx, "moderation") && !large.Enabled(ctx, "moderation") {
+			t.Fatalf("user %d is in the 10%% rollout but not in the 30%% rollout", id)
+		}
+	}
+}
+
+func TestEnabled(t *testing.T) {
+	r := newRegistry(t, "on", "off=false", "half=50%")
+	tests := []struct {
+		name string
+		ctx  context.Context
+		flag string
+		want bool
+	}{
+		{"enabled flag", context.Background(), "on", true},
+		{"disabled flag", userContext("1"), "off", false},
+		{"unknown flag", userContext("1"), "missing", false},
+		{"partial rollout without user", context.Background(), "half", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := r.Enabled(tt.ctx, tt.flag); got != tt.want {
+				t.Errorf("Enabled(%q) = %v, want %v", tt.flag, got, tt.want)
+			}
+		})
+	}
+}
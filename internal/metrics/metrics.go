@@ -0,0 +1,210 @@
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+
+	"github.com/watchlist-kata/review/pkg/logger"
+)
+
+// namespace префикс имен всех метрик сервиса
+const namespace = "review"
+
+// Registry содержит все метрики сервиса
+var Registry = prometheus.NewRegistry()
+
+var (
+	// GRPCHandlingSeconds длительность обработки gRPC запросов по методу и коду ответа
+	GRPCHandlingSeconds = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "grpc_server",
+		Name:      "handling_seconds",
+		Help:      "Duration of gRPC requests by method and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "code"})
+
+	// GRPCActiveStreams количество открытых потоковых gRPC вызовов по методу
+	GRPCActiveStreams = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "grpc_server",
+		Name:      "active_streams",
+		Help:      "Number of open gRPC streams by method.",
+	}, []string{"method"})
+
+	// HTTPHandlingSeconds длительность обработки запросов REST шлюза по маршруту и HTTP статусу
+	HTTPHandlingSeconds = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http_server",
+		Name:      "handling_seconds",
+		Help:      "Duration of REST gateway requests by route and HTTP status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "code"})
+
+	// RepositoryQuerySeconds длительность запросов к базе данных по методу репозитория
+	RepositoryQuerySeconds = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "repository",
+		Name:      "query_seconds",
+		Help:      "Duration of repository queries by method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method"})
+
+	// ReviewsCreated количество созданных отзывов
+	ReviewsCreated = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "reviews_created_total",
+		Help:      "Total number of reviews created.",
+	})
+
+	// ReviewsUpdated количество обновленных отзывов
+	ReviewsUpdated = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "reviews_updated_total",
+		Help:      "Total number of reviews updated.",
+	})
+
+	// ReviewsDeleted количество удаленных отзывов (мягкое удаление, строка остается меткой удаления)
+	ReviewsDeleted = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "reviews_deleted_total",
+		Help:      "Total number of reviews soft-deleted.",
+	})
+
+	// TombstonesPurged количество меток удаления, окончательно удаленных по сроку хранения
+	TombstonesPurged = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "tombstones_purged_total",
+		Help:      "Total number of deleted reviews removed from the table after TOMBSTONE_RETENTION.",
+	})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		GRPCHandlingSeconds,
+		GRPCActiveStreams,
+		HTTPHandlingSeconds,
+		RepositoryQuerySeconds,
+		ReviewsCreated,
+		ReviewsUpdated,
+		ReviewsDeleted,
+		TombstonesPurged,
+	)
+}
+
+// Handler возвращает HTTP обработчик для /metrics
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
+
+// UnaryServerInterceptor измеряет длительность gRPC запросов
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		GRPCHandlingSeconds.WithLabelValues(info.FullMethod, status.Code(err).String()).Observe(time.Since(start).Seconds())
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor измеряет длительность потоковых gRPC вызовов и число открытых потоков
+func StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		active := GRPCActiveStreams.WithLabelValues(info.FullMethod)
+		active.Inc()
+		defer active.Dec()
+
+		start := time.Now()
+		err := handler(srv, ss)
+		GRPCHandlingSeconds.WithLabelValues(info.FullMethod, status.Code(err).String()).Observe(time.Since(start).Seconds())
+		return err
+	}
+}
+
+// ObserveQuery фиксирует длительность запроса репозитория, начатого в момент start
+func ObserveQuery(method string, start time.Time) {
+	RepositoryQuerySeconds.WithLabelValues(method).Observe(time.Since(start).Seconds())
+}
+
+// RegisterDBStats регистрирует метрики пула соединений базы данных
+func RegisterDBStats(db *sql.DB) error {
+	return Registry.Register(collectors.NewDBStatsCollector(db, "postgres"))
+}
+
+// RegisterLogQueues регистрирует метрики очередей асинхронных обработчиков логов
+func RegisterLogQueues(stats func() []logger.QueueStats) error {
+	return Registry.Register(&logQueueCollector{stats: stats})
+}
+
+var (
+	logQueueLengthDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "log", "queue_length"),
+		"Number of log records waiting in the handler queue.",
+		[]string{"handler"}, nil,
+	)
+	logQueueCapacityDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "log", "queue_capacity"),
+		"Capacity of the handler queue.",
+		[]string{"handler"}, nil,
+	)
+	logDroppedDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "log", "dropped_total"),
+		"Total number of log records dropped by the handler: reason=overflow when the queue was full, reason=shutdown when the queue was not drained before the deadline, reason=spool_full when the on-disk spool was full.",
+		[]string{"handler", "reason"}, nil,
+	)
+	logDegradedDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "log", "degraded"),
+		"Whether the handler is unavailable and spools log records to disk (1) or not (0).",
+		[]string{"handler"}, nil,
+	)
+	logSpoolRecordsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "log", "spool_records"),
+		"Number of log records waiting in the on-disk spool.",
+		[]string{"handler"}, nil,
+	)
+	logSpoolBytesDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "log", "spool_bytes"),
+		"Size of the log records waiting in the on-disk spool.",
+		[]string{"handler"}, nil,
+	)
+)
+
+// logQueueCollector собирает состояние очередей обработчиков логов в момент опроса
+type logQueueCollector struct {
+	stats func() []logger.QueueStats
+}
+
+func (c *logQueueCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- logQueueLengthDesc
+	ch <- logQueueCapacityDesc
+	ch <- logDroppedDesc
+	ch <- logDegradedDesc
+	ch <- logSpoolRecordsDesc
+	ch <- logSpoolBytesDesc
+}
+
+func (c *logQueueCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, s := range c.stats() {
+		ch <- prometheus.MustNewConstMetric(logQueueLengthDesc, prometheus.GaugeValue, float64(s.Length), s.Name)
+		ch <- prometheus.MustNewConstMetric(logQueueCapacityDesc, prometheus.GaugeValue, float64(s.Capacity), s.Name)
+		ch <- prometheus.MustNewConstMetric(logDroppedDesc, prometheus.CounterValue, float64(s.Dropped), s.Name, "overflow")
+		ch <- prometheus.MustNewConstMetric(logDroppedDesc, prometheus.CounterValue, float64(s.Lost), s.Name, "shutdown")
+		ch <- prometheus.MustNewConstMetric(logDroppedDesc, prometheus.CounterValue, float64(s.SpoolDropped), s.Name, "spool_full")
+		degraded := 0.0
+		if s.Degraded {
+			degraded = 1
+		}
+		ch <- prometheus.MustNewConstMetric(logDegradedDesc, prometheus.GaugeValue, degraded, s.Name)
+		ch <- prometheus.MustNewConstMetric(logSpoolRecordsDesc, prometheus.GaugeValue, float64(s.SpoolRecords), s.Name)
+		ch <- prometheus.MustNewConstMetric(logSpoolBytesDesc, prometheus.GaugeValue, float64(s.SpoolBytes), s.Name)
+	}
+}
@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+)
+
+// connectError возвращает ошибку подключения pgconn к порту, на котором никто не слушает
+func connectError(t *testing.T) error {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	addr := lis.Addr().String()
+	lis.Close()
+
+	conn, err := pgconn.Connect(context.Background(), "postgres://review@"+addr+"/review?connect_timeout=5")
+	if err == nil {
+		conn.Close(context.Background())
+		t.Fatalf("Connect to closed port %s succeeded", addr)
+	}
+	return err
+}
+
+func TestIsTransient(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "nil", err: nil, want: false},
+		{name: "bad connection", err: driver.ErrBadConn, want: true},
+		{name: "wrapped bad connection", err: fmt.Errorf("query: %w", driver.ErrBadConn), want: true},
+		{name: "connection failure", err: &pgconn.PgError{Code: "08006"}, want: true},
+		{name: "connection does not exist", err: &pgconn.PgError{Code: "08003"}, want: true},
+		{name: "serialization failure", err: &pgconn.PgError{Code: "40001"}, want: true},
+		{name: "deadlock", err: &pgconn.PgError{Code: "40P01"}, want: true},
+		{name: "too many connections", err: &pgconn.PgError{Code: "53300"}, want: true},
+		{name: "admin shutdown", err: fmt.Errorf("query: %w", &pgconn.PgError{Code: "57P01"}), want: true},
+		{name: "cannot connect now", err: &pgconn.PgError{Code: "57P03"}, want: true},
+		{name: "unique violation", err: &pgconn.PgError{Code: "23505"}, want: false},
+		{name: "syntax error", err: &pgconn.PgError{Code: "42601"}, want: false},
+		{name: "network error", err: &net.OpError{Op: "read", Net: "tcp", Err: errors.New("connection reset by peer")}, want: true},
+		{name: "record not found", err: gorm.ErrRecordNotFound, want: false},
+		{name: "review not found", err: ErrReviewNotFound, want: false},
+		{name: "changes compacted", err: ErrChangesCompacted, want: false},
+		{name: "context canceled", err: context.Canceled, want: false},
+		{name: "plain error", err: errors.New("boom"), want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsTransient(tt.err); got != tt.want {
+				t.Errorf("IsTransient(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsTransientConnectError(t *testing.T) {
+	err := connectError(t)
+
+	var connectErr *pgconn.ConnectError
+	if !errors.As(err, &connectErr) {
+		t.Fatalf("Connect error = %T %v, want *pgconn.ConnectError", err, err)
+	}
+	if !IsTransient(err) {
+		t.Errorf("IsTransient(%v) = false, want true", err)
+	}
+	if !IsTransient(fmt.Errorf("failed to connect to database: %w", err)) {
+		t.Errorf("IsTransient of a wrapped connect error = false, want true")
+	}
+}
@@ -2,6 +2,8 @@ package repository
 
 import (
 	"time"
+
+	"gorm.io/gorm"
 )
 
 // GormReview представляет модель отзыва в базе данных
@@ -13,9 +15,32 @@ type GormReview struct {
 	Rating    int       `gorm:"default:0"`      // Оценка отзыва
 	CreatedAt time.Time `gorm:"autoCreateTime"` // Дата создания
 	UpdatedAt time.Time `gorm:"autoUpdateTime"` // Дата обновления
+	// Номер последнего изменения в журнале; назначается базой данных
+	ChangeSeq int64 `gorm:"->"`
+	// ID транзакции последнего изменения; назначается базой данных
+	ChangeTxID int64 `gorm:"column:change_txid;->"`
+	// Дата удаления; удаленный отзыв остается в таблице как метка удаления для синхронизации
+	DeletedAt gorm.DeletedAt `gorm:"index"`
 }
 
 // TableName указывает GORM использовать имя таблицы "review"
 func (GormReview) TableName() string {
 	return "review"
 }
+
+// ChangeCursor позиция в журнале изменений: изменения упорядочены по ID транзакции,
+// а внутри транзакции - по номеру изменения
+type ChangeCursor struct {
+	TxID int64
+	Seq  int64
+}
+
+// Cursor возвращает позицию последнего изменения отзыва в журнале
+func (r GormReview) Cursor() ChangeCursor {
+	return ChangeCursor{TxID: r.ChangeTxID, Seq: r.ChangeSeq}
+}
+
+// Before сообщает, находится ли позиция в журнале раньше other
+func (c ChangeCursor) Before(other ChangeCursor) bool {
+	return c.TxID < other.TxID || c.TxID == other.TxID && c.Seq < other.Seq
+}
@@ -3,27 +3,45 @@ package repository
 import (
 	"context"
 	"errors"
-	"fmt"
 	"github.com/watchlist-kata/review/internal/config"
+	"github.com/watchlist-kata/review/internal/metrics"
+	"github.com/watchlist-kata/review/internal/tracing"
 	"github.com/watchlist-kata/review/pkg/utils"
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 	"gorm.io/gorm"
 	"log/slog"
+	"time"
 )
 
 var (
 	// ErrReviewNotFound возвращается, когда отзыв не найден
 	ErrReviewNotFound = errors.New("review not found")
+	// ErrChangesCompacted возвращается, когда метки удаления после запрошенной позиции
+	// журнала уже удалены по сроку хранения и клиенту нужна полная синхронизация
+	ErrChangesCompacted = errors.New("change feed position is older than retained tombstones")
 )
 
+// ReviewFilter задает условия выборки отзывов; nil поля не ограничивают выборку
+type ReviewFilter struct {
+	MediaID *uint
+	UserID  *uint
+	Rating  *int
+}
+
 type Repository interface {
 	Create(ctx context.Context, review *GormReview) error
 	GetByID(ctx context.Context, id uint) (*GormReview, error)
-	Update(ctx context.Context, review *GormReview) error
+	Update(ctx context.Context, review *GormReview, columns []string) error
 	Delete(ctx context.Context, id uint) error
 	GetAll(ctx context.Context) ([]GormReview, error)
 	GetByRating(ctx context.Context, rating int) ([]GormReview, error)
 	GetByUser(ctx context.Context, userID uint) ([]GormReview, error)
 	GetByMedia(ctx context.Context, mediaID uint) ([]GormReview, error)
+	List(ctx context.Context, filter ReviewFilter, afterID uint, limit int) ([]GormReview, error)
+	ListChanges(ctx context.Context, filter ReviewFilter, after ChangeCursor, limit int) ([]GormReview, error)
+	PurgeDeleted(ctx context.Context, before time.Time, limit int) (int64, error)
 }
 
 type PostgresRepository struct {
@@ -39,93 +57,166 @@ func NewPostgresRepository(cfg *config.Config, logger *slog.Logger) (*PostgresRe
 		return nil, err
 	}
 
+	// Регистрация метрик пула соединений
+	sqlDB, err := db.DB()
+	if err != nil {
+		logger.Error("failed to get database handle", slog.Any("error", err))
+		return nil, err
+	}
+	if err := metrics.RegisterDBStats(sqlDB); err != nil {
+		logger.Warn("failed to register database metrics", slog.Any("error", err))
+	}
+
+	// Схема изменяется только по явному DB_MIGRATE; иначе проверяется, что миграции применены
+	if cfg.DBMigrate {
+		applied, err := Migrate(db)
+		if err != nil {
+			logger.Error("failed to migrate database schema", slog.Any("error", err))
+			return nil, err
+		}
+		logger.Info("database schema migrated", slog.Int("applied", applied), slog.Int("version", SchemaVersion()))
+	} else if err := CheckSchema(db); err != nil {
+		logger.Error("database schema is outdated", slog.Any("error", err))
+		return nil, err
+	}
+
 	return &PostgresRepository{db: db, logger: logger}, nil
 }
 
+// startQuery начинает дочерний span запроса к базе данных; возвращаемая функция
+// завершает span и фиксирует длительность запроса в метриках
+func startQuery(ctx context.Context, method string) (context.Context, func()) {
+	start := time.Now()
+	ctx, span := tracing.Tracer().Start(ctx, "PostgresRepository."+method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("db.system", "postgresql")),
+	)
+	return ctx, func() {
+		metrics.ObserveQuery(method, start)
+		span.End()
+	}
+}
+
+// recordError отмечает ошибку в текущем span
+func recordError(ctx context.Context, err error) {
+	span := trace.SpanFromContext(ctx)
+	span.RecordError(err)
+	span.SetStatus(otelcodes.Error, err.Error())
+}
+
 func (r *PostgresRepository) Create(ctx context.Context, review *GormReview) error {
+	ctx, end := startQuery(ctx, "Create")
+	defer end()
+
 	select {
 	case <-ctx.Done():
-		r.logger.ErrorContext(ctx, fmt.Sprintf("Create operation canceled for review with media ID: %d and user ID: %d", review.MediaID, review.UserID), slog.Any("error", ctx.Err()))
+		r.logger.ErrorContext(ctx, "Create operation canceled", slog.Any("media_id", review.MediaID), slog.Any("user_id", review.UserID), slog.Any("error", ctx.Err()))
+		recordError(ctx, ctx.Err())
 		return ctx.Err()
 	default:
 	}
 
-	if err := r.db.Create(review).Error; err != nil {
-		r.logger.ErrorContext(ctx, fmt.Sprintf("failed to create review for media ID: %d and user ID: %d", review.MediaID, review.UserID), slog.Any("error", err))
+	if err := r.db.WithContext(ctx).Create(review).Error; err != nil {
+		r.logger.ErrorContext(ctx, "failed to create review", slog.Any("media_id", review.MediaID), slog.Any("user_id", review.UserID), slog.Any("error", err))
+		recordError(ctx, err)
 		return err
 	}
 
-	r.logger.InfoContext(ctx, fmt.Sprintf("review created successfully for media ID: %d and user ID: %d", review.MediaID, review.UserID))
+	r.logger.InfoContext(ctx, "review created successfully", slog.Any("media_id", review.MediaID), slog.Any("user_id", review.UserID))
 	return nil
 }
 
 func (r *PostgresRepository) GetByID(ctx context.Context, id uint) (*GormReview, error) {
+	ctx, end := startQuery(ctx, "GetByID")
+	defer end()
+
 	select {
 	case <-ctx.Done():
-		r.logger.ErrorContext(ctx, fmt.Sprintf("GetByID operation canceled for review ID: %d", id), slog.Any("error", ctx.Err()))
+		r.logger.ErrorContext(ctx, "GetByID operation canceled", slog.Any("review_id", id), slog.Any("error", ctx.Err()))
+		recordError(ctx, ctx.Err())
 		return nil, ctx.Err()
 	default:
 	}
 
 	var review GormReview
-	if err := r.db.First(&review, id).Error; err != nil {
+	if err := r.db.WithContext(ctx).First(&review, id).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			r.logger.WarnContext(ctx, fmt.Sprintf("review not found with ID: %d", id))
+			r.logger.WarnContext(ctx, "review not found", slog.Any("review_id", id))
 			return nil, ErrReviewNotFound
 		}
-		r.logger.ErrorContext(ctx, fmt.Sprintf("failed to get review by ID: %d", id), slog.Any("error", err))
+		r.logger.ErrorContext(ctx, "failed to get review", slog.Any("review_id", id), slog.Any("error", err))
+		recordError(ctx, err)
 		return nil, err
 	}
 
-	r.logger.InfoContext(ctx, fmt.Sprintf("review fetched successfully with ID: %d", id))
+	r.logger.InfoContext(ctx, "review fetched successfully", slog.Any("review_id", id))
 	return &review, nil
 }
 
-func (r *PostgresRepository) Update(ctx context.Context, review *GormReview) error {
+// Update записывает только перечисленные колонки отзыва и время обновления
+func (r *PostgresRepository) Update(ctx context.Context, review *GormReview, columns []string) error {
+	ctx, end := startQuery(ctx, "Update")
+	defer end()
+
 	select {
 	case <-ctx.Done():
-		r.logger.ErrorContext(ctx, fmt.Sprintf("Update operation canceled for review ID: %d", review.ID), slog.Any("error", ctx.Err()))
+		r.logger.ErrorContext(ctx, "Update operation canceled", slog.Any("review_id", review.ID), slog.Any("error", ctx.Err()))
+		recordError(ctx, ctx.Err())
 		return ctx.Err()
 	default:
 	}
 
-	if err := r.db.Save(review).Error; err != nil {
-		r.logger.ErrorContext(ctx, fmt.Sprintf("failed to update review with ID: %d", review.ID), slog.Any("error", err))
+	selected := append(columns[:len(columns):len(columns)], "updated_at")
+	if err := r.db.WithContext(ctx).Model(review).Select(selected).Updates(review).Error; err != nil {
+		r.logger.ErrorContext(ctx, "failed to update review", slog.Any("review_id", review.ID), slog.Any("error", err))
+		recordError(ctx, err)
 		return err
 	}
 
-	r.logger.InfoContext(ctx, fmt.Sprintf("review updated successfully with ID: %d", review.ID))
+	r.logger.InfoContext(ctx, "review updated successfully", slog.Any("review_id", review.ID))
 	return nil
 }
 
+// Delete мягко удаляет отзыв: строка остается в таблице с заполненным deleted_at и служит
+// меткой удаления в журнале изменений, а остальные методы ее больше не возвращают
 func (r *PostgresRepository) Delete(ctx context.Context, id uint) error {
+	ctx, end := startQuery(ctx, "Delete")
+	defer end()
+
 	select {
 	case <-ctx.Done():
-		r.logger.ErrorContext(ctx, fmt.Sprintf("Delete operation canceled for review ID: %d", id), slog.Any("error", ctx.Err()))
+		r.logger.ErrorContext(ctx, "Delete operation canceled", slog.Any("review_id", id), slog.Any("error", ctx.Err()))
+		recordError(ctx, ctx.Err())
 		return ctx.Err()
 	default:
 	}
 
-	if err := r.db.Delete(&GormReview{}, id).Error; err != nil {
-		r.logger.ErrorContext(ctx, fmt.Sprintf("failed to delete review with ID: %d", id), slog.Any("error", err))
+	if err := r.db.WithContext(ctx).Delete(&GormReview{}, id).Error; err != nil {
+		r.logger.ErrorContext(ctx, "failed to delete review", slog.Any("review_id", id), slog.Any("error", err))
+		recordError(ctx, err)
 		return err
 	}
 
-	r.logger.InfoContext(ctx, fmt.Sprintf("review deleted successfully with ID: %d", id))
+	r.logger.InfoContext(ctx, "review deleted successfully", slog.Any("review_id", id))
 	return nil
 }
 
 func (r *PostgresRepository) GetAll(ctx context.Context) ([]GormReview, error) {
+	ctx, end := startQuery(ctx, "GetAll")
+	defer end()
+
 	select {
 	case <-ctx.Done():
 		r.logger.ErrorContext(ctx, "GetAll operation canceled", slog.Any("error", ctx.Err()))
+		recordError(ctx, ctx.Err())
 		return nil, ctx.Err()
 	default:
 	}
 
 	var reviews []GormReview
-	if err := r.db.Find(&reviews).Error; err != nil {
+	if err := r.db.WithContext(ctx).Find(&reviews).Error; err != nil {
 		r.logger.ErrorContext(ctx, "failed to get all reviews", slog.Any("error", err))
+		recordError(ctx, err)
 		return nil, err
 	}
 
@@ -134,55 +225,209 @@ func (r *PostgresRepository) GetAll(ctx context.Context) ([]GormReview, error) {
 }
 
 func (r *PostgresRepository) GetByRating(ctx context.Context, rating int) ([]GormReview, error) {
+	ctx, end := startQuery(ctx, "GetByRating")
+	defer end()
+
 	select {
 	case <-ctx.Done():
-		r.logger.ErrorContext(ctx, fmt.Sprintf("GetByRating operation canceled for rating: %d", rating), slog.Any("error", ctx.Err()))
+		r.logger.ErrorContext(ctx, "GetByRating operation canceled", slog.Any("rating", rating), slog.Any("error", ctx.Err()))
+		recordError(ctx, ctx.Err())
 		return nil, ctx.Err()
 	default:
 	}
 
 	var reviews []GormReview
-	if err := r.db.Where("rating = ?", rating).Find(&reviews).Error; err != nil {
-		r.logger.ErrorContext(ctx, fmt.Sprintf("failed to get reviews by rating: %d", rating), slog.Any("error", err))
+	if err := r.db.WithContext(ctx).Where("rating = ?", rating).Find(&reviews).Error; err != nil {
+		r.logger.ErrorContext(ctx, "failed to get reviews by rating", slog.Any("rating", rating), slog.Any("error", err))
+		recordError(ctx, err)
 		return nil, err
 	}
 
-	r.logger.InfoContext(ctx, fmt.Sprintf("reviews fetched successfully by rating: %d", rating))
+	r.logger.InfoContext(ctx, "reviews fetched successfully by rating", slog.Any("rating", rating))
 	return reviews, nil
 }
 
 func (r *PostgresRepository) GetByUser(ctx context.Context, userID uint) ([]GormReview, error) {
+	ctx, end := startQuery(ctx, "GetByUser")
+	defer end()
+
 	select {
 	case <-ctx.Done():
-		r.logger.ErrorContext(ctx, fmt.Sprintf("GetByUser operation canceled for user ID: %d", userID), slog.Any("error", ctx.Err()))
+		r.logger.ErrorContext(ctx, "GetByUser operation canceled", slog.Any("user_id", userID), slog.Any("error", ctx.Err()))
+		recordError(ctx, ctx.Err())
 		return nil, ctx.Err()
 	default:
 	}
 
 	var reviews []GormReview
-	if err := r.db.Where("user_id = ?", userID).Find(&reviews).Error; err != nil {
-		r.logger.ErrorContext(ctx, fmt.Sprintf("failed to get reviews by user ID: %d", userID), slog.Any("error", err))
+	if err := r.db.WithContext(ctx).Where("user_id = ?", userID).Find(&reviews).Error; err != nil {
+		r.logger.ErrorContext(ctx, "failed to get reviews by user", slog.Any("user_id", userID), slog.Any("error", err))
+		recordError(ctx, err)
 		return nil, err
 	}
 
-	r.logger.InfoContext(ctx, fmt.Sprintf("reviews fetched successfully by user ID: %d", userID))
+	r.logger.InfoContext(ctx, "reviews fetched successfully by user", slog.Any("user_id", userID))
 	return reviews, nil
 }
 
 func (r *PostgresRepository) GetByMedia(ctx context.Context, mediaID uint) ([]GormReview, error) {
+	ctx, end := startQuery(ctx, "GetByMedia")
+	defer end()
+
+	select {
+	case <-ctx.Done():
+		r.logger.ErrorContext(ctx, "GetByMedia operation canceled", slog.Any("media_id", mediaID), slog.Any("error", ctx.Err()))
+		recordError(ctx, ctx.Err())
+		return nil, ctx.Err()
+	default:
+	}
+
+	var reviews []GormReview
+	if err := r.db.WithContext(ctx).Where("media_id = ?", mediaID).Find(&reviews).Error; err != nil {
+		r.logger.ErrorContext(ctx, "failed to get reviews by media", slog.Any("media_id", mediaID), slog.Any("error", err))
+		recordError(ctx, err)
+		return nil, err
+	}
+
+	r.logger.InfoContext(ctx, "reviews fetched successfully by media", slog.Any("media_id", mediaID))
+	return reviews, nil
+}
+
+// List возвращает не более limit отзывов, удовлетворяющих фильтру, с ID больше afterID
+// в порядке возрастания ID (keyset пагинация)
+func (r *PostgresRepository) List(ctx context.Context, filter ReviewFilter, afterID uint, limit int) ([]GormReview, error) {
+	ctx, end := startQuery(ctx, "List")
+	defer end()
+
 	select {
 	case <-ctx.Done():
-		r.logger.ErrorContext(ctx, fmt.Sprintf("GetByMedia operation canceled for media ID: %d", mediaID), slog.Any("error", ctx.Err()))
+		r.logger.ErrorContext(ctx, "List operation canceled", slog.Any("after_id", afterID), slog.Any("error", ctx.Err()))
+		recordError(ctx, ctx.Err())
 		return nil, ctx.Err()
 	default:
 	}
 
+	query := r.db.WithContext(ctx).Where("id > ?", afterID)
+	if filter.MediaID != nil {
+		query = query.Where("media_id = ?", *filter.MediaID)
+	}
+	if filter.UserID != nil {
+		query = query.Where("user_id = ?", *filter.UserID)
+	}
+	if filter.Rating != nil {
+		query = query.Where("rating = ?", *filter.Rating)
+	}
+
+	var reviews []GormReview
+	if err := query.Order("id").Limit(limit).Find(&reviews).Error; err != nil {
+		r.logger.ErrorContext(ctx, "failed to list reviews", slog.Any("after_id", afterID), slog.Any("error", err))
+		recordError(ctx, err)
+		return nil, err
+	}
+
+	r.logger.InfoContext(ctx, "reviews listed successfully", slog.Any("after_id", afterID))
+	return reviews, nil
+}
+
+// ListChanges возвращает не более limit отзывов, включая удаленные, измененных после
+// позиции after, в порядке позиций журнала.
+//
+// Номера изменений выдаются без блокировок, поэтому транзакция с меньшим номером
+// может зафиксироваться позже транзакции с большим. Чтобы клиент не пропустил такое
+// изменение, возвращаются только изменения транзакций старше самой старой еще
+// выполняющейся транзакции (xmin снимка), а позиция упорядочена сначала по ID транзакции:
+// любое изменение, которое станет видимым позже, получит позицию больше возвращенных.
+// Долгие пишущие транзакции задерживают выдачу изменений до своего завершения.
+// Если after раньше границы журнала (см. PurgeDeleted), возвращается ErrChangesCompacted.
+func (r *PostgresRepository) ListChanges(ctx context.Context, filter ReviewFilter, after ChangeCursor, limit int) ([]GormReview, error) {
+	ctx, end := startQuery(ctx, "ListChanges")
+	defer end()
+
+	select {
+	case <-ctx.Done():
+		r.logger.ErrorContext(ctx, "ListChanges operation canceled", slog.Any("after", after), slog.Any("error", ctx.Err()))
+		recordError(ctx, ctx.Err())
+		return nil, ctx.Err()
+	default:
+	}
+
+	query := r.db.WithContext(ctx).Unscoped().
+		Where("(change_txid, change_seq) > (?, ?)", after.TxID, after.Seq).
+		Where("change_txid < txid_snapshot_xmin(txid_current_snapshot())")
+	if filter.MediaID != nil {
+		query = query.Where("media_id = ?", *filter.MediaID)
+	}
+	if filter.UserID != nil {
+		query = query.Where("user_id = ?", *filter.UserID)
+	}
+	if filter.Rating != nil {
+		query = query.Where("rating = ?", *filter.Rating)
+	}
+
 	var reviews []GormReview
-	if err := r.db.Where("media_id = ?", mediaID).Find(&reviews).Error; err != nil {
-		r.logger.ErrorContext(ctx, fmt.Sprintf("failed to get reviews by media ID: %d", mediaID), slog.Any("error", err))
+	if err := query.Order("change_txid, change_seq").Limit(limit).Find(&reviews).Error; err != nil {
+		r.logger.ErrorContext(ctx, "failed to list changes", slog.Any("after", after), slog.Any("error", err))
+		recordError(ctx, err)
 		return nil, err
 	}
 
-	r.logger.InfoContext(ctx, fmt.Sprintf("reviews fetched successfully by media ID: %d", mediaID))
+	// Граница читается после изменений: если очистка зафиксировалась до чтения изменений,
+	// она видна здесь, а если после - прочитанные изменения еще содержат метки удаления.
+	// Пустой after означает полную синхронизацию, которой удаленные метки не нужны
+	if after != (ChangeCursor{}) {
+		var horizon []ChangeCursor
+		if err := r.db.WithContext(ctx).Raw(`SELECT change_txid AS tx_id, change_seq AS seq FROM review_change_horizon`).
+			Scan(&horizon).Error; err != nil {
+			r.logger.ErrorContext(ctx, "failed to read change feed horizon", slog.Any("after", after), slog.Any("error", err))
+			recordError(ctx, err)
+			return nil, err
+		}
+		if len(horizon) > 0 && after.Before(horizon[0]) {
+			r.logger.WarnContext(ctx, "change feed position is compacted", slog.Any("after", after), slog.Any("horizon", horizon[0]))
+			return nil, ErrChangesCompacted
+		}
+	}
+
+	r.logger.InfoContext(ctx, "changes listed successfully", slog.Any("after", after))
 	return reviews, nil
 }
+
+// PurgeDeleted окончательно удаляет не более limit отзывов, удаленных раньше before,
+// и возвращает их число. Позиция последнего удаленного изменения становится границей
+// журнала: синхронизация с более раннего токена требует полной синхронизации.
+func (r *PostgresRepository) PurgeDeleted(ctx context.Context, before time.Time, limit int) (int64, error) {
+	ctx, end := startQuery(ctx, "PurgeDeleted")
+	defer end()
+
+	select {
+	case <-ctx.Done():
+		r.logger.ErrorContext(ctx, "PurgeDeleted operation canceled", slog.Time("before", before), slog.Any("error", ctx.Err()))
+		recordError(ctx, ctx.Err())
+		return 0, ctx.Err()
+	default:
+	}
+
+	// Удаление строк и сдвиг границы выполняются одним выражением, поэтому клиент не может
+	// увидеть таблицу без меток удаления и со старой границей
+	var purged int64
+	err := r.db.WithContext(ctx).Raw(`WITH purged AS (
+	DELETE FROM review WHERE id IN (
+		SELECT id FROM review WHERE deleted_at < ? ORDER BY change_txid, change_seq LIMIT ?
+	) RETURNING change_txid, change_seq
+), last AS (
+	SELECT change_txid, change_seq FROM purged ORDER BY change_txid DESC, change_seq DESC LIMIT 1
+), horizon AS (
+	INSERT INTO review_change_horizon (id, change_txid, change_seq) SELECT true, change_txid, change_seq FROM last
+	ON CONFLICT (id) DO UPDATE SET change_txid = EXCLUDED.change_txid, change_seq = EXCLUDED.change_seq
+	WHERE (review_change_horizon.change_txid, review_change_horizon.change_seq) < (EXCLUDED.change_txid, EXCLUDED.change_seq)
+)
+SELECT count(*) FROM purged`, before, limit).Scan(&purged).Error
+	if err != nil {
+		r.logger.ErrorContext(ctx, "failed to purge deleted reviews", slog.Time("before", before), slog.Any("error", err))
+		recordError(ctx, err)
+		return 0, err
+	}
+
+	r.logger.InfoContext(ctx, "deleted reviews purged successfully", slog.Time("before", before), slog.Int64("count", purged))
+	return purged, nil
+}
@@ -0,0 +1,141 @@
+package repository
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// migrationLockKey ключ advisory блокировки, под которой применяются миграции, чтобы
+// одновременно запущенные экземпляры сервиса не применяли их параллельно
+const migrationLockKey = "review_schema_migrations"
+
+// migration версионированное изменение схемы; выражения выполняются в одной транзакции
+// вместе с записью версии в schema_migrations
+type migration struct {
+	version    int
+	name       string
+	statements []string
+}
+
+// migrations изменения схемы в порядке версий. Примененные миграции не изменяются,
+// новые добавляются в конец со следующей версией. Требуется PostgreSQL 11 или новее.
+var migrations = []migration{
+	{
+		// Журнал изменений: каждая вставка и изменение строки (включая мягкое удаление)
+		// получает следующий номер последовательности review_change_seq и ID транзакции,
+		// по которому ListChanges определяет, какие изменения уже нельзя пропустить.
+		// Номера выдаются без блокировок, поэтому запись не сериализуется.
+		version: 1,
+		name:    "change_feed",
+		statements: []string{
+			`CREATE SEQUENCE IF NOT EXISTS review_change_seq`,
+			`ALTER TABLE review ADD COLUMN IF NOT EXISTS change_seq bigint NOT NULL DEFAULT nextval('review_change_seq')`,
+			`ALTER TABLE review ADD COLUMN IF NOT EXISTS change_txid bigint NOT NULL DEFAULT txid_current()`,
+			`ALTER TABLE review ADD COLUMN IF NOT EXISTS deleted_at timestamptz`,
+			`CREATE INDEX IF NOT EXISTS idx_review_change ON review (change_txid, change_seq)`,
+			`CREATE INDEX IF NOT EXISTS idx_review_deleted_at ON review (deleted_at)`,
+			`CREATE OR REPLACE FUNCTION review_next_change() RETURNS trigger AS $$
+BEGIN
+	NEW.change_seq := nextval('review_change_seq');
+	NEW.change_txid := txid_current();
+	RETURN NEW;
+END
+$$ LANGUAGE plpgsql`,
+			`DROP TRIGGER IF EXISTS review_change_seq ON review`,
+			`CREATE TRIGGER review_change_seq BEFORE INSERT OR UPDATE ON review
+	FOR EACH ROW EXECUTE FUNCTION review_next_change()`,
+		},
+	},
+	{
+		// Граница журнала: позиция последней метки удаления, удаленной из таблицы по
+		// истечении TOMBSTONE_RETENTION. Клиент с токеном до границы мог не получить
+		// удаление, поэтому ListChanges возвращает ему ErrChangesCompacted.
+		version: 2,
+		name:    "change_feed_horizon",
+		statements: []string{
+			`CREATE TABLE IF NOT EXISTS review_change_horizon (
+	id boolean PRIMARY KEY DEFAULT true CHECK (id),
+	change_txid bigint NOT NULL,
+	change_seq bigint NOT NULL
+)`,
+		},
+	},
+}
+
+// SchemaVersion версия схемы, которую ожидает репозиторий
+func SchemaVersion() int {
+	return migrations[len(migrations)-1].version
+}
+
+// Migrate применяет еще не примененные миграции и возвращает их число
+func Migrate(db *gorm.DB) (int, error) {
+	applied := 0
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec(`SELECT pg_advisory_xact_lock(hashtext(?))`, migrationLockKey).Error; err != nil {
+			return fmt.Errorf("failed to lock schema migrations: %w", err)
+		}
+		if err := tx.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+	version integer PRIMARY KEY,
+	name text NOT NULL,
+	applied_at timestamptz NOT NULL DEFAULT now()
+)`).Error; err != nil {
+			return fmt.Errorf("failed to create schema_migrations table: %w", err)
+		}
+
+		current, err := schemaVersion(tx)
+		if err != nil {
+			return err
+		}
+		for _, m := range migrations {
+			if m.version <= current {
+				continue
+			}
+			for _, statement := range m.statements {
+				if err := tx.Exec(statement).Error; err != nil {
+					return fmt.Errorf("failed to apply migration %d %s: %w", m.version, m.name, err)
+				}
+			}
+			if err := tx.Exec(`INSERT INTO schema_migrations (version, name) VALUES (?, ?)`, m.version, m.name).Error; err != nil {
+				return fmt.Errorf("failed to record migration %d %s: %w", m.version, m.name, err)
+			}
+			applied++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return applied, nil
+}
+
+// CheckSchema проверяет, что к базе данных применены все миграции
+func CheckSchema(db *gorm.DB) error {
+	var exists bool
+	if err := db.Raw(`SELECT to_regclass('schema_migrations') IS NOT NULL`).Scan(&exists).Error; err != nil {
+		return fmt.Errorf("failed to check schema version: %w", err)
+	}
+	current := 0
+	if exists {
+		var err error
+		if current, err = schemaVersion(db); err != nil {
+			return err
+		}
+	}
+	if current < SchemaVersion() {
+		return fmt.Errorf("database schema version %d is older than required %d: apply migrations with DB_MIGRATE=true", current, SchemaVersion())
+	}
+	return nil
+}
+
+// schemaVersion возвращает версию последней примененной миграции
+func schemaVersion(db *gorm.DB) (int, error) {
+	var version *int
+	if err := db.Raw(`SELECT max(version) FROM schema_migrations`).Scan(&version).Error; err != nil {
+		return 0, fmt.Errorf("failed to read schema version: %w", err)
+	}
+	if version == nil {
+		return 0, nil
+	}
+	return *version, nil
+}
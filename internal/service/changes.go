@@ -0,0 +1,171 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/watchlist-kata/review/internal/repository"
+)
+
+const (
+	watchBatchSize     = 500             // Размер пакета чтения журнала при наблюдении
+	watchPollInterval  = 2 * time.Second // Период опроса журнала на изменения других экземпляров
+	changeTokenVersion = "t"             // Префикс токена журнала, отличающий его от токенов страниц
+)
+
+// changeNotifier будит наблюдателей после изменений, сделанных этим экземпляром сервиса
+type changeNotifier struct {
+	mu sync.Mutex
+	ch chan struct{}
+}
+
+func newChangeNotifier() *changeNotifier {
+	return &changeNotifier{ch: make(chan struct{})}
+}
+
+// wait возвращает канал, который закроется при следующем изменении
+func (n *changeNotifier) wait() <-chan struct{} {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.ch
+}
+
+// notify будит всех ожидающих наблюдателей
+func (n *changeNotifier) notify() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	close(n.ch)
+	n.ch = make(chan struct{})
+}
+
+// encodeChangeToken кодирует позицию в журнале в непрозрачный токен синхронизации
+func encodeChangeToken(cursor repository.ChangeCursor) string {
+	raw := changeTokenVersion + strconv.FormatInt(cursor.TxID, 10) + "." + strconv.FormatInt(cursor.Seq, 10)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// decodeChangeToken возвращает позицию в журнале, после которой продолжается синхронизация
+func decodeChangeToken(token string) (repository.ChangeCursor, error) {
+	var cursor repository.ChangeCursor
+	if token == "" {
+		return cursor, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return cursor, err
+	}
+	if len(raw) == 0 || string(raw[:1]) != changeTokenVersion {
+		return cursor, fmt.Errorf("unexpected change token version")
+	}
+	txID, seq, ok := strings.Cut(string(raw[1:]), ".")
+	if !ok {
+		return cursor, fmt.Errorf("malformed change token")
+	}
+	if cursor.TxID, err = strconv.ParseInt(txID, 10, 64); err != nil {
+		return cursor, err
+	}
+	if cursor.Seq, err = strconv.ParseInt(seq, 10, 64); err != nil {
+		return cursor, err
+	}
+	if cursor.TxID < 0 || cursor.Seq < 0 {
+		return cursor, fmt.Errorf("negative change position")
+	}
+	return cursor, nil
+}
+
+// syncChanges возвращает изменения после sinceToken, токен следующей синхронизации
+// и признак того, что изменения после этого токена еще есть
+func (s *ReviewService) syncChanges(ctx context.Context, filter repository.ReviewFilter, sinceToken string, pageSize int32) ([]repository.GormReview, string, bool, error) {
+	var violations []FieldViolation
+	if pageSize < 0 || pageSize > MaxPageSize {
+		violations = append(violations, FieldViolation{
+			Field:       "page_size",
+			Description: fmt.Sprintf("must be between 0 and %d", MaxPageSize),
+		})
+	}
+	after, err := decodeChangeToken(sinceToken)
+	if err != nil {
+		violations = append(violations, FieldViolation{Field: "since_token", Description: "malformed since token"})
+	}
+	if len(violations) > 0 {
+		return nil, "", false, s.fail(ctx, "invalid sync changes request", invalidArgument(violations...))
+	}
+
+	limit := int(pageSize)
+	if limit == 0 {
+		limit = DefaultPageSize
+	}
+
+	// Запрашиваем на одно изменение больше, чтобы узнать, есть ли продолжение
+	changes, err := s.repo.ListChanges(ctx, filter, after, limit+1)
+	if err != nil {
+		return nil, "", false, s.fail(ctx, "failed to list changes", err, slog.Any("after", after))
+	}
+
+	hasMore := len(changes) > limit
+	if hasMore {
+		changes = changes[:limit]
+	}
+	last := after
+	if len(changes) > 0 {
+		last = changes[len(changes)-1].Cursor()
+	}
+
+	s.logger.InfoContext(ctx, "changes synced successfully", slog.Any("after", after), slog.Int("count", len(changes)))
+	return changes, encodeChangeToken(last), hasMore, nil
+}
+
+// watchChanges передает в send изменения после sinceToken, а затем ждет новых изменений
+// этого экземпляра или периодически опрашивает журнал, пока не будет отменен контекст
+func (s *ReviewService) watchChanges(ctx context.Context, filter repository.ReviewFilter, sinceToken string,
+	send func(*repository.GormReview, string) error) error {
+	after, err := decodeChangeToken(sinceToken)
+	if err != nil {
+		return s.fail(ctx, "invalid watch reviews request",
+			invalidArgument(FieldViolation{Field: "since_token", Description: "malformed since token"}))
+	}
+
+	s.logger.InfoContext(ctx, "watching changes", slog.Any("after", after))
+
+	ticker := time.NewTicker(watchPollInterval)
+	defer ticker.Stop()
+
+	for {
+		// Подписываемся до чтения журнала, чтобы не пропустить изменение между чтением и ожиданием
+		wake := s.changes.wait()
+
+		changes, err := s.repo.ListChanges(ctx, filter, after, watchBatchSize)
+		if err != nil {
+			return s.fail(ctx, "failed to list changes", err, slog.Any("after", after))
+		}
+
+		for i := range changes {
+			if err := send(&changes[i], encodeChangeToken(changes[i].Cursor())); err != nil {
+				// Ошибка отправки после отмены контекста означает отключение клиента
+				if ctxErr := ctx.Err(); ctxErr != nil {
+					err = ctxErr
+				}
+				return s.fail(ctx, "failed to send change", err, slog.Any("change_seq", changes[i].ChangeSeq))
+			}
+			after = changes[i].Cursor()
+		}
+
+		if len(changes) == watchBatchSize {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			s.logger.InfoContext(ctx, "watch stopped", slog.Any("after", after))
+			return toStatus(ctx.Err())
+		case <-wake:
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/watchlist-kata/review/internal/repository"
+)
+
+func TestChangeTokenRoundTrip(t *testing.T) {
+	for _, cursor := range []repository.ChangeCursor{{}, {TxID: 1, Seq: 1}, {TxID: 1 << 40, Seq: 7}} {
+		got, err := decodeChangeToken(encodeChangeToken(cursor))
+		if err != nil {
+			t.Fatalf("decodeChangeToken(encodeChangeToken(%+v)): %v", cursor, err)
+		}
+		if got != cursor {
+			t.Errorf("round trip of %+v = %+v", cursor, got)
+		}
+	}
+}
+
+func TestDecodeChangeTokenRejectsMalformed(t *testing.T) {
+	tests := map[string]string{
+		"not base64":       "!!!",
+		"page token":       encodePageToken(5),
+		"sequence only":    base64.RawURLEncoding.EncodeToString([]byte("c5")),
+		"missing sequence": base64.RawURLEncoding.EncodeToString([]byte("t5")),
+		"negative":         base64.RawURLEncoding.EncodeToString([]byte("t5.-1")),
+		"not a number":     base64.RawURLEncoding.EncodeToString([]byte("tx.1")),
+	}
+	for name, token := range tests {
+		t.Run(name, func(t *testing.T) {
+			if cursor, err := decodeChangeToken(token); err == nil {
+				t.Errorf("decodeChangeToken(%q) = %+v, want error", token, cursor)
+			}
+		})
+	}
+}
+
+// purgingRepository удаляет метки удаления пакетами из remaining и запоминает границы
+type purgingRepository struct {
+	*fakeRepository
+	remaining int64
+	befores   []time.Time
+}
+
+func (r *purgingRepository) PurgeDeleted(_ context.Context, before time.Time, limit int) (int64, error) {
+	r.befores = append(r.befores, before)
+	purged := min(r.remaining, int64(limit))
+	r.remaining -= purged
+	return purged, nil
+}
+
+func TestCompactChangesPurgesInBatches(t *testing.T) {
+	repo := &purgingRepository{fakeRepository: newFakeRepository(), remaining: 2*compactionBatchSize + 1}
+	srv := newTestService(t, repo)
+
+	before := time.Now().Add(-time.Hour)
+	srv.compactChanges(context.Background(), before)
+
+	if repo.remaining != 0 {
+		t.Errorf("remaining = %d, want all tombstones purged", repo.remaining)
+	}
+	if len(repo.befores) != 3 {
+		t.Errorf("PurgeDeleted called %d times, want 3 batches", len(repo.befores))
+	}
+	for _, got := range repo.befores {
+		if !got.Equal(before) {
+			t.Errorf("PurgeDeleted before = %v, want %v", got, before)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/watchlist-kata/review/internal/metrics"
+)
+
+const (
+	compactionInterval  = time.Hour // Период очистки меток удаления
+	compactionBatchSize = 1000      // Число меток удаления, удаляемых одним запросом
+)
+
+// CompactChanges периодически окончательно удаляет отзывы, удаленные раньше чем
+// retention назад, пока не будет отменен контекст. Клиенты, чей токен синхронизации
+// старше удаленных меток, получают FailedPrecondition и начинают синхронизацию заново
+func (s *ReviewService) CompactChanges(ctx context.Context, retention time.Duration) {
+	ticker := time.NewTicker(compactionInterval)
+	defer ticker.Stop()
+
+	for {
+		s.compactChanges(ctx, time.Now().Add(-retention))
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// compactChanges удаляет метки удаления старше before пакетами по compactionBatchSize
+func (s *ReviewService) compactChanges(ctx context.Context, before time.Time) {
+	var total int64
+	for {
+		purged, err := s.repo.PurgeDeleted(ctx, before, compactionBatchSize)
+		if err != nil {
+			if ctx.Err() == nil {
+				s.logger.ErrorContext(ctx, "failed to compact change feed", slog.Time("before", before), slog.Any("error", err))
+			}
+			return
+		}
+		total += purged
+		metrics.TombstonesPurged.Add(float64(purged))
+		if purged < compactionBatchSize {
+			break
+		}
+	}
+
+	if total > 0 {
+		s.logger.InfoContext(ctx, "change feed compacted", slog.Time("before", before), slog.Int64("purged", total))
+	}
+}
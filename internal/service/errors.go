@@ -0,0 +1,160 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	"github.com/watchlist-kata/review/internal/repository"
+)
+
+// ErrorDomain домен ошибок сервиса в ErrorInfo
+const ErrorDomain = "review.watchlist-kata"
+
+// retryDelay рекомендуемая задержка перед повтором при временных ошибках
+const retryDelay = time.Second
+
+// Стабильные коды причин ошибок, передаваемые клиентам в ErrorInfo.Reason
+const (
+	ReasonInvalidArgument    = "INVALID_ARGUMENT"
+	ReasonReviewNotFound     = "REVIEW_NOT_FOUND"
+	ReasonSinceTokenExpired  = "SINCE_TOKEN_EXPIRED"
+	ReasonRequestCanceled    = "REQUEST_CANCELED"
+	ReasonDeadlineExceeded   = "DEADLINE_EXCEEDED"
+	ReasonStorageUnavailable = "STORAGE_UNAVAILABLE"
+	ReasonInternal           = "INTERNAL"
+)
+
+// Kind категория доменной ошибки
+type Kind int
+
+const (
+	KindInternal           Kind = iota // Внутренняя ошибка сервиса
+	KindInvalidArgument                // Некорректный запрос
+	KindNotFound                       // Отзыв не найден
+	KindCanceled                       // Запрос отменен клиентом
+	KindDeadlineExceeded               // Истекло время выполнения запроса
+	KindUnavailable                    // Временная недоступность, запрос можно повторить
+	KindFailedPrecondition             // Запрос невыполним в текущем состоянии, повтор не поможет
+)
+
+// FieldViolation описывает нарушение ограничения поля запроса
+type FieldViolation struct {
+	Field       string // Имя поля в запросе
+	Description string // Описание нарушения
+}
+
+// Error доменная ошибка сервиса. Message и Violations передаются клиенту,
+// Err содержит внутреннюю причину и попадает только в логи.
+type Error struct {
+	Kind       Kind
+	Reason     string
+	Message    string
+	Violations []FieldViolation
+	Err        error
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %v", e.Message, e.Err)
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// invalidArgument создает ошибку валидации с нарушениями полей
+func invalidArgument(violations ...FieldViolation) *Error {
+	return &Error{
+		Kind:       KindInvalidArgument,
+		Reason:     ReasonInvalidArgument,
+		Message:    "invalid request",
+		Violations: violations,
+	}
+}
+
+// classify приводит произвольную ошибку к доменной
+func classify(err error) *Error {
+	var domainErr *Error
+	if errors.As(err, &domainErr) {
+		return domainErr
+	}
+
+	switch {
+	case errors.Is(err, repository.ErrReviewNotFound):
+		return &Error{Kind: KindNotFound, Reason: ReasonReviewNotFound, Message: "review not found", Err: err}
+	case errors.Is(err, repository.ErrChangesCompacted):
+		return &Error{Kind: KindFailedPrecondition, Reason: ReasonSinceTokenExpired,
+			Message: "since token is older than the retained change history, restart sync with an empty token", Err: err}
+	case errors.Is(err, context.Canceled):
+		return &Error{Kind: KindCanceled, Reason: ReasonRequestCanceled, Message: "request canceled", Err: err}
+	case errors.Is(err, context.DeadlineExceeded):
+		return &Error{Kind: KindDeadlineExceeded, Reason: ReasonDeadlineExceeded, Message: "request deadline exceeded", Err: err}
+	case repository.IsTransient(err):
+		return &Error{Kind: KindUnavailable, Reason: ReasonStorageUnavailable, Message: "storage temporarily unavailable", Err: err}
+	default:
+		return &Error{Kind: KindInternal, Reason: ReasonInternal, Message: "internal error", Err: err}
+	}
+}
+
+// code возвращает gRPC код, соответствующий категории ошибки
+func (k Kind) code() codes.Code {
+	switch k {
+	case KindInvalidArgument:
+		return codes.InvalidArgument
+	case KindNotFound:
+		return codes.NotFound
+	case KindCanceled:
+		return codes.Canceled
+	case KindDeadlineExceeded:
+		return codes.DeadlineExceeded
+	case KindUnavailable:
+		return codes.Unavailable
+	case KindFailedPrecondition:
+		return codes.FailedPrecondition
+	default:
+		return codes.Internal
+	}
+}
+
+// toStatus преобразует ошибку в gRPC статус с деталями ErrorInfo, BadRequest и RetryInfo
+func toStatus(err error) error {
+	e := classify(err)
+
+	details := []protoadapt.MessageV1{
+		&errdetails.ErrorInfo{
+			Reason: e.Reason,
+			Domain: ErrorDomain,
+		},
+	}
+
+	if len(e.Violations) > 0 {
+		badRequest := &errdetails.BadRequest{}
+		for _, v := range e.Violations {
+			badRequest.FieldViolations = append(badRequest.FieldViolations, &errdetails.BadRequest_FieldViolation{
+				Field:       v.Field,
+				Description: v.Description,
+			})
+		}
+		details = append(details, badRequest)
+	}
+
+	if e.Kind == KindUnavailable {
+		details = append(details, &errdetails.RetryInfo{RetryDelay: durationpb.New(retryDelay)})
+	}
+
+	st, detailsErr := status.New(e.Kind.code(), e.Message).WithDetails(details...)
+	if detailsErr != nil {
+		return status.Error(e.Kind.code(), e.Message)
+	}
+	return st.Err()
+}
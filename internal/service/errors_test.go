@@ -0,0 +1,201 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/watchlist-kata/review/internal/repository"
+)
+
+// pgConnectError ошибка подключения к базе данных, как ее возвращает pgconn
+var pgConnectError = &pgconn.ConnectError{Config: &pgconn.Config{Host: "db", User: "review", Database: "review"}}
+
+func TestClassify(t *testing.T) {
+	domainErr := invalidArgument(FieldViolation{Field: "rating", Description: "must be between 1 and 10"})
+
+	tests := []struct {
+		name       string
+		err        error
+		wantKind   Kind
+		wantReason string
+	}{
+		{name: "domain error", err: domainErr, wantKind: KindInvalidArgument, wantReason: ReasonInvalidArgument},
+		{name: "wrapped domain error", err: fmt.Errorf("create: %w", domainErr), wantKind: KindInvalidArgument, wantReason: ReasonInvalidArgument},
+		{name: "not found", err: repository.ErrReviewNotFound, wantKind: KindNotFound, wantReason: ReasonReviewNotFound},
+		{name: "wrapped not found", err: fmt.Errorf("get: %w", repository.ErrReviewNotFound), wantKind: KindNotFound, wantReason: ReasonReviewNotFound},
+		{name: "changes compacted", err: repository.ErrChangesCompacted, wantKind: KindFailedPrecondition, wantReason: ReasonSinceTokenExpired},
+		{name: "canceled", err: context.Canceled, wantKind: KindCanceled, wantReason: ReasonRequestCanceled},
+		{name: "deadline", err: context.DeadlineExceeded, wantKind: KindDeadlineExceeded, wantReason: ReasonDeadlineExceeded},
+		{name: "connect error", err: pgConnectError, wantKind: KindUnavailable, wantReason: ReasonStorageUnavailable},
+		{name: "connection failure", err: &pgconn.PgError{Code: "08006"}, wantKind: KindUnavailable, wantReason: ReasonStorageUnavailable},
+		{name: "serialization failure", err: &pgconn.PgError{Code: "40001"}, wantKind: KindUnavailable, wantReason: ReasonStorageUnavailable},
+		{name: "constraint violation", err: &pgconn.PgError{Code: "23505"}, wantKind: KindInternal, wantReason: ReasonInternal},
+		{name: "plain error", err: errors.New("boom"), wantKind: KindInternal, wantReason: ReasonInternal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := classify(tt.err)
+			if got.Kind != tt.wantKind || got.Reason != tt.wantReason {
+				t.Errorf("classify(%v) = kind %d, reason %s, want kind %d, reason %s", tt.err, got.Kind, got.Reason, tt.wantKind, tt.wantReason)
+			}
+			// Внутренняя причина сохраняется для логов
+			if got != domainErr && !errors.Is(got, tt.err) {
+				t.Errorf("classify(%v) does not wrap the original error", tt.err)
+			}
+		})
+	}
+}
+
+// statusDetails возвращает детали gRPC статуса по типам
+func statusDetails(t *testing.T, err error) (*status.Status, *errdetails.ErrorInfo, *errdetails.BadRequest, *errdetails.RetryInfo) {
+	t.Helper()
+	st, ok := status.FromError(err)
+	if !ok {
+		t.Fatalf("error %v is not a gRPC status", err)
+	}
+	var (
+		info       *errdetails.ErrorInfo
+		badRequest *errdetails.BadRequest
+		retry      *errdetails.RetryInfo
+	)
+	for _, detail := range st.Details() {
+		switch d := detail.(type) {
+		case *errdetails.ErrorInfo:
+			info = d
+		case *errdetails.BadRequest:
+			badRequest = d
+		case *errdetails.RetryInfo:
+			retry = d
+		default:
+			t.Errorf("unexpected detail %T", detail)
+		}
+	}
+	return st, info, badRequest, retry
+}
+
+func TestToStatus(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantCode   codes.Code
+		wantReason string
+		wantMsg    string
+		wantRetry  bool
+	}{
+		{name: "invalid argument", err: invalidArgument(FieldViolation{Field: "id", Description: "must be positive"}),
+			wantCode: codes.InvalidArgument, wantReason: ReasonInvalidArgument, wantMsg: "invalid request"},
+		{name: "not found", err: repository.ErrReviewNotFound,
+			wantCode: codes.NotFound, wantReason: ReasonReviewNotFound, wantMsg: "review not found"},
+		{name: "changes compacted", err: repository.ErrChangesCompacted,
+			wantCode: codes.FailedPrecondition, wantReason: ReasonSinceTokenExpired,
+			wantMsg: "since token is older than the retained change history, restart sync with an empty token"},
+		{name: "canceled", err: context.Canceled,
+			wantCode: codes.Canceled, wantReason: ReasonRequestCanceled, wantMsg: "request canceled"},
+		{name: "deadline", err: context.DeadlineExceeded,
+			wantCode: codes.DeadlineExceeded, wantReason: ReasonDeadlineExceeded, wantMsg: "request deadline exceeded"},
+		{name: "transient", err: fmt.Errorf("failed to connect: %w", pgConnectError),
+			wantCode: codes.Unavailable, wantReason: ReasonStorageUnavailable, wantMsg: "storage temporarily unavailable", wantRetry: true},
+		{name: "internal", err: errors.New(`pq: relation "review" does not exist`),
+			wantCode: codes.Internal, wantReason: ReasonInternal, wantMsg: "internal error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st, info, _, retry := statusDetails(t, toStatus(tt.err))
+			if st.Code() != tt.wantCode || st.Message() != tt.wantMsg {
+				t.Errorf("status = %s %q, want %s %q", st.Code(), st.Message(), tt.wantCode, tt.wantMsg)
+			}
+			if info == nil || info.GetReason() != tt.wantReason || info.GetDomain() != ErrorDomain {
+				t.Errorf("ErrorInfo = %v, want reason %s, domain %s", info, tt.wantReason, ErrorDomain)
+			}
+			if (retry != nil) != tt.wantRetry {
+				t.Errorf("RetryInfo = %v, want present: %v", retry, tt.wantRetry)
+			}
+			if retry != nil && retry.GetRetryDelay().AsDuration() != retryDelay {
+				t.Errorf("RetryInfo delay = %v, want %v", retry.GetRetryDelay().AsDuration(), retryDelay)
+			}
+		})
+	}
+}
+
+func TestToStatusBadRequest(t *testing.T) {
+	err := toStatus(invalidArgument(
+		FieldViolation{Field: "rating", Description: "must be between 1 and 10"},
+		FieldViolation{Field: "filter.media_id", Description: "must be positive"},
+	))
+
+	_, _, badRequest, retry := statusDetails(t, err)
+	if badRequest == nil {
+		t.Fatal("status has no BadRequest")
+	}
+	want := []string{"rating: must be between 1 and 10", "filter.media_id: must be positive"}
+	var got []string
+	for _, v := range badRequest.GetFieldViolations() {
+		got = append(got, v.GetField()+": "+v.GetDescription())
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("field violations = %q, want %q", got, want)
+	}
+	if retry != nil {
+		t.Errorf("RetryInfo = %v, want none for invalid arguments", retry)
+	}
+
+	// Ошибки без нарушений полей не получают BadRequest
+	if _, _, badRequest, _ := statusDetails(t, toStatus(repository.ErrReviewNotFound)); badRequest != nil {
+		t.Errorf("not found status has BadRequest %v", badRequest)
+	}
+}
+
+// failingRepository возвращает err из GetByID
+type failingRepository struct {
+	*fakeRepository
+	err error
+}
+
+func (r *failingRepository) GetByID(context.Context, uint) (*repository.GormReview, error) {
+	return nil, r.err
+}
+
+func TestServiceErrorsReachClient(t *testing.T) {
+	const secret = `password=hunter2 host=db.internal`
+
+	tests := []struct {
+		name      string
+		err       error
+		wantCode  codes.Code
+		wantRetry bool
+	}{
+		{name: "not found", err: repository.ErrReviewNotFound, wantCode: codes.NotFound},
+		{name: "transient", err: fmt.Errorf("failed to connect (%s): %w", secret, pgConnectError), wantCode: codes.Unavailable, wantRetry: true},
+		{name: "internal", err: fmt.Errorf("query failed (%s): %w", secret, errors.New("driver: bad state")), wantCode: codes.Internal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newTestService(t, &failingRepository{fakeRepository: newFakeRepository(), err: tt.err})
+
+			_, err := srv.getReview(context.Background(), 1)
+
+			st, _, _, retry := statusDetails(t, err)
+			if st.Code() != tt.wantCode {
+				t.Errorf("code = %s, want %s", st.Code(), tt.wantCode)
+			}
+			if (retry != nil) != tt.wantRetry {
+				t.Errorf("RetryInfo = %v, want present: %v", retry, tt.wantRetry)
+			}
+			// Внутренняя причина попадает только в логи, но не клиенту
+			if strings.Contains(err.Error(), "hunter2") || strings.Contains(err.Error(), "driver: bad state") {
+				t.Errorf("status %q leaks the internal error", err)
+			}
+		})
+	}
+}
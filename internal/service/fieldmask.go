@@ -0,0 +1,104 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+)
+
+// UpdateMaskMetadataKey ключ gRPC метаданных, в котором клиенты v1 API передают
+// маску обновления через запятую, например "content,rating". В v1 сообщении
+// UpdateReviewRequest нет поля маски, поэтому метаданные являются частью контракта
+// v1 Update; без них обновляются только непустые поля. Новым клиентам следует
+// использовать review.v2 UpdateReview с полем update_mask.
+const UpdateMaskMetadataKey = "x-update-mask"
+
+// updateMaskAll путь маски, означающий замену всех изменяемых полей
+const updateMaskAll = "*"
+
+// updatableFields отображает пути маски обновления на колонки таблицы review
+var updatableFields = map[string]string{
+	"content": "content",
+	"rating":  "rating",
+}
+
+// reviewPatch содержит новые значения изменяемых полей отзыва
+type reviewPatch struct {
+	Content string
+	Rating  int32
+}
+
+// updateMaskFromContext читает маску обновления из входящих gRPC метаданных
+func updateMaskFromContext(ctx context.Context) (*fieldmaskpb.FieldMask, bool) {
+	values := metadata.ValueFromIncomingContext(ctx, UpdateMaskMetadataKey)
+	if len(values) == 0 {
+		return nil, false
+	}
+
+	mask := &fieldmaskpb.FieldMask{}
+	for _, value := range values {
+		for _, path := range strings.Split(value, ",") {
+			if path = strings.TrimSpace(path); path != "" {
+				mask.Paths = append(mask.Paths, path)
+			}
+		}
+	}
+	return mask, true
+}
+
+// inferUpdateMask строит маску по непустым полям, как это делал Update до появления масок
+func inferUpdateMask(patch reviewPatch) *fieldmaskpb.FieldMask {
+	mask := &fieldmaskpb.FieldMask{}
+	if patch.Content != "" {
+		mask.Paths = append(mask.Paths, "content")
+	}
+	if patch.Rating != 0 {
+		mask.Paths = append(mask.Paths, "rating")
+	}
+	return mask
+}
+
+// maskColumns проверяет пути маски и возвращает соответствующие им колонки
+func maskColumns(mask *fieldmaskpb.FieldMask) ([]string, []FieldViolation) {
+	var (
+		columns    []string
+		violations []FieldViolation
+		seen       = make(map[string]bool)
+	)
+
+	for _, path := range mask.GetPaths() {
+		if path == updateMaskAll {
+			if len(mask.GetPaths()) > 1 {
+				violations = append(violations, FieldViolation{
+					Field:       "update_mask",
+					Description: fmt.Sprintf("%q cannot be combined with other paths", updateMaskAll),
+				})
+				continue
+			}
+			for _, column := range updatableFields {
+				columns = append(columns, column)
+			}
+			sort.Strings(columns)
+			return columns, nil
+		}
+
+		column, ok := updatableFields[path]
+		if !ok {
+			violations = append(violations, FieldViolation{
+				Field:       "update_mask",
+				Description: fmt.Sprintf("unknown or immutable field path %q", path),
+			})
+			continue
+		}
+		if !seen[column] {
+			seen[column] = true
+			columns = append(columns, column)
+		}
+	}
+
+	return columns, violations
+}
@@ -0,0 +1,127 @@
+package service
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+
+	"github.com/watchlist-kata/protos/review"
+	"github.com/watchlist-kata/review/internal/repository"
+)
+
+func TestMaskColumns(t *testing.T) {
+	tests := []struct {
+		name       string
+		paths      []string
+		columns    []string
+		violations int
+	}{
+		{name: "single path", paths: []string{"content"}, columns: []string{"content"}},
+		{name: "duplicate paths", paths: []string{"rating", "rating"}, columns: []string{"rating"}},
+		{name: "all fields", paths: []string{"*"}, columns: []string{"content", "rating"}},
+		{name: "empty mask", paths: nil, columns: nil},
+		{name: "unknown path", paths: []string{"title"}, violations: 1},
+		{name: "immutable path", paths: []string{"user_id"}, violations: 1},
+		{name: "unknown with known path", paths: []string{"content", "id"}, columns: []string{"content"}, violations: 1},
+		{name: "wildcard with other path", paths: []string{"*", "content"}, columns: []string{"content"}, violations: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			columns, violations := maskColumns(&fieldmaskpb.FieldMask{Paths: tt.paths})
+			if !reflect.DeepEqual(columns, tt.columns) {
+				t.Errorf("columns = %v, want %v", columns, tt.columns)
+			}
+			if len(violations) != tt.violations {
+				t.Errorf("violations = %v, want %d", violations, tt.violations)
+			}
+			for _, violation := range violations {
+				if violation.Field != "update_mask" {
+					t.Errorf("violation field = %q, want update_mask", violation.Field)
+				}
+			}
+		})
+	}
+}
+
+func TestUpdateWritesOnlyMaskedColumns(t *testing.T) {
+	stored := repository.GormReview{ID: 1, MediaID: 10, UserID: 20, Content: "great", Rating: 8}
+
+	tests := []struct {
+		name    string
+		mask    string // Значение метаданных x-update-mask; пусто - без метаданных
+		req     *review.UpdateReviewRequest
+		columns []string
+		want    repository.GormReview
+	}{
+		{
+			name:    "clear content",
+			mask:    "content",
+			req:     &review.UpdateReviewRequest{Id: 1, Content: "", Rating: 3},
+			columns: []string{"content"},
+			want:    repository.GormReview{ID: 1, MediaID: 10, UserID: 20, Content: "", Rating: 8},
+		},
+		{
+			name:    "rating only",
+			mask:    "rating",
+			req:     &review.UpdateReviewRequest{Id: 1, Content: "ignored", Rating: 5},
+			columns: []string{"rating"},
+			want:    repository.GormReview{ID: 1, MediaID: 10, UserID: 20, Content: "great", Rating: 5},
+		},
+		{
+			name:    "all fields",
+			mask:    "*",
+			req:     &review.UpdateReviewRequest{Id: 1, Content: "fine", Rating: 6},
+			columns: []string{"content", "rating"},
+			want:    repository.GormReview{ID: 1, MediaID: 10, UserID: 20, Content: "fine", Rating: 6},
+		},
+		{
+			name:    "inferred from non-empty fields",
+			req:     &review.UpdateReviewRequest{Id: 1, Content: "", Rating: 4},
+			columns: []string{"rating"},
+			want:    repository.GormReview{ID: 1, MediaID: 10, UserID: 20, Content: "great", Rating: 4},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newFakeRepository(stored)
+			srv := newTestService(t, repo)
+
+			ctx := context.Background()
+			if tt.mask != "" {
+				ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(UpdateMaskMetadataKey, tt.mask))
+			}
+			if _, err := srv.Update(ctx, tt.req); err != nil {
+				t.Fatalf("Update: %v", err)
+			}
+
+			if !reflect.DeepEqual(repo.updateColumns, tt.columns) {
+				t.Errorf("written columns = %v, want %v", repo.updateColumns, tt.columns)
+			}
+			if got := repo.reviews[1]; !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("stored review = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpdateRejectsUnknownPath(t *testing.T) {
+	stored := repository.GormReview{ID: 1, MediaID: 10, UserID: 20, Content: "great", Rating: 8}
+	repo := newFakeRepository(stored)
+	srv := newTestService(t, repo)
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(UpdateMaskMetadataKey, "content,user_id"))
+	_, err := srv.Update(ctx, &review.UpdateReviewRequest{Id: 1, Content: "changed"})
+	assertInvalidArgument(t, err, "update_mask")
+
+	if repo.updateColumns != nil {
+		t.Errorf("written columns = %v, want none", repo.updateColumns)
+	}
+	if got := repo.reviews[1]; got != stored {
+		t.Errorf("stored review = %+v, want unchanged %+v", got, stored)
+	}
+}
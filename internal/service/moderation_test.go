@@ -0,0 +1,58 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc/metadata"
+
+	"github.com/watchlist-kata/protos/review"
+	"github.com/watchlist-kata/review/internal/featureflag"
+	"github.com/watchlist-kata/review/internal/repository"
+)
+
+func TestModerationRejectsEmptyContent(t *testing.T) {
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(featureflag.UserIDMetadataKey, "7"))
+	tests := map[string]struct {
+		flags   []string
+		content string
+		wantErr bool
+	}{
+		"flag off":        {content: "", wantErr: false},
+		"empty content":   {flags: []string{FlagModeration}, content: "", wantErr: true},
+		"blank content":   {flags: []string{FlagModeration}, content: " \n\t", wantErr: true},
+		"non-empty":       {flags: []string{FlagModeration}, content: "great", wantErr: false},
+		"flag disabled":   {flags: []string{FlagModeration + "=false"}, content: "", wantErr: false},
+		"rollout of 0%":   {flags: []string{FlagModeration + "=0%"}, content: "", wantErr: false},
+		"rollout of 100%": {flags: []string{FlagModeration + "=100%"}, content: "", wantErr: true},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			srv := newTestService(t, newFakeRepository(), tt.flags...)
+			_, err := srv.Create(ctx, &review.CreateReviewRequest{MediaId: 1, UserId: 7, Content: tt.content, Rating: 5})
+			if tt.wantErr {
+				assertInvalidArgument(t, err, "content")
+			} else if err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+		})
+	}
+}
+
+func TestModerationAppliesToMaskedContentOnly(t *testing.T) {
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(featureflag.UserIDMetadataKey, "7"))
+	repo := newFakeRepository(repository.GormReview{ID: 1, MediaID: 1, UserID: 7, Content: "ok", Rating: 5})
+	srv := newTestService(t, repo, FlagModeration)
+
+	// Без маски пустой текст не входит в обновление и не проверяется
+	if _, err := srv.Update(ctx, &review.UpdateReviewRequest{Id: 1, Rating: 6}); err != nil {
+		t.Fatalf("Update rating: %v", err)
+	}
+
+	maskCtx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(
+		featureflag.UserIDMetadataKey, "7",
+		UpdateMaskMetadataKey, "content",
+	))
+	_, err := srv.Update(maskCtx, &review.UpdateReviewRequest{Id: 1})
+	assertInvalidArgument(t, err, "content")
+}
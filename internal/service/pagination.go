@@ -0,0 +1,75 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"log/slog"
+	"strconv"
+
+	"github.com/watchlist-kata/review/internal/repository"
+)
+
+const (
+	DefaultPageSize = 50   // Размер страницы по умолчанию
+	MaxPageSize     = 1000 // Максимальный размер страницы
+)
+
+// encodePageToken кодирует ID последнего отзыва страницы в непрозрачный токен
+func encodePageToken(lastID uint) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatUint(uint64(lastID), 10)))
+}
+
+// decodePageToken возвращает ID, после которого начинается следующая страница
+func decodePageToken(token string) (uint, error) {
+	if token == "" {
+		return 0, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return 0, err
+	}
+	id, err := strconv.ParseUint(string(raw), 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	return uint(id), nil
+}
+
+// listReviews возвращает страницу отзывов, удовлетворяющих фильтру, и токен следующей страницы
+func (s *ReviewService) listReviews(ctx context.Context, filter repository.ReviewFilter, pageSize int32, pageToken string) ([]repository.GormReview, string, error) {
+	var violations []FieldViolation
+	if pageSize < 0 || pageSize > MaxPageSize {
+		violations = append(violations, FieldViolation{
+			Field:       "page_size",
+			Description: fmt.Sprintf("must be between 0 and %d", MaxPageSize),
+		})
+	}
+	afterID, err := decodePageToken(pageToken)
+	if err != nil {
+		violations = append(violations, FieldViolation{Field: "page_token", Description: "malformed page token"})
+	}
+	if len(violations) > 0 {
+		return nil, "", s.fail(ctx, "invalid list reviews request", invalidArgument(violations...))
+	}
+
+	limit := int(pageSize)
+	if limit == 0 {
+		limit = DefaultPageSize
+	}
+
+	// Запрашиваем на один отзыв больше, чтобы узнать, есть ли следующая страница
+	gormReviews, err := s.repo.List(ctx, filter, afterID, limit+1)
+	if err != nil {
+		return nil, "", s.fail(ctx, "failed to list reviews", err, slog.Any("after_id", afterID))
+	}
+
+	var nextPageToken string
+	if len(gormReviews) > limit {
+		gormReviews = gormReviews[:limit]
+		nextPageToken = encodePageToken(gormReviews[limit-1].ID)
+	}
+
+	s.logger.InfoContext(ctx, "reviews page fetched successfully", slog.Any("after_id", afterID))
+	return gormReviews, nextPageToken, nil
+}
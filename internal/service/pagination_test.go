@@ -0,0 +1,86 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"testing"
+
+	reviewv2 "github.com/watchlist-kata/review/api/proto/review/v2"
+	"github.com/watchlist-kata/review/internal/repository"
+)
+
+func TestPageTokenRoundTrip(t *testing.T) {
+	for _, id := range []uint{0, 1, 42, 1 << 40} {
+		got, err := decodePageToken(encodePageToken(id))
+		if err != nil {
+			t.Fatalf("decodePageToken(encodePageToken(%d)): %v", id, err)
+		}
+		if got != id {
+			t.Errorf("round trip of %d = %d", id, got)
+		}
+	}
+
+	if id, err := decodePageToken(""); err != nil || id != 0 {
+		t.Errorf("decodePageToken(\"\") = %d, %v; want 0, nil", id, err)
+	}
+}
+
+func TestDecodePageTokenRejectsMalformed(t *testing.T) {
+	tests := map[string]string{
+		"not base64":     "!!!",
+		"not a number":   base64.RawURLEncoding.EncodeToString([]byte("abc")),
+		"negative":       base64.RawURLEncoding.EncodeToString([]byte("-1")),
+		"padded base64":  base64.URLEncoding.EncodeToString([]byte("1")),
+		"trailing bytes": base64.RawURLEncoding.EncodeToString([]byte("12x")),
+	}
+	for name, token := range tests {
+		t.Run(name, func(t *testing.T) {
+			if id, err := decodePageToken(token); err == nil {
+				t.Errorf("decodePageToken(%q) = %d, want error", token, id)
+			}
+		})
+	}
+}
+
+func TestListReviewsPages(t *testing.T) {
+	var reviews []repository.GormReview
+	for id := uint(1); id <= 5; id++ {
+		reviews = append(reviews, repository.GormReview{ID: id, MediaID: 10, UserID: 20, Rating: 5})
+	}
+	srv := NewReviewServiceV2(newTestService(t, newFakeRepository(reviews...)))
+
+	var (
+		ids   []int64
+		token string
+	)
+	for page := 0; ; page++ {
+		if page > len(reviews) {
+			t.Fatal("pagination did not terminate")
+		}
+		resp, err := srv.ListReviews(context.Background(), &reviewv2.ListReviewsRequest{PageSize: 2, PageToken: token})
+		if err != nil {
+			t.Fatalf("ListReviews: %v", err)
+		}
+		for _, review := range resp.Reviews {
+			ids = append(ids, review.Id)
+		}
+		if token = resp.NextPageToken; token == "" {
+			break
+		}
+	}
+
+	if len(ids) != len(reviews) {
+		t.Fatalf("listed ids = %v, want 1..%d", ids, len(reviews))
+	}
+	for i, id := range ids {
+		if id != int64(i+1) {
+			t.Fatalf("listed ids = %v, want 1..%d", ids, len(reviews))
+		}
+	}
+}
+
+func TestListReviewsRejectsMalformedToken(t *testing.T) {
+	srv := NewReviewServiceV2(newTestService(t, newFakeRepository()))
+	_, err := srv.ListReviews(context.Background(), &reviewv2.ListReviewsRequest{PageToken: "!!!"})
+	assertInvalidArgument(t, err, "page_token")
+}
@@ -2,169 +2,286 @@ package service
 
 import (
 	"context"
-	"errors"
 	"fmt"
 	"log/slog"
+	"strings"
 	"time"
 
-	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
 
 	"github.com/watchlist-kata/protos/review"
+	"github.com/watchlist-kata/review/internal/featureflag"
+	"github.com/watchlist-kata/review/internal/metrics"
 	"github.com/watchlist-kata/review/internal/repository"
 )
 
+const (
+	minRating = 1  // Минимальная оценка
+	maxRating = 10 // Максимальная оценка
+)
+
+// FlagModeration флаг функциональности модерации: для пользователей, которым он
+// включен, отзыв не может быть создан или изменен с пустым текстом
+const FlagModeration = "moderation"
+
 type ReviewService struct {
 	review.UnimplementedReviewServiceServer
-	repo   repository.Repository
-	logger *slog.Logger
+	repo    repository.Repository
+	logger  *slog.Logger
+	changes *changeNotifier
+	flags   *featureflag.Registry
 }
 
-func NewReviewService(repo repository.Repository, logger *slog.Logger) *ReviewService {
+func NewReviewService(repo repository.Repository, logger *slog.Logger, flags *featureflag.Registry) *ReviewService {
 	return &ReviewService{
-		repo:   repo,
-		logger: logger,
+		repo:    repo,
+		logger:  logger,
+		changes: newChangeNotifier(),
+		flags:   flags,
 	}
 }
 
+// featureEnabled сообщает, включен ли флаг функциональности для пользователя,
+// переданного в метаданных запроса featureflag.UserIDMetadataKey
+func (s *ReviewService) featureEnabled(ctx context.Context, name string) bool {
+	return s.flags.Enabled(ctx, name)
+}
+
 func (s *ReviewService) checkContextCancelled(ctx context.Context, method string) error {
 	select {
 	case <-ctx.Done():
-		s.logger.ErrorContext(ctx, fmt.Sprintf("%s operation canceled", method), slog.Any("error", ctx.Err()))
+		s.logger.ErrorContext(ctx, "operation canceled", slog.String("operation", method), slog.Any("error", ctx.Err()))
 		return ctx.Err()
 	default:
 		return nil
 	}
 }
 
+// fail записывает ошибку в лог вместе с внутренней причиной и атрибутами и возвращает клиенту
+// gRPC статус, содержащий только безопасное сообщение и детали
+func (s *ReviewService) fail(ctx context.Context, msg string, err error, attrs ...slog.Attr) error {
+	e := classify(err)
+	level := slog.LevelWarn
+	if e.Kind == KindInternal || e.Kind == KindUnavailable {
+		level = slog.LevelError
+	}
+	attrs = append(attrs, slog.String("reason", e.Reason), slog.Any("error", err))
+	s.logger.LogAttrs(ctx, level, msg, attrs...)
+	return toStatus(e)
+}
+
+// validateRating проверяет, что оценка находится в допустимом диапазоне
+func validateRating(rating int32) []FieldViolation {
+	if rating < minRating || rating > maxRating {
+		return []FieldViolation{{Field: "rating", Description: fmt.Sprintf("must be between %d and %d", minRating, maxRating)}}
+	}
+	return nil
+}
+
+// validateContent проверяет текст отзыва по правилам модерации, если флаг
+// FlagModeration включен для пользователя из контекста запроса
+func (s *ReviewService) validateContent(ctx context.Context, content string) []FieldViolation {
+	if s.featureEnabled(ctx, FlagModeration) && strings.TrimSpace(content) == "" {
+		return []FieldViolation{{Field: "content", Description: "must not be empty"}}
+	}
+	return nil
+}
+
+// validateID проверяет, что идентификатор положителен
+func validateID(field string, id int64) []FieldViolation {
+	if id <= 0 {
+		return []FieldViolation{{Field: field, Description: "must be a positive number"}}
+	}
+	return nil
+}
+
 func (s *ReviewService) Create(ctx context.Context, req *review.CreateReviewRequest) (*review.CreateReviewResponse, error) {
 	if err := s.checkContextCancelled(ctx, "Create"); err != nil {
-		return nil, status.Error(codes.Canceled, err.Error())
+		return nil, toStatus(err)
 	}
 
-	if req.Rating < 1 || req.Rating > 10 {
-		s.logger.WarnContext(ctx, "invalid rating: must be between 1 and 10")
-		return nil, status.Errorf(codes.InvalidArgument, "Rating must be between 1 and 10")
+	gormReview, err := s.createReview(ctx, req.MediaId, req.UserId, req.Content, req.Rating)
+	if err != nil {
+		return nil, err
+	}
+
+	return &review.CreateReviewResponse{
+		Review: ConvertToProtoReview(gormReview),
+	}, nil
+}
+
+// createReview проверяет поля и создает отзыв
+func (s *ReviewService) createReview(ctx context.Context, mediaID, userID int64, content string, rating int32) (*repository.GormReview, error) {
+	var violations []FieldViolation
+	violations = append(violations, validateID("media_id", mediaID)...)
+	violations = append(violations, validateID("user_id", userID)...)
+	violations = append(violations, validateRating(rating)...)
+	violations = append(violations, s.validateContent(ctx, content)...)
+	if len(violations) > 0 {
+		return nil, s.fail(ctx, "invalid create review request", invalidArgument(violations...))
 	}
 
 	gormReview := &repository.GormReview{
-		MediaID: uint(req.MediaId),
-		UserID:  uint(req.UserId),
-		Content: req.Content,
-		Rating:  int(req.Rating),
+		MediaID: uint(mediaID),
+		UserID:  uint(userID),
+		Content: content,
+		Rating:  int(rating),
 	}
 
 	if err := s.repo.Create(ctx, gormReview); err != nil {
-		s.logger.ErrorContext(ctx, fmt.Sprintf("failed to create review for media ID: %d and user ID: %d", req.MediaId, req.UserId), slog.Any("error", err))
-		return nil, status.Errorf(codes.Internal, "Failed to create review: %v", err)
+		return nil, s.fail(ctx, "failed to create review", err, slog.Any("media_id", mediaID), slog.Any("user_id", userID))
 	}
 
-	protoReview := ConvertToProtoReview(gormReview)
-
-	s.logger.InfoContext(ctx, fmt.Sprintf("review created successfully for media ID: %d and user ID: %d", req.MediaId, req.UserId))
-	return &review.CreateReviewResponse{
-		Review: protoReview,
-	}, nil
+	metrics.ReviewsCreated.Inc()
+	s.changes.notify()
+	s.logger.InfoContext(ctx, "review created successfully", slog.Any("media_id", mediaID), slog.Any("user_id", userID))
+	return gormReview, nil
 }
 
 func (s *ReviewService) GetByID(ctx context.Context, req *review.GetReviewRequest) (*review.GetReviewResponse, error) {
 	if err := s.checkContextCancelled(ctx, "GetByID"); err != nil {
-		return nil, status.Error(codes.Canceled, err.Error())
+		return nil, toStatus(err)
 	}
 
-	gormReview, err := s.repo.GetByID(ctx, uint(req.Id))
+	gormReview, err := s.getReview(ctx, req.Id)
 	if err != nil {
-		if errors.Is(err, repository.ErrReviewNotFound) {
-			s.logger.WarnContext(ctx, fmt.Sprintf("review not found with ID: %d", req.Id))
-			return nil, status.Errorf(codes.NotFound, "Review not found: %v", err)
-		}
-		s.logger.ErrorContext(ctx, fmt.Sprintf("failed to get review by ID: %d", req.Id), slog.Any("error", err))
-		return nil, status.Errorf(codes.Internal, "Failed to get review: %v", err)
+		return nil, err
 	}
 
-	protoReview := ConvertToProtoReview(gormReview)
-
-	s.logger.InfoContext(ctx, fmt.Sprintf("review fetched successfully with ID: %d", req.Id))
 	return &review.GetReviewResponse{
-		Review: protoReview,
+		Review: ConvertToProtoReview(gormReview),
 	}, nil
 }
 
-func (s *ReviewService) Update(ctx context.Context, req *review.UpdateReviewRequest) (*review.UpdateReviewResponse, error) {
-	if err := s.checkContextCancelled(ctx, "Update"); err != nil {
-		return nil, status.Error(codes.Canceled, err.Error())
+// getReview возвращает отзыв по идентификатору
+func (s *ReviewService) getReview(ctx context.Context, id int64) (*repository.GormReview, error) {
+	if violations := validateID("id", id); len(violations) > 0 {
+		return nil, s.fail(ctx, "invalid get review request", invalidArgument(violations...))
 	}
 
-	gormReview, err := s.repo.GetByID(ctx, uint(req.Id))
+	gormReview, err := s.repo.GetByID(ctx, uint(id))
 	if err != nil {
-		if errors.Is(err, repository.ErrReviewNotFound) {
-			s.logger.WarnContext(ctx, fmt.Sprintf("review not found with ID: %d", req.Id))
-			return nil, status.Errorf(codes.NotFound, "Review not found: %v", err)
-		}
-		s.logger.ErrorContext(ctx, fmt.Sprintf("failed to get review for update with ID: %d", req.Id), slog.Any("error", err))
-		return nil, status.Errorf(codes.Internal, "Failed to get review: %v", err)
+		return nil, s.fail(ctx, "failed to get review", err, slog.Any("review_id", id))
 	}
 
-	if req.Content != "" {
-		gormReview.Content = req.Content
-	}
+	s.logger.InfoContext(ctx, "review fetched successfully", slog.Any("review_id", id))
+	return gormReview, nil
+}
 
-	if req.Rating != 0 {
-		if req.Rating < 1 || req.Rating > 10 {
-			s.logger.WarnContext(ctx, "invalid rating: must be between 1 and 10")
-			return nil, status.Errorf(codes.InvalidArgument, "Rating must be between 1 and 10")
-		}
-		gormReview.Rating = int(req.Rating)
+// Update изменяет поля отзыва, перечисленные в маске обновления. Маска передается
+// в метаданных UpdateMaskMetadataKey; без нее обновляются только непустые поля запроса.
+func (s *ReviewService) Update(ctx context.Context, req *review.UpdateReviewRequest) (*review.UpdateReviewResponse, error) {
+	if err := s.checkContextCancelled(ctx, "Update"); err != nil {
+		return nil, toStatus(err)
 	}
 
-	if err := s.repo.Update(ctx, gormReview); err != nil {
-		s.logger.ErrorContext(ctx, fmt.Sprintf("failed to update review with ID: %d", req.Id), slog.Any("error", err))
-		return nil, status.Errorf(codes.Internal, "Failed to update review: %v", err)
+	patch := reviewPatch{Content: req.Content, Rating: req.Rating}
+	mask, ok := updateMaskFromContext(ctx)
+	if !ok {
+		mask = inferUpdateMask(patch)
 	}
 
-	protoReview := ConvertToProtoReview(gormReview)
+	gormReview, err := s.updateReview(ctx, req.Id, patch, mask)
+	if err != nil {
+		return nil, err
+	}
 
-	s.logger.InfoContext(ctx, fmt.Sprintf("review updated successfully with ID: %d", req.Id))
 	return &review.UpdateReviewResponse{
-		Review: protoReview,
+		Review: ConvertToProtoReview(gormReview),
 	}, nil
 }
 
-func (s *ReviewService) Delete(ctx context.Context, req *review.DeleteReviewRequest) (*review.DeleteReviewResponse, error) {
-	if err := s.checkContextCancelled(ctx, "Delete"); err != nil {
-		return nil, status.Error(codes.Canceled, err.Error())
+// updateReview применяет к отзыву значения полей из маски и записывает только их
+func (s *ReviewService) updateReview(ctx context.Context, id int64, patch reviewPatch, mask *fieldmaskpb.FieldMask) (*repository.GormReview, error) {
+	columns, violations := maskColumns(mask)
+	violations = append(validateID("id", id), violations...)
+	for _, column := range columns {
+		switch column {
+		case "content":
+			violations = append(violations, s.validateContent(ctx, patch.Content)...)
+		case "rating":
+			violations = append(violations, validateRating(patch.Rating)...)
+		}
+	}
+	if len(violations) > 0 {
+		return nil, s.fail(ctx, "invalid update review request", invalidArgument(violations...))
 	}
 
-	_, err := s.repo.GetByID(ctx, uint(req.Id))
+	gormReview, err := s.repo.GetByID(ctx, uint(id))
 	if err != nil {
-		if errors.Is(err, repository.ErrReviewNotFound) {
-			s.logger.WarnContext(ctx, fmt.Sprintf("review not found with ID: %d", req.Id))
-			return nil, status.Errorf(codes.NotFound, "Review not found: %v", err)
+		return nil, s.fail(ctx, "failed to get review for update", err, slog.Any("review_id", id))
+	}
+
+	if len(columns) == 0 {
+		s.logger.InfoContext(ctx, "empty update mask, review left unchanged", slog.Any("review_id", id))
+		return gormReview, nil
+	}
+
+	for _, column := range columns {
+		switch column {
+		case "content":
+			gormReview.Content = patch.Content
+		case "rating":
+			gormReview.Rating = int(patch.Rating)
 		}
-		s.logger.ErrorContext(ctx, fmt.Sprintf("failed to check review existence with ID: %d", req.Id), slog.Any("error", err))
-		return nil, status.Errorf(codes.Internal, "Failed to check review existence: %v", err)
 	}
 
-	if err := s.repo.Delete(ctx, uint(req.Id)); err != nil {
-		s.logger.ErrorContext(ctx, fmt.Sprintf("failed to delete review with ID: %d", req.Id), slog.Any("error", err))
-		return nil, status.Errorf(codes.Internal, "Failed to delete review: %v", err)
+	if err := s.repo.Update(ctx, gormReview, columns); err != nil {
+		return nil, s.fail(ctx, "failed to update review", err, slog.Any("review_id", id))
+	}
+
+	metrics.ReviewsUpdated.Inc()
+	s.changes.notify()
+	s.logger.InfoContext(ctx, "review updated successfully", slog.Any("review_id", id))
+	return gormReview, nil
+}
+
+// Delete удаляет отзыв. С появлением журнала изменений удаление в v1, как и в v2, мягкое:
+// отзыв больше не возвращается методами сервиса, но строка остается в таблице review
+// с заполненным deleted_at как метка удаления для SyncChanges и WatchReviews и
+// окончательно удаляется по истечении TOMBSTONE_RETENTION (см. CompactChanges)
+func (s *ReviewService) Delete(ctx context.Context, req *review.DeleteReviewRequest) (*review.DeleteReviewResponse, error) {
+	if err := s.checkContextCancelled(ctx, "Delete"); err != nil {
+		return nil, toStatus(err)
+	}
+
+	if err := s.deleteReview(ctx, req.Id); err != nil {
+		return nil, err
 	}
 
-	s.logger.InfoContext(ctx, fmt.Sprintf("review deleted successfully with ID: %d", req.Id))
 	return &review.DeleteReviewResponse{
 		Success: true,
 	}, nil
 }
 
+// deleteReview удаляет существующий отзыв
+func (s *ReviewService) deleteReview(ctx context.Context, id int64) error {
+	if violations := validateID("id", id); len(violations) > 0 {
+		return s.fail(ctx, "invalid delete review request", invalidArgument(violations...))
+	}
+
+	if _, err := s.repo.GetByID(ctx, uint(id)); err != nil {
+		return s.fail(ctx, "failed to check review existence", err, slog.Any("review_id", id))
+	}
+
+	if err := s.repo.Delete(ctx, uint(id)); err != nil {
+		return s.fail(ctx, "failed to delete review", err, slog.Any("review_id", id))
+	}
+
+	metrics.ReviewsDeleted.Inc()
+	s.changes.notify()
+	s.logger.InfoContext(ctx, "review deleted successfully", slog.Any("review_id", id), slog.Bool("soft", true))
+	return nil
+}
+
 func (s *ReviewService) GetAll(ctx context.Context, req *review.GetAllReviewsRequest) (*review.GetAllReviewsResponse, error) {
 	if err := s.checkContextCancelled(ctx, "GetAll"); err != nil {
-		return nil, status.Error(codes.Canceled, err.Error())
+		return nil, toStatus(err)
 	}
 
 	gormReviews, err := s.repo.GetAll(ctx)
 	if err != nil {
-		s.logger.ErrorContext(ctx, "failed to get all reviews", slog.Any("error", err))
-		return nil, status.Errorf(codes.Internal, "Failed to get reviews: %v", err)
+		return nil, s.fail(ctx, "failed to get all reviews", err)
 	}
 
 	protoReviews := make([]*review.Review, 0, len(gormReviews))
@@ -180,18 +297,16 @@ func (s *ReviewService) GetAll(ctx context.Context, req *review.GetAllReviewsReq
 
 func (s *ReviewService) GetByRating(ctx context.Context, req *review.GetByRatingRequest) (*review.GetByRatingResponse, error) {
 	if err := s.checkContextCancelled(ctx, "GetByRating"); err != nil {
-		return nil, status.Error(codes.Canceled, err.Error())
+		return nil, toStatus(err)
 	}
 
-	if req.Rating < 1 || req.Rating > 10 {
-		s.logger.WarnContext(ctx, "invalid rating: must be between 1 and 10")
-		return nil, status.Errorf(codes.InvalidArgument, "Rating must be between 1 and 10")
+	if violations := validateRating(req.Rating); len(violations) > 0 {
+		return nil, s.fail(ctx, "invalid get by rating request", invalidArgument(violations...))
 	}
 
 	gormReviews, err := s.repo.GetByRating(ctx, int(req.Rating))
 	if err != nil {
-		s.logger.ErrorContext(ctx, fmt.Sprintf("failed to get reviews by rating: %d", req.Rating), slog.Any("error", err))
-		return nil, status.Errorf(codes.Internal, "Failed to get reviews by rating: %v", err)
+		return nil, s.fail(ctx, "failed to get reviews by rating", err, slog.Any("rating", req.Rating))
 	}
 
 	protoReviews := make([]*review.Review, 0, len(gormReviews))
@@ -199,7 +314,7 @@ func (s *ReviewService) GetByRating(ctx context.Context, req *review.GetByRating
 		protoReviews = append(protoReviews, ConvertToProtoReview(&gormReviews[i]))
 	}
 
-	s.logger.InfoContext(ctx, fmt.Sprintf("reviews fetched successfully by rating: %d", req.Rating))
+	s.logger.InfoContext(ctx, "reviews fetched successfully by rating", slog.Any("rating", req.Rating))
 	return &review.GetByRatingResponse{
 		Reviews: protoReviews,
 	}, nil
@@ -207,13 +322,16 @@ func (s *ReviewService) GetByRating(ctx context.Context, req *review.GetByRating
 
 func (s *ReviewService) GetByUser(ctx context.Context, req *review.GetByUserRequest) (*review.GetByUserResponse, error) {
 	if err := s.checkContextCancelled(ctx, "GetByUser"); err != nil {
-		return nil, status.Error(codes.Canceled, err.Error())
+		return nil, toStatus(err)
+	}
+
+	if violations := validateID("user_id", req.UserId); len(violations) > 0 {
+		return nil, s.fail(ctx, "invalid get by user request", invalidArgument(violations...))
 	}
 
 	gormReviews, err := s.repo.GetByUser(ctx, uint(req.UserId))
 	if err != nil {
-		s.logger.ErrorContext(ctx, fmt.Sprintf("failed to get reviews by user ID: %d", req.UserId), slog.Any("error", err))
-		return nil, status.Errorf(codes.Internal, "Failed to get reviews by user: %v", err)
+		return nil, s.fail(ctx, "failed to get reviews by user", err, slog.Any("user_id", req.UserId))
 	}
 
 	protoReviews := make([]*review.Review, 0, len(gormReviews))
@@ -221,7 +339,7 @@ func (s *ReviewService) GetByUser(ctx context.Context, req *review.GetByUserRequ
 		protoReviews = append(protoReviews, ConvertToProtoReview(&gormReviews[i]))
 	}
 
-	s.logger.InfoContext(ctx, fmt.Sprintf("reviews fetched successfully by user ID: %d", req.UserId))
+	s.logger.InfoContext(ctx, "reviews fetched successfully by user", slog.Any("user_id", req.UserId))
 	return &review.GetByUserResponse{
 		Reviews: protoReviews,
 	}, nil
@@ -229,13 +347,16 @@ func (s *ReviewService) GetByUser(ctx context.Context, req *review.GetByUserRequ
 
 func (s *ReviewService) GetByMedia(ctx context.Context, req *review.GetByMediaRequest) (*review.GetByMediaResponse, error) {
 	if err := s.checkContextCancelled(ctx, "GetByMedia"); err != nil {
-		return nil, status.Error(codes.Canceled, err.Error())
+		return nil, toStatus(err)
+	}
+
+	if violations := validateID("media_id", req.MediaId); len(violations) > 0 {
+		return nil, s.fail(ctx, "invalid get by media request", invalidArgument(violations...))
 	}
 
 	gormReviews, err := s.repo.GetByMedia(ctx, uint(req.MediaId))
 	if err != nil {
-		s.logger.ErrorContext(ctx, fmt.Sprintf("failed to get reviews by media ID: %d", req.MediaId), slog.Any("error", err))
-		return nil, status.Errorf(codes.Internal, "Failed to get reviews by media: %v", err)
+		return nil, s.fail(ctx, "failed to get reviews by media", err, slog.Any("media_id", req.MediaId))
 	}
 
 	protoReviews := make([]*review.Review, 0, len(gormReviews))
@@ -243,7 +364,7 @@ func (s *ReviewService) GetByMedia(ctx context.Context, req *review.GetByMediaRe
 		protoReviews = append(protoReviews, ConvertToProtoReview(&gormReviews[i]))
 	}
 
-	s.logger.InfoContext(ctx, fmt.Sprintf("reviews fetched successfully by media ID: %d", req.MediaId))
+	s.logger.InfoContext(ctx, "reviews fetched successfully by media", slog.Any("media_id", req.MediaId))
 	return &review.GetByMediaResponse{
 		Reviews: protoReviews,
 	}, nil
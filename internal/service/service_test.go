@@ -0,0 +1,162 @@
+package service
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/watchlist-kata/review/internal/config"
+	"github.com/watchlist-kata/review/internal/featureflag"
+	"github.com/watchlist-kata/review/internal/repository"
+)
+
+// fakeRepository хранит отзывы в памяти и запоминает колонки последнего Update
+type fakeRepository struct {
+	mu            sync.Mutex
+	reviews       map[uint]repository.GormReview
+	nextID        uint
+	updateColumns []string
+}
+
+func newFakeRepository(reviews ...repository.GormReview) *fakeRepository {
+	r := &fakeRepository{reviews: make(map[uint]repository.GormReview)}
+	for _, review := range reviews {
+		r.reviews[review.ID] = review
+		r.nextID = max(r.nextID, review.ID)
+	}
+	return r
+}
+
+func (r *fakeRepository) Create(_ context.Context, review *repository.GormReview) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.nextID++
+	review.ID = r.nextID
+	r.reviews[review.ID] = *review
+	return nil
+}
+
+func (r *fakeRepository) GetByID(_ context.Context, id uint) (*repository.GormReview, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	review, ok := r.reviews[id]
+	if !ok {
+		return nil, repository.ErrReviewNotFound
+	}
+	return &review, nil
+}
+
+// Update записывает только перечисленные колонки, как PostgresRepository
+func (r *fakeRepository) Update(_ context.Context, review *repository.GormReview, columns []string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored, ok := r.reviews[review.ID]
+	if !ok {
+		return repository.ErrReviewNotFound
+	}
+	for _, column := range columns {
+		switch column {
+		case "content":
+			stored.Content = review.Content
+		case "rating":
+			stored.Rating = review.Rating
+		}
+	}
+	r.reviews[review.ID] = stored
+	r.updateColumns = append([]string(nil), columns...)
+	return nil
+}
+
+func (r *fakeRepository) Delete(_ context.Context, id uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.reviews, id)
+	return nil
+}
+
+func (r *fakeRepository) GetAll(_ context.Context) ([]repository.GormReview, error) {
+	return r.filter(func(repository.GormReview) bool { return true }), nil
+}
+
+func (r *fakeRepository) GetByRating(_ context.Context, rating int) ([]repository.GormReview, error) {
+	return r.filter(func(review repository.GormReview) bool { return review.Rating == rating }), nil
+}
+
+func (r *fakeRepository) GetByUser(_ context.Context, userID uint) ([]repository.GormReview, error) {
+	return r.filter(func(review repository.GormReview) bool { return review.UserID == userID }), nil
+}
+
+func (r *fakeRepository) GetByMedia(_ context.Context, mediaID uint) ([]repository.GormReview, error) {
+	return r.filter(func(review repository.GormReview) bool { return review.MediaID == mediaID }), nil
+}
+
+func (r *fakeRepository) List(_ context.Context, filter repository.ReviewFilter, afterID uint, limit int) ([]repository.GormReview, error) {
+	reviews := r.filter(func(review repository.GormReview) bool {
+		return review.ID > afterID &&
+			(filter.MediaID == nil || review.MediaID == *filter.MediaID) &&
+			(filter.UserID == nil || review.UserID == *filter.UserID)
+	})
+	if len(reviews) > limit {
+		reviews = reviews[:limit]
+	}
+	return reviews, nil
+}
+
+func (r *fakeRepository) ListChanges(context.Context, repository.ReviewFilter, repository.ChangeCursor, int) ([]repository.GormReview, error) {
+	return nil, nil
+}
+
+func (r *fakeRepository) PurgeDeleted(context.Context, time.Time, int) (int64, error) {
+	return 0, nil
+}
+
+// filter возвращает отзывы, удовлетворяющие условию, в порядке ID
+func (r *fakeRepository) filter(match func(repository.GormReview) bool) []repository.GormReview {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var reviews []repository.GormReview
+	for _, review := range r.reviews {
+		if match(review) {
+			reviews = append(reviews, review)
+		}
+	}
+	sort.Slice(reviews, func(i, j int) bool { return reviews[i].ID < reviews[j].ID })
+	return reviews
+}
+
+// newTestService создает сервис поверх репозитория с флагами из entries
+func newTestService(t *testing.T, repo repository.Repository, flags ...string) *ReviewService {
+	t.Helper()
+	registry, err := featureflag.New(&config.Config{FeatureFlags: flags})
+	if err != nil {
+		t.Fatalf("featureflag.New: %v", err)
+	}
+	return NewReviewService(repo, slog.New(slog.NewTextHandler(io.Discard, nil)), registry)
+}
+
+// assertInvalidArgument проверяет, что err - InvalidArgument с нарушением поля field
+func assertInvalidArgument(t *testing.T, err error, field string) {
+	t.Helper()
+	st, ok := status.FromError(err)
+	if !ok || st.Code() != codes.InvalidArgument {
+		t.Fatalf("error = %v, want InvalidArgument", err)
+	}
+	for _, detail := range st.Details() {
+		if badRequest, ok := detail.(*errdetails.BadRequest); ok {
+			for _, violation := range badRequest.GetFieldViolations() {
+				if violation.GetField() == field {
+					return
+				}
+			}
+		}
+	}
+	t.Fatalf("error %v has no BadRequest violation of field %q", err, field)
+}
@@ -0,0 +1,71 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/watchlist-kata/review/internal/repository"
+)
+
+const (
+	defaultStreamBatchSize = 500  // Размер пакета чтения по умолчанию
+	maxStreamBatchSize     = 5000 // Максимальный размер пакета чтения
+)
+
+// streamReviews читает отзывы пакетами по ID (keyset) и передает каждый в send вместе
+// с токеном, по которому поток можно продолжить. send блокируется, пока клиент
+// не готов принять сообщение, поэтому в памяти находится не больше одного пакета.
+func (s *ReviewService) streamReviews(ctx context.Context, filter repository.ReviewFilter, resumeToken string, batchSize int32,
+	send func(*repository.GormReview, string) error) error {
+	var violations []FieldViolation
+	if batchSize < 0 || batchSize > maxStreamBatchSize {
+		violations = append(violations, FieldViolation{
+			Field:       "batch_size",
+			Description: fmt.Sprintf("must be between 0 and %d", maxStreamBatchSize),
+		})
+	}
+	afterID, err := decodePageToken(resumeToken)
+	if err != nil {
+		violations = append(violations, FieldViolation{Field: "resume_token", Description: "malformed resume token"})
+	}
+	if len(violations) > 0 {
+		return s.fail(ctx, "invalid stream reviews request", invalidArgument(violations...))
+	}
+
+	limit := int(batchSize)
+	if limit == 0 {
+		limit = defaultStreamBatchSize
+	}
+
+	startID, sent := afterID, 0
+	for {
+		if err := s.checkContextCancelled(ctx, "StreamReviews"); err != nil {
+			return toStatus(err)
+		}
+
+		batch, err := s.repo.List(ctx, filter, afterID, limit)
+		if err != nil {
+			return s.fail(ctx, "failed to read reviews batch", err, slog.Any("after_id", afterID))
+		}
+
+		for i := range batch {
+			if err := send(&batch[i], encodePageToken(batch[i].ID)); err != nil {
+				// Ошибка отправки после отмены контекста означает отключение клиента
+				if ctxErr := ctx.Err(); ctxErr != nil {
+					err = ctxErr
+				}
+				return s.fail(ctx, "failed to send review", err, slog.Any("review_id", batch[i].ID))
+			}
+			afterID = batch[i].ID
+			sent++
+		}
+
+		if len(batch) < limit {
+			break
+		}
+	}
+
+	s.logger.InfoContext(ctx, "reviews streamed successfully", slog.Any("after_id", startID), slog.Int("sent", sent))
+	return nil
+}
@@ -0,0 +1,289 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	reviewv2 "github.com/watchlist-kata/review/api/proto/review/v2"
+	"github.com/watchlist-kata/review/internal/repository"
+)
+
+// fakeStream сервер потока StreamReviews: запоминает отправленные сообщения
+// и вызывает onSend перед каждой отправкой
+type fakeStream struct {
+	grpc.ServerStream
+	ctx    context.Context
+	sent   []*reviewv2.StreamReviewsResponse
+	onSend func(n int) error // n - номер отправляемого сообщения, начиная с 1
+}
+
+func (s *fakeStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *fakeStream) Send(resp *reviewv2.StreamReviewsResponse) error {
+	if s.onSend != nil {
+		if err := s.onSend(len(s.sent) + 1); err != nil {
+			return err
+		}
+	}
+	s.sent = append(s.sent, resp)
+	return nil
+}
+
+// ids возвращает ID отправленных отзывов
+func (s *fakeStream) ids() []int64 {
+	ids := make([]int64, 0, len(s.sent))
+	for _, resp := range s.sent {
+		ids = append(ids, resp.GetReview().GetId())
+	}
+	return ids
+}
+
+// batchRecordingRepository запоминает afterID и limit каждого вызова List
+type batchRecordingRepository struct {
+	*fakeRepository
+	mu      sync.Mutex
+	batches []string
+}
+
+func (r *batchRecordingRepository) List(ctx context.Context, filter repository.ReviewFilter, afterID uint, limit int) ([]repository.GormReview, error) {
+	r.mu.Lock()
+	r.batches = append(r.batches, fmt.Sprintf("after %d limit %d", afterID, limit))
+	r.mu.Unlock()
+	return r.fakeRepository.List(ctx, filter, afterID, limit)
+}
+
+// newStreamRepository создает репозиторий с отзывами с ID от 1 до n
+func newStreamRepository(n int) *batchRecordingRepository {
+	reviews := make([]repository.GormReview, 0, n)
+	for id := 1; id <= n; id++ {
+		reviews = append(reviews, repository.GormReview{ID: uint(id), MediaID: 1, UserID: 1, Content: "review", Rating: 5})
+	}
+	return &batchRecordingRepository{fakeRepository: newFakeRepository(reviews...)}
+}
+
+func TestStreamReviewsBatchSizing(t *testing.T) {
+	tests := []struct {
+		name        string
+		reviews     int
+		batchSize   int32
+		wantBatches []string
+	}{
+		{
+			name:        "explicit batch size",
+			reviews:     5,
+			batchSize:   2,
+			wantBatches: []string{"after 0 limit 2", "after 2 limit 2", "after 4 limit 2"},
+		},
+		{
+			// Полный последний пакет требует еще одного чтения, чтобы узнать о конце выборки
+			name:        "exact multiple",
+			reviews:     4,
+			batchSize:   2,
+			wantBatches: []string{"after 0 limit 2", "after 2 limit 2", "after 4 limit 2"},
+		},
+		{
+			name:        "default batch size",
+			reviews:     3,
+			batchSize:   0,
+			wantBatches: []string{fmt.Sprintf("after 0 limit %d", defaultStreamBatchSize)},
+		},
+		{
+			name:        "maximum batch size",
+			reviews:     1,
+			batchSize:   maxStreamBatchSize,
+			wantBatches: []string{fmt.Sprintf("after 0 limit %d", maxStreamBatchSize)},
+		},
+		{
+			name:        "empty table",
+			reviews:     0,
+			batchSize:   2,
+			wantBatches: []string{"after 0 limit 2"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newStreamRepository(tt.reviews)
+			srv := NewReviewServiceV2(newTestService(t, repo))
+			stream := &fakeStream{ctx: context.Background()}
+
+			if err := srv.StreamReviews(&reviewv2.StreamReviewsRequest{BatchSize: tt.batchSize}, stream); err != nil {
+				t.Fatalf("StreamReviews: %v", err)
+			}
+
+			if len(stream.sent) != tt.reviews {
+				t.Errorf("sent %d reviews, want %d", len(stream.sent), tt.reviews)
+			}
+			if fmt.Sprint(repo.batches) != fmt.Sprint(tt.wantBatches) {
+				t.Errorf("batches = %q, want %q", repo.batches, tt.wantBatches)
+			}
+		})
+	}
+}
+
+func TestStreamReviewsRejectsInvalidRequest(t *testing.T) {
+	tests := []struct {
+		name  string
+		req   *reviewv2.StreamReviewsRequest
+		field string
+	}{
+		{name: "negative batch size", req: &reviewv2.StreamReviewsRequest{BatchSize: -1}, field: "batch_size"},
+		{name: "batch size above maximum", req: &reviewv2.StreamReviewsRequest{BatchSize: maxStreamBatchSize + 1}, field: "batch_size"},
+		{name: "malformed token", req: &reviewv2.StreamReviewsRequest{ResumeToken: "!!!"}, field: "resume_token"},
+		{name: "change token", req: &reviewv2.StreamReviewsRequest{ResumeToken: encodeChangeToken(repository.ChangeCursor{TxID: 1, Seq: 1})}, field: "resume_token"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newStreamRepository(3)
+			srv := NewReviewServiceV2(newTestService(t, repo))
+			stream := &fakeStream{ctx: context.Background()}
+
+			err := srv.StreamReviews(tt.req, stream)
+
+			assertInvalidArgument(t, err, tt.field)
+			if len(stream.sent) != 0 || len(repo.batches) != 0 {
+				t.Errorf("sent %d reviews in %d batches, want none", len(stream.sent), len(repo.batches))
+			}
+		})
+	}
+}
+
+func TestStreamReviewsResumesFromCheckpoint(t *testing.T) {
+	repo := newStreamRepository(7)
+	srv := NewReviewServiceV2(newTestService(t, repo))
+
+	// Поток обрывается после третьего отзыва второго пакета
+	errBroken := errors.New("connection reset")
+	first := &fakeStream{ctx: context.Background(), onSend: func(n int) error {
+		if n == 4 {
+			return errBroken
+		}
+		return nil
+	}}
+	if err := srv.StreamReviews(&reviewv2.StreamReviewsRequest{BatchSize: 3}, first); err == nil {
+		t.Fatal("StreamReviews with a broken stream succeeded")
+	}
+	if fmt.Sprint(first.ids()) != "[1 2 3]" {
+		t.Fatalf("first stream sent %v, want [1 2 3]", first.ids())
+	}
+
+	// Продолжение с последнего полученного checkpoint_token передает только оставшиеся отзывы
+	checkpoint := first.sent[len(first.sent)-1].GetCheckpointToken()
+	repo.batches = nil
+	second := &fakeStream{ctx: context.Background()}
+	if err := srv.StreamReviews(&reviewv2.StreamReviewsRequest{BatchSize: 3, ResumeToken: checkpoint}, second); err != nil {
+		t.Fatalf("resumed StreamReviews: %v", err)
+	}
+	if fmt.Sprint(second.ids()) != "[4 5 6 7]" {
+		t.Errorf("resumed stream sent %v, want [4 5 6 7]", second.ids())
+	}
+	if len(repo.batches) == 0 || repo.batches[0] != "after 3 limit 3" {
+		t.Errorf("resumed batches = %q, want the first to start after 3", repo.batches)
+	}
+
+	// Каждый checkpoint_token указывает на свой отзыв
+	for _, resp := range second.sent {
+		afterID, err := decodePageToken(resp.GetCheckpointToken())
+		if err != nil || int64(afterID) != resp.GetReview().GetId() {
+			t.Errorf("checkpoint of review %d decodes to %d, %v", resp.GetReview().GetId(), afterID, err)
+		}
+	}
+}
+
+func TestStreamReviewsStopsOnSendError(t *testing.T) {
+	repo := newStreamRepository(10)
+	srv := NewReviewServiceV2(newTestService(t, repo))
+	stream := &fakeStream{ctx: context.Background(), onSend: func(n int) error {
+		if n == 2 {
+			return errors.New("transport is closing")
+		}
+		return nil
+	}}
+
+	err := srv.StreamReviews(&reviewv2.StreamReviewsRequest{BatchSize: 5}, stream)
+
+	if code := status.Code(err); code != codes.Internal {
+		t.Errorf("code = %s, want Internal", code)
+	}
+	if len(stream.sent) != 1 {
+		t.Errorf("sent %d reviews, want 1", len(stream.sent))
+	}
+	if len(repo.batches) != 1 {
+		t.Errorf("read %d batches, want the stream to stop within the first", len(repo.batches))
+	}
+}
+
+func TestStreamReviewsStopsOnCancel(t *testing.T) {
+	t.Run("before the stream starts", func(t *testing.T) {
+		repo := newStreamRepository(3)
+		srv := NewReviewServiceV2(newTestService(t, repo))
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		err := srv.StreamReviews(&reviewv2.StreamReviewsRequest{}, &fakeStream{ctx: ctx})
+
+		if code := status.Code(err); code != codes.Canceled {
+			t.Errorf("code = %s, want Canceled", code)
+		}
+		if len(repo.batches) != 0 {
+			t.Errorf("read %d batches, want none", len(repo.batches))
+		}
+	})
+
+	t.Run("between batches", func(t *testing.T) {
+		repo := newStreamRepository(10)
+		srv := NewReviewServiceV2(newTestService(t, repo))
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		// Клиент отключается после последнего отзыва первого пакета
+		stream := &fakeStream{ctx: ctx, onSend: func(n int) error {
+			if n == 2 {
+				cancel()
+			}
+			return nil
+		}}
+
+		err := srv.StreamReviews(&reviewv2.StreamReviewsRequest{BatchSize: 2}, stream)
+
+		if code := status.Code(err); code != codes.Canceled {
+			t.Errorf("code = %s, want Canceled", code)
+		}
+		if fmt.Sprint(stream.ids()) != "[1 2]" || len(repo.batches) != 1 {
+			t.Errorf("sent %v in %d batches, want [1 2] in 1", stream.ids(), len(repo.batches))
+		}
+	})
+
+	t.Run("send fails after cancel", func(t *testing.T) {
+		repo := newStreamRepository(10)
+		srv := NewReviewServiceV2(newTestService(t, repo))
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		// Отправка после отключения клиента завершается ошибкой транспорта
+		stream := &fakeStream{ctx: ctx, onSend: func(n int) error {
+			if n == 3 {
+				cancel()
+				return errors.New("transport is closing")
+			}
+			return nil
+		}}
+
+		err := srv.StreamReviews(&reviewv2.StreamReviewsRequest{BatchSize: 5}, stream)
+
+		if code := status.Code(err); code != codes.Canceled {
+			t.Errorf("code = %s, want Canceled for a disconnected client", code)
+		}
+		if len(stream.sent) != 2 {
+			t.Errorf("sent %d reviews, want 2", len(stream.sent))
+		}
+	})
+}
@@ -0,0 +1,265 @@
+package service
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	reviewv2 "github.com/watchlist-kata/review/api/proto/review/v2"
+	"github.com/watchlist-kata/review/internal/repository"
+)
+
+// ReviewServiceV2 реализует API review.v2 поверх того же ядра, что и ReviewService
+type ReviewServiceV2 struct {
+	reviewv2.UnimplementedReviewServiceServer
+	core *ReviewService
+}
+
+// NewReviewServiceV2 создает обработчик API review.v2
+func NewReviewServiceV2(core *ReviewService) *ReviewServiceV2 {
+	return &ReviewServiceV2{core: core}
+}
+
+func (s *ReviewServiceV2) CreateReview(ctx context.Context, req *reviewv2.CreateReviewRequest) (*reviewv2.CreateReviewResponse, error) {
+	if err := s.core.checkContextCancelled(ctx, "CreateReview"); err != nil {
+		return nil, toStatus(err)
+	}
+
+	gormReview, err := s.core.createReview(ctx, req.MediaId, req.UserId, req.Content, req.Rating)
+	if err != nil {
+		return nil, err
+	}
+
+	return &reviewv2.CreateReviewResponse{
+		Review: ConvertToProtoReviewV2(gormReview),
+	}, nil
+}
+
+func (s *ReviewServiceV2) GetReview(ctx context.Context, req *reviewv2.GetReviewRequest) (*reviewv2.GetReviewResponse, error) {
+	if err := s.core.checkContextCancelled(ctx, "GetReview"); err != nil {
+		return nil, toStatus(err)
+	}
+
+	gormReview, err := s.core.getReview(ctx, req.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	return &reviewv2.GetReviewResponse{
+		Review: ConvertToProtoReviewV2(gormReview),
+	}, nil
+}
+
+func (s *ReviewServiceV2) UpdateReview(ctx context.Context, req *reviewv2.UpdateReviewRequest) (*reviewv2.UpdateReviewResponse, error) {
+	if err := s.core.checkContextCancelled(ctx, "UpdateReview"); err != nil {
+		return nil, toStatus(err)
+	}
+
+	if req.Review == nil {
+		return nil, s.core.fail(ctx, "invalid update review request",
+			invalidArgument(FieldViolation{Field: "review", Description: "is required"}))
+	}
+	if req.UpdateMask == nil {
+		return nil, s.core.fail(ctx, "invalid update review request",
+			invalidArgument(FieldViolation{Field: "update_mask", Description: "is required"}))
+	}
+
+	patch := reviewPatch{Content: req.Review.Content, Rating: req.Review.Rating}
+	gormReview, err := s.core.updateReview(ctx, req.Review.Id, patch, req.UpdateMask)
+	if err != nil {
+		return nil, err
+	}
+
+	return &reviewv2.UpdateReviewResponse{
+		Review: ConvertToProtoReviewV2(gormReview),
+	}, nil
+}
+
+func (s *ReviewServiceV2) DeleteReview(ctx context.Context, req *reviewv2.DeleteReviewRequest) (*reviewv2.DeleteReviewResponse, error) {
+	if err := s.core.checkContextCancelled(ctx, "DeleteReview"); err != nil {
+		return nil, toStatus(err)
+	}
+
+	if err := s.core.deleteReview(ctx, req.Id); err != nil {
+		return nil, err
+	}
+
+	return &reviewv2.DeleteReviewResponse{}, nil
+}
+
+func (s *ReviewServiceV2) ListReviews(ctx context.Context, req *reviewv2.ListReviewsRequest) (*reviewv2.ListReviewsResponse, error) {
+	if err := s.core.checkContextCancelled(ctx, "ListReviews"); err != nil {
+		return nil, toStatus(err)
+	}
+
+	filter, violations := convertFromProtoFilterV2(req.Filter)
+	if len(violations) > 0 {
+		return nil, s.core.fail(ctx, "invalid list reviews request", invalidArgument(violations...))
+	}
+
+	gormReviews, nextPageToken, err := s.core.listReviews(ctx, filter, req.PageSize, req.PageToken)
+	if err != nil {
+		return nil, err
+	}
+
+	protoReviews := make([]*reviewv2.Review, 0, len(gormReviews))
+	for i := range gormReviews {
+		protoReviews = append(protoReviews, ConvertToProtoReviewV2(&gormReviews[i]))
+	}
+
+	return &reviewv2.ListReviewsResponse{
+		Reviews:       protoReviews,
+		NextPageToken: nextPageToken,
+	}, nil
+}
+
+func (s *ReviewServiceV2) StreamReviews(req *reviewv2.StreamReviewsRequest, stream grpc.ServerStreamingServer[reviewv2.StreamReviewsResponse]) error {
+	ctx := stream.Context()
+	if err := s.core.checkContextCancelled(ctx, "StreamReviews"); err != nil {
+		return toStatus(err)
+	}
+
+	filter, violations := convertFromProtoFilterV2(req.Filter)
+	if len(violations) > 0 {
+		return s.core.fail(ctx, "invalid stream reviews request", invalidArgument(violations...))
+	}
+
+	return s.core.streamReviews(ctx, filter, req.ResumeToken, req.BatchSize, func(gormReview *repository.GormReview, checkpoint string) error {
+		return stream.Send(&reviewv2.StreamReviewsResponse{
+			Review:          ConvertToProtoReviewV2(gormReview),
+			CheckpointToken: checkpoint,
+		})
+	})
+}
+
+func (s *ReviewServiceV2) SyncChanges(ctx context.Context, req *reviewv2.SyncChangesRequest) (*reviewv2.SyncChangesResponse, error) {
+	if err := s.core.checkContextCancelled(ctx, "SyncChanges"); err != nil {
+		return nil, toStatus(err)
+	}
+
+	filter, violations := convertFromProtoChangeFilterV2(req.Filter)
+	if len(violations) > 0 {
+		return nil, s.core.fail(ctx, "invalid sync changes request", invalidArgument(violations...))
+	}
+
+	gormReviews, nextToken, hasMore, err := s.core.syncChanges(ctx, filter, req.SinceToken, req.PageSize)
+	if err != nil {
+		return nil, err
+	}
+
+	protoChanges := make([]*reviewv2.ReviewChange, 0, len(gormReviews))
+	for i := range gormReviews {
+		protoChanges = append(protoChanges, ConvertToProtoChangeV2(&gormReviews[i]))
+	}
+
+	return &reviewv2.SyncChangesResponse{
+		Changes:   protoChanges,
+		NextToken: nextToken,
+		HasMore:   hasMore,
+	}, nil
+}
+
+func (s *ReviewServiceV2) WatchReviews(req *reviewv2.WatchReviewsRequest, stream grpc.ServerStreamingServer[reviewv2.WatchReviewsResponse]) error {
+	ctx := stream.Context()
+	if err := s.core.checkContextCancelled(ctx, "WatchReviews"); err != nil {
+		return toStatus(err)
+	}
+
+	filter, violations := convertFromProtoChangeFilterV2(req.Filter)
+	if len(violations) > 0 {
+		return s.core.fail(ctx, "invalid watch reviews request", invalidArgument(violations...))
+	}
+
+	return s.core.watchChanges(ctx, filter, req.SinceToken, func(gormReview *repository.GormReview, token string) error {
+		return stream.Send(&reviewv2.WatchReviewsResponse{
+			Change: ConvertToProtoChangeV2(gormReview),
+			Token:  token,
+		})
+	})
+}
+
+// ConvertToProtoReviewV2 преобразует модель отзыва в сообщение review.v2
+func ConvertToProtoReviewV2(gormReview *repository.GormReview) *reviewv2.Review {
+	return &reviewv2.Review{
+		Id:         int64(gormReview.ID),
+		MediaId:    int64(gormReview.MediaID),
+		UserId:     int64(gormReview.UserID),
+		Content:    gormReview.Content,
+		Rating:     int32(gormReview.Rating),
+		CreateTime: timestamppb.New(gormReview.CreatedAt),
+		UpdateTime: timestamppb.New(gormReview.UpdatedAt),
+	}
+}
+
+// ConvertToProtoChangeV2 преобразует запись журнала в изменение review.v2:
+// удаленный отзыв становится меткой удаления, остальные - текущим состоянием отзыва
+func ConvertToProtoChangeV2(gormReview *repository.GormReview) *reviewv2.ReviewChange {
+	change := &reviewv2.ReviewChange{Sequence: gormReview.ChangeSeq}
+	if gormReview.DeletedAt.Valid {
+		change.Change = &reviewv2.ReviewChange_Tombstone{Tombstone: &reviewv2.ReviewTombstone{
+			Id:         int64(gormReview.ID),
+			MediaId:    int64(gormReview.MediaID),
+			UserId:     int64(gormReview.UserID),
+			DeleteTime: timestamppb.New(gormReview.DeletedAt.Time),
+		}}
+		return change
+	}
+	change.Change = &reviewv2.ReviewChange_Upsert{Upsert: ConvertToProtoReviewV2(gormReview)}
+	return change
+}
+
+// convertFromProtoFilterV2 проверяет фильтр review.v2 и преобразует его в фильтр репозитория
+func convertFromProtoFilterV2(filter *reviewv2.ReviewFilter) (repository.ReviewFilter, []FieldViolation) {
+	var (
+		result     repository.ReviewFilter
+		violations []FieldViolation
+	)
+	if filter == nil {
+		return result, nil
+	}
+
+	if filter.MediaId != nil {
+		violations = append(violations, validateID("filter.media_id", *filter.MediaId)...)
+		mediaID := uint(*filter.MediaId)
+		result.MediaID = &mediaID
+	}
+	if filter.UserId != nil {
+		violations = append(violations, validateID("filter.user_id", *filter.UserId)...)
+		userID := uint(*filter.UserId)
+		result.UserID = &userID
+	}
+	if filter.Rating != nil {
+		for _, v := range validateRating(*filter.Rating) {
+			violations = append(violations, FieldViolation{Field: "filter." + v.Field, Description: v.Description})
+		}
+		rating := int(*filter.Rating)
+		result.Rating = &rating
+	}
+
+	return result, violations
+}
+
+// convertFromProtoChangeFilterV2 проверяет фильтр журнала изменений и преобразует его в фильтр репозитория
+func convertFromProtoChangeFilterV2(filter *reviewv2.ChangeFilter) (repository.ReviewFilter, []FieldViolation) {
+	var (
+		result     repository.ReviewFilter
+		violations []FieldViolation
+	)
+	if filter == nil {
+		return result, nil
+	}
+
+	if filter.MediaId != nil {
+		violations = append(violations, validateID("filter.media_id", *filter.MediaId)...)
+		mediaID := uint(*filter.MediaId)
+		result.MediaID = &mediaID
+	}
+	if filter.UserId != nil {
+		violations = append(violations, validateID("filter.user_id", *filter.UserId)...)
+		userID := uint(*filter.UserId)
+		result.UserID = &userID
+	}
+
+	return result, violations
+}
@@ -0,0 +1,133 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+
+	reviewv2 "github.com/watchlist-kata/review/api/proto/review/v2"
+	"github.com/watchlist-kata/review/internal/repository"
+)
+
+func TestConvertToProtoReviewParity(t *testing.T) {
+	moscow := time.FixedZone("MSK", 3*60*60)
+	tests := []struct {
+		name   string
+		review repository.GormReview
+	}{
+		{
+			name: "utc",
+			review: repository.GormReview{
+				ID: 1, MediaID: 10, UserID: 20, Content: "great", Rating: 8,
+				CreatedAt: time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC),
+				UpdatedAt: time.Date(2024, 5, 2, 8, 0, 15, 0, time.UTC),
+			},
+		},
+		{
+			name: "fractional seconds and zone",
+			review: repository.GormReview{
+				ID: 2, MediaID: 11, UserID: 21, Content: "отличный фильм", Rating: 10,
+				CreatedAt: time.Date(2024, 12, 31, 23, 59, 59, 999999999, moscow),
+				UpdatedAt: time.Date(2025, 1, 1, 0, 0, 0, 123000000, moscow),
+			},
+		},
+		{
+			name:   "zero values",
+			review: repository.GormReview{ID: 3},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v1 := ConvertToProtoReview(&tt.review)
+			v2 := ConvertToProtoReviewV2(&tt.review)
+
+			if v1.Id != v2.Id || v1.MediaId != v2.MediaId || v1.UserId != v2.UserId {
+				t.Errorf("ids differ: v1 %d/%d/%d, v2 %d/%d/%d", v1.Id, v1.MediaId, v1.UserId, v2.Id, v2.MediaId, v2.UserId)
+			}
+			if v1.Content != v2.Content || v1.Rating != v2.Rating {
+				t.Errorf("content or rating differ: v1 %q/%d, v2 %q/%d", v1.Content, v1.Rating, v2.Content, v2.Rating)
+			}
+
+			// v1 передает время строкой RFC3339 с точностью до секунды
+			for _, ts := range []struct {
+				field string
+				v1    string
+				v2    time.Time
+			}{
+				{"created", v1.CreatedAt, v2.CreateTime.AsTime()},
+				{"updated", v1.UpdatedAt, v2.UpdateTime.AsTime()},
+			} {
+				parsed, err := time.Parse(time.RFC3339, ts.v1)
+				if err != nil {
+					t.Fatalf("v1 %s time %q: %v", ts.field, ts.v1, err)
+				}
+				if !parsed.Equal(ts.v2.Truncate(time.Second)) {
+					t.Errorf("%s time differs: v1 %s, v2 %s", ts.field, parsed, ts.v2)
+				}
+			}
+		})
+	}
+}
+
+func TestConvertFromProtoFilterV2(t *testing.T) {
+	tests := []struct {
+		name       string
+		filter     *reviewv2.ReviewFilter
+		want       repository.ReviewFilter
+		violations []string // Поля нарушений
+	}{
+		{name: "nil filter"},
+		{name: "empty filter", filter: &reviewv2.ReviewFilter{}},
+		{
+			name:   "all fields",
+			filter: &reviewv2.ReviewFilter{MediaId: proto.Int64(10), UserId: proto.Int64(20), Rating: proto.Int32(7)},
+			want:   repository.ReviewFilter{MediaID: ptr(uint(10)), UserID: ptr(uint(20)), Rating: ptr(7)},
+		},
+		{
+			name:       "zero media id",
+			filter:     &reviewv2.ReviewFilter{MediaId: proto.Int64(0)},
+			violations: []string{"filter.media_id"},
+		},
+		{
+			name:       "negative user id",
+			filter:     &reviewv2.ReviewFilter{UserId: proto.Int64(-5)},
+			violations: []string{"filter.user_id"},
+		},
+		{
+			name:       "all invalid",
+			filter:     &reviewv2.ReviewFilter{MediaId: proto.Int64(-1), UserId: proto.Int64(0), Rating: proto.Int32(11)},
+			violations: []string{"filter.media_id", "filter.user_id", "filter.rating"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, violations := convertFromProtoFilterV2(tt.filter)
+
+			var fields []string
+			for _, violation := range violations {
+				fields = append(fields, violation.Field)
+			}
+			if !reflect.DeepEqual(fields, tt.violations) {
+				t.Fatalf("violations = %v, want %v", fields, tt.violations)
+			}
+			if len(tt.violations) == 0 && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("filter = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConvertFromProtoChangeFilterV2RejectsNonPositiveIDs(t *testing.T) {
+	_, violations := convertFromProtoChangeFilterV2(&reviewv2.ChangeFilter{MediaId: proto.Int64(0), UserId: proto.Int64(-1)})
+	if len(violations) != 2 || violations[0].Field != "filter.media_id" || violations[1].Field != "filter.user_id" {
+		t.Errorf("violations = %v, want filter.media_id and filter.user_id", violations)
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
@@ -0,0 +1,228 @@
+package logger
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+	"unicode"
+)
+
+// handlerState holds the attributes and groups added with WithAttrs and WithGroup.
+// Attributes are stored already nested into the groups that were open when they
+// were added, so a handler derived from another one never changes its parent.
+type handlerState struct {
+	groups []string
+	attrs  []slog.Attr
+}
+
+// withAttrs returns a copy of the state with attrs added to the open groups.
+func (s handlerState) withAttrs(attrs []slog.Attr) handlerState {
+	resolved := resolveAttrs(attrs)
+	if len(resolved) == 0 {
+		return s
+	}
+	next := handlerState{groups: s.groups}
+	next.attrs = make([]slog.Attr, 0, len(s.attrs)+1)
+	next.attrs = append(next.attrs, s.attrs...)
+	next.attrs = append(next.attrs, nestInGroups(s.groups, resolved)...)
+	return next
+}
+
+// withGroup returns a copy of the state with a new group opened.
+func (s handlerState) withGroup(name string) handlerState {
+	if name == "" {
+		return s
+	}
+	next := handlerState{attrs: s.attrs}
+	next.groups = make([]string, 0, len(s.groups)+1)
+	next.groups = append(next.groups, s.groups...)
+	next.groups = append(next.groups, name)
+	return next
+}
+
+// collect returns the handler attributes followed by the record attributes nested
+// into the open groups. LogValuer values are resolved at this point, so the result
+// can be rendered later from another goroutine.
+func (s handlerState) collect(record slog.Record) []slog.Attr {
+	recordAttrs := make([]slog.Attr, 0, record.NumAttrs())
+	record.Attrs(func(attr slog.Attr) bool {
+		recordAttrs = append(recordAttrs, attr)
+		return true
+	})
+	recordAttrs = resolveAttrs(recordAttrs)
+
+	attrs := make([]slog.Attr, 0, len(s.attrs)+1)
+	attrs = append(attrs, s.attrs...)
+	return append(attrs, nestInGroups(s.groups, recordAttrs)...)
+}
+
+// nestInGroups wraps attrs into the given groups, innermost last.
+func nestInGroups(groups []string, attrs []slog.Attr) []slog.Attr {
+	if len(attrs) == 0 {
+		return nil
+	}
+	for i := len(groups) - 1; i >= 0; i-- {
+		attrs = []slog.Attr{{Key: groups[i], Value: slog.GroupValue(attrs...)}}
+	}
+	return attrs
+}
+
+// resolveAttrs resolves LogValuer values recursively and drops empty attributes
+// and empty groups, following the slog.Handler rules.
+func resolveAttrs(attrs []slog.Attr) []slog.Attr {
+	resolved := make([]slog.Attr, 0, len(attrs))
+	for _, attr := range attrs {
+		attr.Value = attr.Value.Resolve()
+		if attr.Equal(slog.Attr{}) {
+			continue
+		}
+		if attr.Value.Kind() == slog.KindGroup {
+			group := resolveAttrs(attr.Value.Group())
+			if len(group) == 0 {
+				continue
+			}
+			if attr.Key == "" {
+				// Attributes of a group with an empty key are inlined
+				resolved = append(resolved, group...)
+				continue
+			}
+			attr.Value = slog.GroupValue(group...)
+		}
+		resolved = append(resolved, attr)
+	}
+	return resolved
+}
+
+// attrsToMap converts attributes into nested maps for JSON encoding. Groups with
+// the same key are merged; a later attribute with the same key wins.
+func attrsToMap(dst map[string]any, attrs []slog.Attr) map[string]any {
+	if dst == nil {
+		dst = make(map[string]any, len(attrs))
+	}
+	for _, attr := range attrs {
+		if attr.Value.Kind() == slog.KindGroup {
+			group, _ := dst[attr.Key].(map[string]any)
+			dst[attr.Key] = attrsToMap(group, attr.Value.Group())
+			continue
+		}
+		dst[attr.Key] = jsonValue(attr.Value)
+	}
+	return dst
+}
+
+// jsonValue converts a resolved non-group value into a JSON-encodable value.
+func jsonValue(v slog.Value) any {
+	switch v.Kind() {
+	case slog.KindString:
+		return v.String()
+	case slog.KindInt64:
+		return v.Int64()
+	case slog.KindUint64:
+		return v.Uint64()
+	case slog.KindFloat64:
+		return v.Float64()
+	case slog.KindBool:
+		return v.Bool()
+	case slog.KindDuration:
+		return v.Duration().String()
+	case slog.KindTime:
+		return v.Time().Format(time.RFC3339Nano)
+	}
+
+	value := v.Any()
+	if err, ok := value.(error); ok {
+		return err.Error()
+	}
+	if _, err := json.Marshal(value); err != nil {
+		return fmt.Sprintf("%+v", value)
+	}
+	return value
+}
+
+// appendKeyValues appends attributes as space-separated key=value pairs. Keys of
+// nested attributes are prefixed with their groups separated by dots.
+func appendKeyValues(buf []byte, prefix string, attrs []slog.Attr) []byte {
+	for _, attr := range attrs {
+		key := attr.Key
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		if attr.Value.Kind() == slog.KindGroup {
+			buf = appendKeyValues(buf, key, attr.Value.Group())
+			continue
+		}
+		buf = append(buf, ' ')
+		buf = append(buf, quoteIfNeeded(key)...)
+		buf = append(buf, '=')
+		buf = append(buf, quoteIfNeeded(textValue(attr.Value))...)
+	}
+	return buf
+}
+
+// textValue formats a resolved non-group value for text output.
+func textValue(v slog.Value) string {
+	switch v.Kind() {
+	case slog.KindTime:
+		return v.Time().Format(time.RFC3339Nano)
+	case slog.KindAny:
+		if err, ok := v.Any().(error); ok {
+			return err.Error()
+		}
+		return fmt.Sprintf("%+v", v.Any())
+	default:
+		return v.String()
+	}
+}
+
+// quoteIfNeeded quotes s if it is empty or contains spaces, quotes, '=' or
+// non-printable characters, so that key=value output stays unambiguous.
+func quoteIfNeeded(s string) string {
+	if s == "" {
+		return `""`
+	}
+	for _, r := range s {
+		if r == '=' || r == '"' || unicode.IsSpace(r) || !unicode.IsPrint(r) {
+			return strconv.Quote(s)
+		}
+	}
+	return s
+}
+
+// traceAttrs returns the trace and span IDs as attributes, if present.
+func traceAttrs(traceID, spanID string) []slog.Attr {
+	var attrs []slog.Attr
+	if traceID != "" {
+		attrs = append(attrs, slog.String(TraceIDKey, traceID))
+	}
+	if spanID != "" {
+		attrs = append(attrs, slog.String("span_id", spanID))
+	}
+	return attrs
+}
+
+// jsonLine encodes a record with its attributes as a single JSON object. The
+// time, level, msg, source, trace_id and span_id keys take precedence over
+// attributes; source is omitted if empty.
+func jsonLine(record slog.Record, attrs []slog.Attr, traceID, spanID, source string) ([]byte, error) {
+	fields := attrsToMap(nil, attrs)
+	fields["time"] = record.Time.Format(time.RFC3339)
+	fields["level"] = record.Level.String()
+	fields["msg"] = record.Message
+	if source != "" {
+		fields["source"] = source
+	}
+	for _, attr := range traceAttrs(traceID, spanID) {
+		fields[attr.Key] = attr.Value.String()
+	}
+	return json.Marshal(fields)
+}
+
+// textAttrs formats attributes and trace IDs as a key=value suffix.
+func textAttrs(attrs []slog.Attr, traceID, spanID string) string {
+	var buf []byte
+	buf = appendKeyValues(buf, "", attrs)
+	buf = appendKeyValues(buf, "", traceAttrs(traceID, spanID))
+	return string(buf)
+}
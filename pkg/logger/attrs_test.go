@@ -0,0 +1,239 @@
+package logger
+
+import (
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// sinkOutputs is a record as rendered by each sink.
+type sinkOutputs struct {
+	kafka  map[string]any // Kafka JSON message without time, level and msg
+	file   map[string]any // JSON file line without time, level and msg
+	stdout string         // logfmt key=value pairs after the message
+}
+
+// logToSinks logs through a Kafka, a file and a stdout handler combined in a
+// MultiHandler and returns what each of them has written.
+func logToSinks(t *testing.T, log func(*slog.Logger)) sinkOutputs {
+	t.Helper()
+	dir := t.TempDir()
+
+	logSpool, err := openSpool(filepath.Join(dir, "spool"), 1<<20)
+	if err != nil {
+		t.Fatalf("openSpool: %v", err)
+	}
+	defer logSpool.Close()
+	producer := newFakeProducer()
+	kafka := &KafkaHandler{kafkaSink: &kafkaSink{
+		topic:    "logs",
+		queue:    newLogQueue(QueueOptions{Size: 10}),
+		spool:    logSpool,
+		quitChan: make(chan struct{}),
+		producer: producer,
+	}}
+
+	filePath := filepath.Join(dir, "app.log")
+	file, err := openRotatingFile(filePath, RotationOptions{})
+	if err != nil {
+		t.Fatalf("openRotatingFile: %v", err)
+	}
+	fileHandler := &FileHandler{fileSink: &fileSink{
+		file:     file,
+		format:   FormatOptions{Format: FormatJSON},
+		queue:    newLogQueue(QueueOptions{Size: 10, DrainTimeout: 5 * time.Second}),
+		quitChan: make(chan struct{}),
+		hupChan:  make(chan os.Signal, 1),
+	}}
+	fileHandler.wg.Add(1)
+	go fileHandler.processLogs()
+
+	stdoutPath := filepath.Join(dir, "stdout")
+	stdoutFile, err := os.Create(stdoutPath)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	defer stdoutFile.Close()
+	stdout := &StdoutHandler{writer: stdoutFile, format: FormatOptions{Format: FormatLogfmt}, level: new(slog.LevelVar)}
+
+	log(slog.New(NewMultiHandler(kafka, fileHandler, stdout)))
+
+	var out sinkOutputs
+	select {
+	case entry := <-kafka.queue.entries:
+		kafka.send(entry, nil)
+		message := <-producer.input
+		value, _ := message.Value.Encode()
+		out.kafka = jsonAttrs(t, value)
+	default:
+		t.Fatal("kafka handler has queued no record")
+	}
+
+	if err := fileHandler.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	out.file = jsonAttrs(t, data)
+
+	data, err = os.ReadFile(stdoutPath)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	_, pairs, ok := strings.Cut(strings.TrimSuffix(string(data), "\n"), " msg=record")
+	if !ok {
+		t.Fatalf("stdout line %q has no msg=record", data)
+	}
+	out.stdout = strings.TrimPrefix(pairs, " ")
+	return out
+}
+
+// jsonAttrs decodes a JSON log line and removes the time, level and msg keys.
+func jsonAttrs(t *testing.T, line []byte) map[string]any {
+	t.Helper()
+	var fields map[string]any
+	if err := json.Unmarshal(line, &fields); err != nil {
+		t.Fatalf("invalid JSON line %q: %v", line, err)
+	}
+	for _, key := range []string{"time", "level", "msg"} {
+		delete(fields, key)
+	}
+	return fields
+}
+
+func TestAttributeNesting(t *testing.T) {
+	tests := []struct {
+		name       string
+		log        func(*slog.Logger)
+		wantJSON   string
+		wantLogfmt string
+	}{
+		{
+			name: "with before group",
+			log: func(l *slog.Logger) {
+				l.With("a", 1).WithGroup("g").Info("record", "b", 2)
+			},
+			wantJSON:   `{"a":1,"g":{"b":2}}`,
+			wantLogfmt: "a=1 g.b=2",
+		},
+		{
+			name: "group then with",
+			log: func(l *slog.Logger) {
+				l.WithGroup("g").With("a", 1).Info("record", "b", 2)
+			},
+			wantJSON:   `{"g":{"a":1,"b":2}}`,
+			wantLogfmt: "g.a=1 g.b=2",
+		},
+		{
+			name: "nested groups",
+			log: func(l *slog.Logger) {
+				l.WithGroup("g").With("a", 1).WithGroup("h").Info("record", "b", 2, slog.Group("i", "c", 3))
+			},
+			wantJSON:   `{"g":{"a":1,"h":{"b":2,"i":{"c":3}}}}`,
+			wantLogfmt: "g.a=1 g.h.b=2 g.h.i.c=3",
+		},
+		{
+			name: "derived handler keeps parent",
+			log: func(l *slog.Logger) {
+				parent := l.WithGroup("g")
+				parent.With("a", 1).WithGroup("h")
+				parent.Info("record", "b", 2)
+			},
+			wantJSON:   `{"g":{"b":2}}`,
+			wantLogfmt: "g.b=2",
+		},
+		{
+			name: "group without attributes elided",
+			log: func(l *slog.Logger) {
+				l.With("a", 1).WithGroup("g").Info("record")
+			},
+			wantJSON:   `{"a":1}`,
+			wantLogfmt: "a=1",
+		},
+		{
+			name: "empty groups elided",
+			log: func(l *slog.Logger) {
+				l.With(slog.Group("empty")).Info("record", slog.Group("outer", slog.Group("inner")), "a", 1)
+			},
+			wantJSON:   `{"a":1}`,
+			wantLogfmt: "a=1",
+		},
+		{
+			name: "group with empty key inlined",
+			log: func(l *slog.Logger) {
+				l.WithGroup("g").Info("record", slog.Group("", "a", 1), "b", 2)
+			},
+			wantJSON:   `{"g":{"a":1,"b":2}}`,
+			wantLogfmt: "g.a=1 g.b=2",
+		},
+		{
+			name: "duplicate groups merged",
+			log: func(l *slog.Logger) {
+				l.With(slog.Group("req", "id", 1)).Info("record", slog.Group("req", "path", "/x"))
+			},
+			wantJSON:   `{"req":{"id":1,"path":"/x"}}`,
+			wantLogfmt: "req.id=1 req.path=/x",
+		},
+		{
+			name: "duplicate groups merged with open group",
+			log: func(l *slog.Logger) {
+				l.WithGroup("req").With("id", 1).Info("record", slog.Group("headers", "a", "1"), "id", 2)
+			},
+			wantJSON:   `{"req":{"id":2,"headers":{"a":"1"}}}`,
+			wantLogfmt: "req.id=1 req.headers.a=1 req.id=2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var want map[string]any
+			if err := json.Unmarshal([]byte(tt.wantJSON), &want); err != nil {
+				t.Fatalf("invalid wantJSON: %v", err)
+			}
+
+			out := logToSinks(t, tt.log)
+
+			if !reflect.DeepEqual(out.kafka, want) {
+				t.Errorf("kafka attributes = %v, want %s", out.kafka, tt.wantJSON)
+			}
+			if !reflect.DeepEqual(out.file, want) {
+				t.Errorf("file attributes = %v, want %s", out.file, tt.wantJSON)
+			}
+			if out.stdout != tt.wantLogfmt {
+				t.Errorf("stdout attributes = %q, want %q", out.stdout, tt.wantLogfmt)
+			}
+		})
+	}
+}
+
+func TestHandlerStateWithGroup(t *testing.T) {
+	base := handlerState{}.withAttrs([]slog.Attr{slog.Int("a", 1)})
+
+	if got := base.withGroup(""); !reflect.DeepEqual(got, base) {
+		t.Errorf("withGroup(\"\") = %+v, want the state unchanged", got)
+	}
+
+	// Sibling groups opened from one parent must not share its groups slice
+	parent := base.withGroup("g")
+	first := parent.withGroup("h")
+	second := parent.withGroup("i")
+	if strings.Join(parent.groups, ".") != "g" || strings.Join(first.groups, ".") != "g.h" || strings.Join(second.groups, ".") != "g.i" {
+		t.Errorf("groups = %v, %v, %v, want [g], [g h], [g i]", parent.groups, first.groups, second.groups)
+	}
+	if len(base.groups) != 0 {
+		t.Errorf("base groups = %v, want none", base.groups)
+	}
+
+	// Attributes added before the group stay at the top level
+	attrs := first.collect(slog.NewRecord(time.Now(), slog.LevelInfo, "record", 0))
+	if len(attrs) != 1 || attrs[0].Key != "a" {
+		t.Errorf("collect without record attributes = %v, want [a=1]", attrs)
+	}
+}
@@ -0,0 +1,98 @@
+package logger
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+)
+
+// Keys of the context fields added to records.
+const (
+	RequestIDKey = "request_id"
+	UserIDKey    = "user_id"
+	MethodKey    = "method"
+	TraceIDKey   = "trace_id"
+)
+
+// contextFieldsKey is the context key of contextFields.
+type contextFieldsKey struct{}
+
+// contextFields are the fields carried by a context. The trace ID is kept apart
+// from the attributes because it is written together with the span ID.
+type contextFields struct {
+	attrs   []slog.Attr
+	traceID string
+}
+
+// fieldsFromContext returns the fields carried by ctx.
+func fieldsFromContext(ctx context.Context) contextFields {
+	if ctx == nil {
+		return contextFields{}
+	}
+	fields, _ := ctx.Value(contextFieldsKey{}).(contextFields)
+	return fields
+}
+
+// ContextWith returns a copy of ctx carrying attrs. RedactHandler, which wraps
+// every sink of NewLogger, adds them to records logged with the context, e.g. by
+// InfoContext, before redaction. An attribute replaces a previously added
+// attribute with the same key.
+func ContextWith(ctx context.Context, attrs ...slog.Attr) context.Context {
+	fields := fieldsFromContext(ctx)
+	merged := make([]slog.Attr, 0, len(fields.attrs)+len(attrs))
+	for _, attr := range fields.attrs {
+		replaced := false
+		for _, added := range attrs {
+			if added.Key == attr.Key {
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			merged = append(merged, attr)
+		}
+	}
+	fields.attrs = append(merged, attrs...)
+	return context.WithValue(ctx, contextFieldsKey{}, fields)
+}
+
+// WithRequestID returns a copy of ctx carrying the request ID.
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return ContextWith(ctx, slog.String(RequestIDKey, requestID))
+}
+
+// WithUserID returns a copy of ctx carrying the user ID.
+func WithUserID(ctx context.Context, userID string) context.Context {
+	return ContextWith(ctx, slog.String(UserIDKey, userID))
+}
+
+// WithMethod returns a copy of ctx carrying the name of the called method.
+func WithMethod(ctx context.Context, method string) context.Context {
+	return ContextWith(ctx, slog.String(MethodKey, method))
+}
+
+// WithTraceID returns a copy of ctx carrying a trace ID. It is used only when ctx
+// has no active span, whose trace ID takes precedence.
+func WithTraceID(ctx context.Context, traceID string) context.Context {
+	fields := fieldsFromContext(ctx)
+	fields.traceID = traceID
+	return context.WithValue(ctx, contextFieldsKey{}, fields)
+}
+
+// RequestID returns the request ID carried by ctx, or an empty string.
+func RequestID(ctx context.Context) string {
+	for _, attr := range fieldsFromContext(ctx).attrs {
+		if attr.Key == RequestIDKey {
+			return attr.Value.String()
+		}
+	}
+	return ""
+}
+
+// NewRequestID returns a random request ID.
+func NewRequestID() string {
+	var id [16]byte
+	rand.Read(id[:])
+	return hex.EncodeToString(id[:])
+}
@@ -0,0 +1,119 @@
+package logger
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"time"
+)
+
+// Format is the layout of log lines written by StdoutHandler and FileHandler.
+type Format string
+
+const (
+	FormatText   Format = "text"   // [LEVEL] - time - message key=value ..., optionally colored
+	FormatLogfmt Format = "logfmt" // time=... level=... msg=... key=value ...
+	FormatJSON   Format = "json"   // One JSON object per line
+)
+
+// ColorMode selects when FormatText lines are colored.
+type ColorMode string
+
+const (
+	ColorAuto   ColorMode = "auto"   // Color when writing to a terminal and NO_COLOR is not set
+	ColorAlways ColorMode = "always" // Always color
+	ColorNever  ColorMode = "never"  // Never color
+)
+
+// FormatOptions configures how a handler renders records.
+type FormatOptions struct {
+	Format    Format // Line layout; empty means FormatJSON
+	Color     bool   // Color the level of FormatText lines
+	AddSource bool   // Add the source file:line of the logging call
+}
+
+// UseColor reports whether output to file should be colored in the given mode.
+// In ColorAuto mode color is used only for terminals, and never when the NO_COLOR
+// environment variable is set to a non-empty value (https://no-color.org).
+func UseColor(mode ColorMode, file *os.File) bool {
+	switch mode {
+	case ColorAlways:
+		return true
+	case ColorNever:
+		return false
+	}
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// formatLine renders a record with its attributes and trace IDs as a single line
+// terminated by a newline.
+func formatLine(opts FormatOptions, record slog.Record, attrs []slog.Attr, traceID, spanID string) ([]byte, error) {
+	var source string
+	if opts.AddSource {
+		source = recordSource(record)
+	}
+
+	switch opts.Format {
+	case FormatText:
+		line := fmt.Sprintf("%s - %s - %s", levelLabel(record.Level, opts.Color), record.Time.Format("2006-01-02 15:04:05"), record.Message)
+		if source != "" {
+			line += " " + source
+		}
+		return []byte(line + textAttrs(attrs, traceID, spanID) + "\n"), nil
+	case FormatLogfmt:
+		buf := []byte("time=" + record.Time.Format(time.RFC3339Nano))
+		buf = append(buf, " level="...)
+		buf = append(buf, record.Level.String()...)
+		buf = append(buf, " msg="...)
+		buf = append(buf, quoteIfNeeded(record.Message)...)
+		if source != "" {
+			buf = append(buf, " source="...)
+			buf = append(buf, quoteIfNeeded(source)...)
+		}
+		buf = append(buf, textAttrs(attrs, traceID, spanID)...)
+		return append(buf, '\n'), nil
+	default:
+		line, err := jsonLine(record, attrs, traceID, spanID, source)
+		if err != nil {
+			return nil, err
+		}
+		return append(line, '\n'), nil
+	}
+}
+
+// levelLabel returns the bracketed level name, colored by severity if requested.
+func levelLabel(level slog.Level, color bool) string {
+	label := "[" + level.String() + "]"
+	if !color {
+		return label
+	}
+	code := ColorBlue
+	switch {
+	case level >= slog.LevelError:
+		code = ColorRed
+	case level >= slog.LevelWarn:
+		code = ColorYellow
+	case level >= slog.LevelInfo:
+		code = ColorGreen
+	}
+	return code + label + ColorReset
+}
+
+// recordSource returns the file:line of the logging call, or an empty string if unknown.
+func recordSource(record slog.Record) string {
+	if record.PC == 0 {
+		return ""
+	}
+	frame, _ := runtime.CallersFrames([]uintptr{record.PC}).Next()
+	if frame.File == "" {
+		return ""
+	}
+	return filepath.Base(filepath.Dir(frame.File)) + "/" + filepath.Base(frame.File) + ":" + strconv.Itoa(frame.Line)
+}
@@ -0,0 +1,259 @@
+package logger
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// goldenRecord returns the record rendered by the golden tests together with its
+// attributes and trace IDs.
+func goldenRecord() (slog.Record, []slog.Attr, string, string) {
+	record := slog.NewRecord(time.Date(2026, 1, 2, 15, 4, 5, 123000000, time.UTC), slog.LevelInfo, "user created", 0)
+	attrs := []slog.Attr{
+		slog.String("user", "alice"),
+		slog.Group("req", slog.Int("status", 201), slog.Duration("took", 1500*time.Millisecond)),
+	}
+	return record, attrs, "0af7651916cd43dd8448eb211c80319c", "b7ad6b7169203331"
+}
+
+func TestFormatLineGolden(t *testing.T) {
+	tests := []struct {
+		name string
+		opts FormatOptions
+		want string
+	}{
+		{
+			name: "text",
+			opts: FormatOptions{Format: FormatText},
+			want: "[INFO] - 2026-01-02 15:04:05 - user created user=alice req.status=201 req.took=1.5s" +
+				" trace_id=0af7651916cd43dd8448eb211c80319c span_id=b7ad6b7169203331\n",
+		},
+		{
+			name: "text colored",
+			opts: FormatOptions{Format: FormatText, Color: true},
+			want: "\033[32m[INFO]\033[0m - 2026-01-02 15:04:05 - user created user=alice req.status=201 req.took=1.5s" +
+				" trace_id=0af7651916cd43dd8448eb211c80319c span_id=b7ad6b7169203331\n",
+		},
+		{
+			name: "logfmt",
+			opts: FormatOptions{Format: FormatLogfmt},
+			want: `time=2026-01-02T15:04:05.123Z level=INFO msg="user created" user=alice req.status=201 req.took=1.5s` +
+				" trace_id=0af7651916cd43dd8448eb211c80319c span_id=b7ad6b7169203331\n",
+		},
+		{
+			name: "logfmt ignores color",
+			opts: FormatOptions{Format: FormatLogfmt, Color: true},
+			want: `time=2026-01-02T15:04:05.123Z level=INFO msg="user created" user=alice req.status=201 req.took=1.5s` +
+				" trace_id=0af7651916cd43dd8448eb211c80319c span_id=b7ad6b7169203331\n",
+		},
+		{
+			name: "json",
+			opts: FormatOptions{Format: FormatJSON},
+			want: `{"level":"INFO","msg":"user created","req":{"status":201,"took":"1.5s"},"span_id":"b7ad6b7169203331",` +
+				`"time":"2026-01-02T15:04:05Z","trace_id":"0af7651916cd43dd8448eb211c80319c","user":"alice"}` + "\n",
+		},
+		{
+			name: "empty format is json",
+			opts: FormatOptions{},
+			want: `{"level":"INFO","msg":"user created","req":{"status":201,"took":"1.5s"},"span_id":"b7ad6b7169203331",` +
+				`"time":"2026-01-02T15:04:05Z","trace_id":"0af7651916cd43dd8448eb211c80319c","user":"alice"}` + "\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			record, attrs, traceID, spanID := goldenRecord()
+
+			got, err := formatLine(tt.opts, record, attrs, traceID, spanID)
+			if err != nil {
+				t.Fatalf("formatLine: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("formatLine =\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJSONLineGolden(t *testing.T) {
+	record, attrs, traceID, spanID := goldenRecord()
+	// Reserved keys take precedence over attributes with the same key
+	attrs = append(attrs, slog.String("msg", "shadowed"), slog.String("level", "shadowed"))
+
+	tests := []struct {
+		name    string
+		traceID string
+		spanID  string
+		source  string
+		want    string
+	}{
+		{
+			name:    "trace and source",
+			traceID: traceID,
+			spanID:  spanID,
+			source:  "service/service.go:42",
+			want: `{"level":"INFO","msg":"user created","req":{"status":201,"took":"1.5s"},"source":"service/service.go:42",` +
+				`"span_id":"b7ad6b7169203331","time":"2026-01-02T15:04:05Z","trace_id":"0af7651916cd43dd8448eb211c80319c","user":"alice"}`,
+		},
+		{
+			name:    "trace without span",
+			traceID: traceID,
+			want: `{"level":"INFO","msg":"user created","req":{"status":201,"took":"1.5s"},` +
+				`"time":"2026-01-02T15:04:05Z","trace_id":"0af7651916cd43dd8448eb211c80319c","user":"alice"}`,
+		},
+		{
+			name: "no trace",
+			want: `{"level":"INFO","msg":"user created","req":{"status":201,"took":"1.5s"},"time":"2026-01-02T15:04:05Z","user":"alice"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := jsonLine(record, attrs, tt.traceID, tt.spanID, tt.source)
+			if err != nil {
+				t.Fatalf("jsonLine: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("jsonLine =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatLineAddSource(t *testing.T) {
+	var pcs [1]uintptr
+	runtime.Callers(1, pcs[:])
+	record := slog.NewRecord(time.Date(2026, 1, 2, 15, 4, 5, 0, time.UTC), slog.LevelWarn, "slow", pcs[0])
+
+	tests := []struct {
+		format Format
+		want   string // Text preceding the source
+	}{
+		{format: FormatText, want: "[WARN] - 2026-01-02 15:04:05 - slow logger/format_test.go:"},
+		{format: FormatLogfmt, want: "time=2026-01-02T15:04:05Z level=WARN msg=slow source=logger/format_test.go:"},
+		{format: FormatJSON, want: `{"level":"WARN","msg":"slow","source":"logger/format_test.go:`},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.format), func(t *testing.T) {
+			got, err := formatLine(FormatOptions{Format: tt.format, AddSource: true}, record, nil, "", "")
+			if err != nil {
+				t.Fatalf("formatLine: %v", err)
+			}
+			if !strings.HasPrefix(string(got), tt.want) {
+				t.Errorf("formatLine = %q, want prefix %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLogfmtQuoting(t *testing.T) {
+	tests := []struct {
+		name  string
+		key   string
+		value string
+		want  string
+	}{
+		{name: "plain", key: "k", value: "plain", want: "k=plain"},
+		{name: "space", key: "k", value: "two words", want: `k="two words"`},
+		{name: "equals", key: "k", value: "a=b", want: `k="a=b"`},
+		{name: "quotes", key: "k", value: `say "hi"`, want: `k="say \"hi\""`},
+		{name: "empty", key: "k", value: "", want: `k=""`},
+		{name: "tab", key: "k", value: "a\tb", want: `k="a\tb"`},
+		{name: "newline", key: "k", value: "line\nbreak", want: `k="line\nbreak"`},
+		{name: "control character", key: "k", value: "a\x00b", want: `k="a\x00b"`},
+		{name: "unicode", key: "k", value: "фильм", want: "k=фильм"},
+		{name: "key with space", key: "two words", value: "v", want: `"two words"=v`},
+		{name: "key with equals", key: "a=b", value: "v", want: `"a=b"=v`},
+	}
+
+	record := slog.NewRecord(time.Date(2026, 1, 2, 15, 4, 5, 0, time.UTC), slog.LevelInfo, "quoted", 0)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := formatLine(FormatOptions{Format: FormatLogfmt}, record, []slog.Attr{slog.String(tt.key, tt.value)}, "", "")
+			if err != nil {
+				t.Fatalf("formatLine: %v", err)
+			}
+			want := "time=2026-01-02T15:04:05Z level=INFO msg=quoted " + tt.want + "\n"
+			if string(got) != want {
+				t.Errorf("formatLine = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestLevelLabelColors(t *testing.T) {
+	tests := []struct {
+		level slog.Level
+		want  string
+	}{
+		{level: slog.LevelDebug, want: ColorBlue + "[DEBUG]" + ColorReset},
+		{level: slog.LevelInfo, want: ColorGreen + "[INFO]" + ColorReset},
+		{level: slog.LevelWarn, want: ColorYellow + "[WARN]" + ColorReset},
+		{level: slog.LevelError, want: ColorRed + "[ERROR]" + ColorReset},
+		{level: slog.LevelError + 4, want: ColorRed + "[ERROR+4]" + ColorReset},
+	}
+
+	for _, tt := range tests {
+		if got := levelLabel(tt.level, true); got != tt.want {
+			t.Errorf("levelLabel(%s, true) = %q, want %q", tt.level, got, tt.want)
+		}
+		if got, want := levelLabel(tt.level, false), "["+tt.level.String()+"]"; got != want {
+			t.Errorf("levelLabel(%s, false) = %q, want %q", tt.level, got, want)
+		}
+	}
+}
+
+func TestUseColor(t *testing.T) {
+	file, err := os.Create(filepath.Join(t.TempDir(), "out.log"))
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	defer file.Close()
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Pipe: %v", err)
+	}
+	defer reader.Close()
+	defer writer.Close()
+	// A terminal is detected as a character device, which /dev/null also is
+	device, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatalf("OpenFile: %v", err)
+	}
+	defer device.Close()
+
+	tests := []struct {
+		name    string
+		mode    ColorMode
+		noColor string
+		file    *os.File
+		want    bool
+	}{
+		{name: "always", mode: ColorAlways, file: file, want: true},
+		{name: "always ignores NO_COLOR", mode: ColorAlways, noColor: "1", file: file, want: true},
+		{name: "never", mode: ColorNever, file: file, want: false},
+		{name: "auto regular file", mode: ColorAuto, file: file, want: false},
+		{name: "auto pipe", mode: ColorAuto, file: writer, want: false},
+		{name: "empty mode is auto", mode: "", file: file, want: false},
+		{name: "auto character device", mode: ColorAuto, file: device, want: true},
+		{name: "auto character device with NO_COLOR", mode: ColorAuto, noColor: "1", file: device, want: false},
+		{name: "never character device", mode: ColorNever, file: device, want: false},
+		{name: "auto without file", mode: ColorAuto, file: nil, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("NO_COLOR", tt.noColor)
+
+			if got := UseColor(tt.mode, tt.file); got != tt.want {
+				t.Errorf("UseColor(%q) = %v, want %v", tt.mode, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,176 @@
+package logger
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/xdg-go/scram"
+)
+
+// PartitionKey selects the Kafka message key, and therefore the partition, of a record.
+type PartitionKey string
+
+const (
+	PartitionByService   PartitionKey = "service"    // All records of the service go to one partition
+	PartitionByTraceID   PartitionKey = "trace_id"   // Records of a trace go to one partition
+	PartitionByRequestID PartitionKey = "request_id" // Records of a request go to one partition
+)
+
+// SASL mechanisms supported by KafkaOptions.
+const (
+	SASLPlain       = sarama.SASLTypePlaintext
+	SASLScramSHA256 = sarama.SASLTypeSCRAMSHA256
+	SASLScramSHA512 = sarama.SASLTypeSCRAMSHA512
+)
+
+// KafkaOptions configures the Kafka producer of KafkaHandler.
+type KafkaOptions struct {
+	Service   string // Service name, used as the message key with PartitionByService
+	AddSource bool   // Add the source file:line of the logging call
+
+	TLS                   bool   // Connect to brokers over TLS
+	TLSCAFile             string // CA to verify brokers; system roots if empty
+	TLSCertFile           string // Client certificate for mutual TLS
+	TLSKeyFile            string // Client key for mutual TLS
+	TLSInsecureSkipVerify bool   // Do not verify broker certificates
+
+	SASLMechanism string // SASL mechanism: SASLPlain, SASLScramSHA256 or SASLScramSHA512; empty disables SASL
+	SASLUser      string // SASL user
+	SASLPassword  string // SASL password
+
+	Compression  string        // Compression codec: none, gzip, snappy, lz4 or zstd
+	Idempotent   bool          // Enable the idempotent producer
+	BatchSize    int           // Bytes to accumulate before a batch is sent; 0 sends as soon as possible
+	Linger       time.Duration // Maximum time to accumulate a batch; 0 sends as soon as possible
+	PartitionKey PartitionKey  // Message key of records; empty means PartitionByService
+}
+
+// newSaramaConfig builds the producer configuration from the options.
+func newSaramaConfig(opts KafkaOptions) (*sarama.Config, error) {
+	config := sarama.NewConfig()
+	config.Producer.RequiredAcks = sarama.WaitForAll
+	config.Producer.Retry.Max = 5
+	config.Producer.Return.Successes = false
+	config.Producer.Return.Errors = true
+	config.Producer.Partitioner = sarama.NewHashPartitioner
+	config.Producer.Flush.Bytes = opts.BatchSize
+	config.Producer.Flush.Frequency = opts.Linger
+
+	if opts.Compression != "" {
+		if err := config.Producer.Compression.UnmarshalText([]byte(opts.Compression)); err != nil {
+			return nil, fmt.Errorf("invalid kafka compression: %w", err)
+		}
+	}
+
+	if opts.Idempotent {
+		// The idempotent producer requires a single in-flight request per broker
+		config.Producer.Idempotent = true
+		config.Net.MaxOpenRequests = 1
+	}
+
+	if opts.TLS {
+		tlsConfig, err := kafkaTLSConfig(opts)
+		if err != nil {
+			return nil, err
+		}
+		config.Net.TLS.Enable = true
+		config.Net.TLS.Config = tlsConfig
+	}
+
+	if opts.SASLMechanism != "" {
+		config.Net.SASL.Enable = true
+		config.Net.SASL.Mechanism = sarama.SASLMechanism(opts.SASLMechanism)
+		config.Net.SASL.User = opts.SASLUser
+		config.Net.SASL.Password = opts.SASLPassword
+		switch config.Net.SASL.Mechanism {
+		case SASLPlain:
+		case SASLScramSHA256:
+			config.Net.SASL.SCRAMClientGeneratorFunc = func() sarama.SCRAMClient { return &scramClient{hash: scram.SHA256} }
+		case SASLScramSHA512:
+			config.Net.SASL.SCRAMClientGeneratorFunc = func() sarama.SCRAMClient { return &scramClient{hash: scram.SHA512} }
+		default:
+			return nil, fmt.Errorf("unsupported kafka SASL mechanism %q", opts.SASLMechanism)
+		}
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid kafka producer configuration: %w", err)
+	}
+	return config, nil
+}
+
+// kafkaTLSConfig loads the CA and client certificate for connections to brokers.
+func kafkaTLSConfig(opts KafkaOptions) (*tls.Config, error) {
+	config := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: opts.TLSInsecureSkipVerify,
+	}
+
+	if opts.TLSCAFile != "" {
+		pem, err := os.ReadFile(opts.TLSCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read kafka CA file: %w", err)
+		}
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no valid certificates found in kafka CA file %s", opts.TLSCAFile)
+		}
+	}
+
+	if opts.TLSCertFile != "" {
+		cert, err := tls.LoadX509KeyPair(opts.TLSCertFile, opts.TLSKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load kafka client key pair: %w", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
+
+// scramClient implements sarama.SCRAMClient on top of xdg-go/scram.
+type scramClient struct {
+	hash         scram.HashGeneratorFcn
+	conversation *scram.ClientConversation
+}
+
+func (c *scramClient) Begin(user, password, authzID string) error {
+	client, err := c.hash.NewClient(user, password, authzID)
+	if err != nil {
+		return err
+	}
+	c.conversation = client.NewConversation()
+	return nil
+}
+
+func (c *scramClient) Step(challenge string) (string, error) {
+	return c.conversation.Step(challenge)
+}
+
+func (c *scramClient) Done() bool {
+	return c.conversation.Done()
+}
+
+// messageKey returns the message key of the entry for the partition key option.
+// A nil key lets the partitioner pick a random partition.
+func (k *kafkaSink) messageKey(entry logEntry) sarama.Encoder {
+	switch k.options.PartitionKey {
+	case PartitionByTraceID:
+		if entry.traceID != "" {
+			return sarama.StringEncoder(entry.traceID)
+		}
+		return nil
+	case PartitionByRequestID:
+		for _, attr := range entry.attrs {
+			if attr.Key == RequestIDKey {
+				return sarama.StringEncoder(attr.Value.String())
+			}
+		}
+		return nil
+	default:
+		return sarama.StringEncoder(k.options.Service)
+	}
+}
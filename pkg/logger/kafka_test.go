@@ -0,0 +1,302 @@
+package logger
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"log/slog"
+	"math/big"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/xdg-go/scram"
+)
+
+// writeKeyPair writes a self-signed certificate and its key to dir.
+func writeKeyPair(t *testing.T, dir string) (certFile, keyFile string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "review"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("CreateCertificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("MarshalECPrivateKey: %v", err)
+	}
+
+	certFile = filepath.Join(dir, "client.crt")
+	keyFile = filepath.Join(dir, "client.key")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	return certFile, keyFile
+}
+
+// scramHash returns the hash of the SCRAM client created by the generator.
+func scramHash(t *testing.T, config *sarama.Config) scram.HashGeneratorFcn {
+	t.Helper()
+	if config.Net.SASL.SCRAMClientGeneratorFunc == nil {
+		t.Fatal("SCRAMClientGeneratorFunc is not set")
+	}
+	client, ok := config.Net.SASL.SCRAMClientGeneratorFunc().(*scramClient)
+	if !ok {
+		t.Fatalf("SCRAM client is %T, want *scramClient", config.Net.SASL.SCRAMClientGeneratorFunc())
+	}
+	if err := client.Begin("review", "secret", ""); err != nil {
+		t.Fatalf("Begin: %v", err)
+	}
+	return client.hash
+}
+
+func sameFunc(a, b any) bool {
+	return reflect.ValueOf(a).Pointer() == reflect.ValueOf(b).Pointer()
+}
+
+func TestNewSaramaConfig(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeKeyPair(t, dir)
+	garbage := filepath.Join(dir, "garbage.pem")
+	if err := os.WriteFile(garbage, []byte("not a certificate"), 0600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	missing := filepath.Join(dir, "missing.pem")
+
+	tests := []struct {
+		name    string
+		opts    KafkaOptions
+		wantErr string
+		check   func(t *testing.T, config *sarama.Config)
+	}{
+		{
+			name: "defaults",
+			opts: KafkaOptions{},
+			check: func(t *testing.T, config *sarama.Config) {
+				if config.Producer.RequiredAcks != sarama.WaitForAll || config.Producer.Idempotent {
+					t.Errorf("acks = %d, idempotent = %v, want all, false", config.Producer.RequiredAcks, config.Producer.Idempotent)
+				}
+				if config.Producer.Compression != sarama.CompressionNone {
+					t.Errorf("compression = %s, want none", config.Producer.Compression)
+				}
+				if config.Net.SASL.Enable || config.Net.TLS.Enable {
+					t.Errorf("SASL = %v, TLS = %v, want both disabled", config.Net.SASL.Enable, config.Net.TLS.Enable)
+				}
+				if !config.Producer.Return.Errors || config.Producer.Return.Successes {
+					t.Error("producer must return errors and no successes")
+				}
+			},
+		},
+		{
+			name: "batching",
+			opts: KafkaOptions{BatchSize: 64 << 10, Linger: 5 * time.Millisecond},
+			check: func(t *testing.T, config *sarama.Config) {
+				if config.Producer.Flush.Bytes != 64<<10 || config.Producer.Flush.Frequency != 5*time.Millisecond {
+					t.Errorf("flush = %d bytes, %s, want 65536 bytes, 5ms", config.Producer.Flush.Bytes, config.Producer.Flush.Frequency)
+				}
+			},
+		},
+		{
+			name: "compression gzip",
+			opts: KafkaOptions{Compression: "gzip"},
+			check: func(t *testing.T, config *sarama.Config) {
+				if config.Producer.Compression != sarama.CompressionGZIP {
+					t.Errorf("compression = %s, want gzip", config.Producer.Compression)
+				}
+			},
+		},
+		{
+			name: "compression zstd",
+			opts: KafkaOptions{Compression: "zstd"},
+			check: func(t *testing.T, config *sarama.Config) {
+				if config.Producer.Compression != sarama.CompressionZSTD {
+					t.Errorf("compression = %s, want zstd", config.Producer.Compression)
+				}
+			},
+		},
+		{
+			name:    "invalid compression",
+			opts:    KafkaOptions{Compression: "brotli"},
+			wantErr: "invalid kafka compression",
+		},
+		{
+			name: "idempotent",
+			opts: KafkaOptions{Idempotent: true},
+			check: func(t *testing.T, config *sarama.Config) {
+				if !config.Producer.Idempotent || config.Producer.RequiredAcks != sarama.WaitForAll || config.Net.MaxOpenRequests != 1 {
+					t.Errorf("idempotent = %v, acks = %d, max open requests = %d, want true, all, 1",
+						config.Producer.Idempotent, config.Producer.RequiredAcks, config.Net.MaxOpenRequests)
+				}
+			},
+		},
+		{
+			name: "sasl plain",
+			opts: KafkaOptions{SASLMechanism: SASLPlain, SASLUser: "review", SASLPassword: "secret"},
+			check: func(t *testing.T, config *sarama.Config) {
+				sasl := config.Net.SASL
+				if !sasl.Enable || sasl.Mechanism != sarama.SASLTypePlaintext || sasl.User != "review" || sasl.Password != "secret" {
+					t.Errorf("SASL = %v %s %s, want PLAIN with the user and password", sasl.Enable, sasl.Mechanism, sasl.User)
+				}
+				if sasl.SCRAMClientGeneratorFunc != nil {
+					t.Error("SCRAMClientGeneratorFunc is set for PLAIN")
+				}
+			},
+		},
+		{
+			name: "sasl scram sha256",
+			opts: KafkaOptions{SASLMechanism: SASLScramSHA256, SASLUser: "review", SASLPassword: "secret"},
+			check: func(t *testing.T, config *sarama.Config) {
+				if config.Net.SASL.Mechanism != sarama.SASLTypeSCRAMSHA256 || !sameFunc(scramHash(t, config), scram.SHA256) {
+					t.Errorf("SASL mechanism = %s, want SCRAM-SHA-256 with a SHA-256 client", config.Net.SASL.Mechanism)
+				}
+			},
+		},
+		{
+			name: "sasl scram sha512",
+			opts: KafkaOptions{SASLMechanism: SASLScramSHA512, SASLUser: "review", SASLPassword: "secret"},
+			check: func(t *testing.T, config *sarama.Config) {
+				if config.Net.SASL.Mechanism != sarama.SASLTypeSCRAMSHA512 || !sameFunc(scramHash(t, config), scram.SHA512) {
+					t.Errorf("SASL mechanism = %s, want SCRAM-SHA-512 with a SHA-512 client", config.Net.SASL.Mechanism)
+				}
+			},
+		},
+		{
+			name:    "unsupported sasl mechanism",
+			opts:    KafkaOptions{SASLMechanism: "GSSAPI", SASLUser: "review", SASLPassword: "secret"},
+			wantErr: `unsupported kafka SASL mechanism "GSSAPI"`,
+		},
+		{
+			name:    "sasl without user",
+			opts:    KafkaOptions{SASLMechanism: SASLPlain, SASLPassword: "secret"},
+			wantErr: "invalid kafka producer configuration",
+		},
+		{
+			name: "tls with ca and client certificate",
+			opts: KafkaOptions{TLS: true, TLSCAFile: certFile, TLSCertFile: certFile, TLSKeyFile: keyFile},
+			check: func(t *testing.T, config *sarama.Config) {
+				tlsConfig := config.Net.TLS.Config
+				if !config.Net.TLS.Enable || tlsConfig == nil {
+					t.Fatal("TLS is not enabled")
+				}
+				if tlsConfig.RootCAs == nil || len(tlsConfig.Certificates) != 1 {
+					t.Errorf("root CAs = %v, certificates = %d, want the CA and one certificate", tlsConfig.RootCAs != nil, len(tlsConfig.Certificates))
+				}
+				if tlsConfig.MinVersion != tls.VersionTLS12 || tlsConfig.InsecureSkipVerify {
+					t.Errorf("min version = %x, insecure = %v, want TLS 1.2, false", tlsConfig.MinVersion, tlsConfig.InsecureSkipVerify)
+				}
+			},
+		},
+		{
+			name: "tls with system roots",
+			opts: KafkaOptions{TLS: true, TLSInsecureSkipVerify: true},
+			check: func(t *testing.T, config *sarama.Config) {
+				tlsConfig := config.Net.TLS.Config
+				if tlsConfig.RootCAs != nil || len(tlsConfig.Certificates) != 0 || !tlsConfig.InsecureSkipVerify {
+					t.Errorf("TLS config = %+v, want system roots without a client certificate", tlsConfig)
+				}
+			},
+		},
+		{
+			name:    "tls missing ca",
+			opts:    KafkaOptions{TLS: true, TLSCAFile: missing},
+			wantErr: "failed to read kafka CA file",
+		},
+		{
+			name:    "tls invalid ca",
+			opts:    KafkaOptions{TLS: true, TLSCAFile: garbage},
+			wantErr: "no valid certificates found in kafka CA file",
+		},
+		{
+			name:    "tls missing client certificate",
+			opts:    KafkaOptions{TLS: true, TLSCertFile: missing, TLSKeyFile: keyFile},
+			wantErr: "failed to load kafka client key pair",
+		},
+		{
+			name:    "tls client certificate without key",
+			opts:    KafkaOptions{TLS: true, TLSCertFile: certFile},
+			wantErr: "failed to load kafka client key pair",
+		},
+		{
+			name: "tls files ignored without tls",
+			opts: KafkaOptions{TLSCAFile: missing},
+			check: func(t *testing.T, config *sarama.Config) {
+				if config.Net.TLS.Enable {
+					t.Error("TLS is enabled")
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := newSaramaConfig(tt.opts)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("newSaramaConfig = %v, want %q", err, tt.wantErr)
+				}
+				if strings.Contains(err.Error(), "secret") {
+					t.Errorf("error leaks the SASL password: %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("newSaramaConfig: %v", err)
+			}
+			tt.check(t, config)
+		})
+	}
+}
+
+func TestMessageKey(t *testing.T) {
+	entry := logEntry{
+		traceID: "0af7651916cd43dd8448eb211c80319c",
+		attrs:   []slog.Attr{slog.String("user", "alice"), slog.String(RequestIDKey, "req-1")},
+	}
+
+	tests := []struct {
+		name  string
+		key   PartitionKey
+		entry logEntry
+		want  sarama.Encoder
+	}{
+		{name: "default is service", key: "", entry: entry, want: sarama.StringEncoder("review")},
+		{name: "service", key: PartitionByService, entry: entry, want: sarama.StringEncoder("review")},
+		{name: "trace id", key: PartitionByTraceID, entry: entry, want: sarama.StringEncoder("0af7651916cd43dd8448eb211c80319c")},
+		{name: "trace id missing", key: PartitionByTraceID, entry: logEntry{}, want: nil},
+		{name: "request id", key: PartitionByRequestID, entry: entry, want: sarama.StringEncoder("req-1")},
+		{name: "request id missing", key: PartitionByRequestID, entry: logEntry{traceID: entry.traceID}, want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k := &kafkaSink{options: KafkaOptions{Service: "review", PartitionKey: tt.key}}
+
+			if got := k.messageKey(tt.entry); got != tt.want {
+				t.Errorf("messageKey = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
@@ -2,14 +2,19 @@ package logger
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"sync"
+	"syscall"
 	"time"
 
 	"github.com/IBM/sarama"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 	"log/slog"
 )
 
@@ -22,140 +27,320 @@ const (
 	ColorBlue   = "\033[34m"
 )
 
-// KafkaHandler sends logs to Kafka topic asynchronously.
+// logEntry is a record queued for asynchronous processing together with its
+// attributes and the trace context captured at the time it was logged.
+type logEntry struct {
+	record  slog.Record
+	attrs   []slog.Attr
+	traceID string
+	spanID  string
+	carrier propagation.MapCarrier
+}
+
+// newLogEntry captures the record, its attributes resolved against the handler
+// state and the trace context from ctx.
+func newLogEntry(ctx context.Context, record slog.Record, state handlerState, withCarrier bool) logEntry {
+	entry := logEntry{
+		record: slog.NewRecord(record.Time, record.Level, record.Message, record.PC),
+		attrs:  state.collect(record),
+	}
+	entry.traceID, entry.spanID = traceIDs(ctx)
+	if withCarrier && entry.traceID != "" {
+		entry.carrier = propagation.MapCarrier{}
+		otel.GetTextMapPropagator().Inject(ctx, entry.carrier)
+	}
+	return entry
+}
+
+// traceIDs returns the trace and span IDs of the span stored in ctx, or the trace
+// ID added with WithTraceID if there is no span.
+func traceIDs(ctx context.Context) (traceID, spanID string) {
+	spanCtx := trace.SpanContextFromContext(ctx)
+	if !spanCtx.IsValid() {
+		return fieldsFromContext(ctx).traceID, ""
+	}
+	return spanCtx.TraceID().String(), spanCtx.SpanID().String()
+}
+
+// Sink names reported in QueueStats and used to address handler levels.
+const (
+	SinkKafka  = "kafka"
+	SinkFile   = "file"
+	SinkStdout = "stdout"
+)
+
+// QueueStats describes the state of an asynchronous handler queue.
+type QueueStats struct {
+	Name     string // Handler name
+	Length   int    // Records waiting in the queue
+	Capacity int    // Queue capacity
+	Dropped  uint64 // Records dropped because the queue was full
+	Lost     uint64 // Records not delivered before the drain deadline on Close
+
+	Degraded     bool   // The sink is unavailable and records are spooled to disk
+	SpoolRecords int64  // Records waiting in the on-disk spool
+	SpoolBytes   int64  // Size of the records waiting in the on-disk spool
+	SpoolDropped uint64 // Records dropped because the spool was full
+}
+
+// KafkaHandler sends logs to Kafka topic asynchronously. Handlers derived with
+// WithAttrs and WithGroup share the producer and the queue of their parent.
 type KafkaHandler struct {
-	producer  sarama.AsyncProducer
+	*kafkaSink
+	state handlerState
+}
+
+// kafkaSink is the producer, queue and spool shared by a KafkaHandler and its derived handlers.
+type kafkaSink struct {
+	brokers   []string
 	topic     string
-	logChan   chan slog.Record
+	options   KafkaOptions
+	queue     *logQueue
+	spool     *spool
 	wg        sync.WaitGroup
 	quitChan  chan struct{}
 	saramaCfg *sarama.Config
+	level     slog.LevelVar
+
+	mu         sync.Mutex
+	producer   sarama.AsyncProducer // nil while Kafka is unreachable
+	errorsDone chan struct{}
+	diverted   bool // New records go to the spool after a producer error until it is replayed
 }
 
-// NewKafkaHandler initializes a new KafkaHandler.
-func NewKafkaHandler(brokers []string, topic string, bufferSize int) (*KafkaHandler, error) {
-	config := sarama.NewConfig()
-	config.Producer.RequiredAcks = sarama.WaitForAll
-	config.Producer.Retry.Max = 5
-	config.Producer.Return.Successes = false
-	config.Producer.Return.Errors = true
-	config.Producer.Partitioner = sarama.NewHashPartitioner
+// NewKafkaHandler initializes a new KafkaHandler. If Kafka is unreachable the handler
+// starts in degraded mode: records are spooled to disk and replayed once it recovers.
+func NewKafkaHandler(brokers []string, topic string, kafka KafkaOptions, queue QueueOptions, spool SpoolOptions) (*KafkaHandler, error) {
+	config, err := newSaramaConfig(kafka)
+	if err != nil {
+		return nil, err
+	}
 
-	producer, err := sarama.NewAsyncProducer(brokers, config)
+	logSpool, err := openSpool(spool.Dir, spool.MaxSize)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create async producer: %w", err)
+		return nil, fmt.Errorf("failed to open log spool: %w", err)
 	}
 
-	handler := &KafkaHandler{
-		producer:  producer,
+	handler := &KafkaHandler{kafkaSink: &kafkaSink{
+		brokers:   brokers,
 		topic:     topic,
-		logChan:   make(chan slog.Record, bufferSize),
+		options:   kafka,
+		queue:     newLogQueue(queue),
+		spool:     logSpool,
 		quitChan:  make(chan struct{}),
 		saramaCfg: config,
+	}}
+
+	producer, err := sarama.NewAsyncProducer(brokers, config)
+	if err != nil {
+		fmt.Printf("kafka is unavailable, spooling log records to disk: %v\n", err)
+	} else {
+		handler.startProducer(producer)
 	}
 
 	handler.wg.Add(1)
 	go handler.processLogs()
 
 	handler.wg.Add(1)
-	go handler.handleProducerErrors()
+	go handler.replayLoop()
 
 	return handler, nil
 }
 
-// processLogs sends logs into channel for asynchronous processing.
-func (k *KafkaHandler) processLogs() {
-	defer k.wg.Done()
-	for {
-		select {
-		case record := <-k.logChan:
-			logEntry := map[string]interface{}{
-				"time":  record.Time.Format(time.RFC3339),
-				"level": record.Level.String(),
-				"msg":   record.Message,
-			}
-			payload, err := json.Marshal(logEntry)
-			if err != nil {
-				fmt.Printf("failed to marshal log entry: %v\n", err)
-				continue
-			}
+// startProducer makes the producer active and starts handling its errors.
+func (k *kafkaSink) startProducer(producer sarama.AsyncProducer) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
 
-			message := &sarama.ProducerMessage{
-				Topic: k.topic,
-				Key:   sarama.StringEncoder("log"),
-				Value: sarama.ByteEncoder(payload),
-			}
+	k.producer = producer
+	k.errorsDone = make(chan struct{})
+	go k.handleProducerErrors(producer, k.errorsDone)
+}
 
-			k.producer.Input() <- message
+// activeProducer returns the producer, or nil while Kafka is unreachable.
+func (k *kafkaSink) activeProducer() sarama.AsyncProducer {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return k.producer
+}
 
-		case <-k.quitChan:
-			return
-		}
+// directProducer returns the producer if records can be passed to it directly, or nil
+// while Kafka is unreachable or records are diverted to the spool after a producer error.
+func (k *kafkaSink) directProducer() sarama.AsyncProducer {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if k.diverted {
+		return nil
 	}
+	return k.producer
 }
 
-// handleProducerErrors processes producer errors.
-func (k *KafkaHandler) handleProducerErrors() {
+// resumeDirectSends lets send pass records to the producer again once the spool is empty.
+func (k *kafkaSink) resumeDirectSends() {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if !k.spool.pending() {
+		k.diverted = false
+	}
+}
+
+// processLogs sends queued records to the producer. On Close it drains the queue
+// until the drain deadline.
+func (k *kafkaSink) processLogs() {
 	defer k.wg.Done()
 	for {
 		select {
-		case err, ok := <-k.producer.Errors():
-			if !ok {
-				return
-			}
-			fmt.Printf("failed to write message to kafka: %v\n", err)
+		case entry := <-k.queue.entries:
+			k.send(entry, nil)
 		case <-k.quitChan:
+			k.queue.drain(k.send)
 			return
 		}
 	}
 }
 
-// Enabled checks if the level is enabled.
+// send passes the record to the producer, or to the spool while Kafka is unreachable,
+// a producer error has diverted records to the spool or earlier records are waiting
+// for replay. It reports false if the deadline expired
+// before the producer accepted the message; a nil deadline waits indefinitely.
+func (k *kafkaSink) send(entry logEntry, deadline <-chan time.Time) bool {
+	var source string
+	if k.options.AddSource {
+		source = recordSource(entry.record)
+	}
+	payload, err := jsonLine(entry.record, entry.attrs, entry.traceID, entry.spanID, source)
+	if err != nil {
+		fmt.Printf("failed to marshal log entry: %v\n", err)
+		return true
+	}
+
+	message := &sarama.ProducerMessage{
+		Topic: k.topic,
+		Key:   k.messageKey(entry),
+		Value: sarama.ByteEncoder(payload),
+	}
+	for key, value := range entry.carrier {
+		message.Headers = append(message.Headers, sarama.RecordHeader{Key: []byte(key), Value: []byte(value)})
+	}
+
+	producer := k.directProducer()
+	if producer == nil || k.spool.pending() {
+		k.spoolMessage(message)
+		return true
+	}
+
+	select {
+	case producer.Input() <- message:
+		return true
+	case <-deadline:
+		return false
+	}
+}
+
+// handleProducerErrors spools messages the producer failed to deliver until the
+// producer is closed. The first error diverts new records to the spool before the
+// failed message is appended, so no record overtakes it, and they stay diverted
+// until replay has emptied the spool.
+func (k *kafkaSink) handleProducerErrors(producer sarama.AsyncProducer, done chan struct{}) {
+	defer close(done)
+	for err := range producer.Errors() {
+		k.mu.Lock()
+		if !k.diverted {
+			fmt.Printf("failed to write message to kafka, spooling log records to disk: %v\n", err)
+			k.diverted = true
+		}
+		k.spoolMessage(err.Msg)
+		k.mu.Unlock()
+	}
+}
+
+// Enabled reports whether the level is at or above the minimum level of the handler.
 func (k *KafkaHandler) Enabled(ctx context.Context, level slog.Level) bool {
-	return true
+	return level >= k.level.Level()
 }
 
-// Handle sends logs into a channel for asynchronous processing.
+// Name returns the sink name of the handler.
+func (k *kafkaSink) Name() string {
+	return SinkKafka
+}
+
+// LevelVar returns the minimum level of the handler, which can be changed at runtime.
+func (k *kafkaSink) LevelVar() *slog.LevelVar {
+	return &k.level
+}
+
+// Handle queues the record for asynchronous processing according to the overflow policy.
 func (k *KafkaHandler) Handle(ctx context.Context, record slog.Record) error {
-	select {
-	case k.logChan <- record:
-		return nil
-	default:
-		fmt.Println("log channel is full, dropping log message")
-		return nil
-	}
+	k.queue.push(newLogEntry(ctx, record, k.state, true))
+	return nil
 }
 
-// WithAttrs adds attributes to the handler.
+// Stats returns the current queue and spool state.
+func (k *kafkaSink) Stats() QueueStats {
+	stats := k.queue.stats(SinkKafka)
+	stats.SpoolRecords, stats.SpoolBytes, stats.SpoolDropped = k.spool.stats()
+	stats.Degraded = k.directProducer() == nil || stats.SpoolRecords > 0
+	return stats
+}
+
+// WithAttrs returns a handler that adds attrs to every record.
 func (k *KafkaHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
-	return k
+	return &KafkaHandler{kafkaSink: k.kafkaSink, state: k.state.withAttrs(attrs)}
 }
 
-// WithGroup adds a group to the handler.
+// WithGroup returns a handler that nests subsequent attributes into the group.
 func (k *KafkaHandler) WithGroup(name string) slog.Handler {
-	return k
+	return &KafkaHandler{kafkaSink: k.kafkaSink, state: k.state.withGroup(name)}
 }
 
-// Close gracefully shuts down KafkaHandler.
-func (k *KafkaHandler) Close() error {
+// Close gracefully shuts down KafkaHandler. Queued records are delivered and the
+// producer is flushed until the drain deadline; undelivered records stay in the
+// spool for the next run. The spool is closed only after the producer has returned
+// every undelivered message, even if that takes longer than the deadline.
+func (k *kafkaSink) Close() error {
+	deadline := time.NewTimer(k.queue.opts.DrainTimeout)
+	defer deadline.Stop()
+
 	close(k.quitChan)
 	k.wg.Wait()
-	if err := k.producer.Close(); err != nil {
-		return fmt.Errorf("failed to close producer: %w", err)
+
+	var err error
+	k.mu.Lock()
+	producer, errorsDone := k.producer, k.errorsDone
+	k.mu.Unlock()
+	if producer != nil {
+		producer.AsyncClose()
+		select {
+		case <-errorsDone:
+		case <-deadline.C:
+			err = fmt.Errorf("timed out flushing kafka producer")
+			<-errorsDone
+		}
 	}
-	return nil
+	return errors.Join(err, k.spool.Close())
 }
 
-// FileHandler saves logs to a file asynchronously.
+// FileHandler saves logs to a file asynchronously, by default as JSON lines. The file is rotated
+// according to RotationOptions and reopened on SIGHUP. Handlers derived with
+// WithAttrs and WithGroup share the file and the queue of their parent.
 type FileHandler struct {
-	file     *os.File
-	logChan  chan slog.Record
+	*fileSink
+	state handlerState
+}
+
+// fileSink is the file and queue shared by a FileHandler and its derived handlers.
+type fileSink struct {
+	file     *rotatingFile
+	format   FormatOptions
+	queue    *logQueue
 	wg       sync.WaitGroup
 	quitChan chan struct{}
+	hupChan  chan os.Signal
+	level    slog.LevelVar
 }
 
 // NewFileHandler initializes a new FileHandler.
-func NewFileHandler(serviceName string, bufferSize int) (*FileHandler, error) {
+func NewFileHandler(serviceName string, format FormatOptions, queue QueueOptions, rotation RotationOptions) (*FileHandler, error) {
 	logDir := filepath.Join("logs", serviceName)
 	err := os.MkdirAll(logDir, os.ModePerm)
 	if err != nil {
@@ -163,16 +348,19 @@ func NewFileHandler(serviceName string, bufferSize int) (*FileHandler, error) {
 	}
 
 	logFilePath := filepath.Join(logDir, "app.log")
-	file, err := os.OpenFile(logFilePath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	file, err := openRotatingFile(logFilePath, rotation)
 	if err != nil {
 		return nil, err
 	}
 
-	handler := &FileHandler{
+	handler := &FileHandler{fileSink: &fileSink{
 		file:     file,
-		logChan:  make(chan slog.Record, bufferSize),
+		format:   format,
+		queue:    newLogQueue(queue),
 		quitChan: make(chan struct{}),
-	}
+		hupChan:  make(chan os.Signal, 1),
+	}}
+	signal.Notify(handler.hupChan, syscall.SIGHUP)
 
 	handler.wg.Add(1)
 	go handler.processLogs()
@@ -180,104 +368,136 @@ func NewFileHandler(serviceName string, bufferSize int) (*FileHandler, error) {
 	return handler, nil
 }
 
-// processLogs reads log records from a channel and writes them to the file.
-func (f *FileHandler) processLogs() {
+// processLogs writes queued records to the file. On Close it drains the queue
+// until the drain deadline.
+func (f *fileSink) processLogs() {
 	defer f.wg.Done()
 	for {
 		select {
-		case record := <-f.logChan:
-			line := fmt.Sprintf("[%s] - %s - %s", record.Level.String(), record.Time.Format(time.RFC3339), record.Message)
-			f.file.Write(append([]byte(line), '\n'))
+		case entry := <-f.queue.entries:
+			f.write(entry, nil)
+		case <-f.hupChan:
+			// An external logrotate has moved the file away
+			if err := f.file.Reopen(); err != nil {
+				fmt.Printf("failed to reopen log file: %v\n", err)
+			}
 		case <-f.quitChan:
+			f.queue.drain(f.write)
 			return
 		}
 	}
 }
 
-// Enabled checks if the level is enabled.
-func (f *FileHandler) Enabled(ctx context.Context, level slog.Level) bool {
+// write appends the record to the file. File writes do not wait on the deadline.
+func (f *fileSink) write(entry logEntry, _ <-chan time.Time) bool {
+	line, err := formatLine(f.format, entry.record, entry.attrs, entry.traceID, entry.spanID)
+	if err != nil {
+		fmt.Printf("failed to marshal log entry: %v\n", err)
+		return true
+	}
+	if _, err := f.file.Write(line); err != nil {
+		fmt.Printf("failed to write log entry: %v\n", err)
+	}
 	return true
 }
 
-// Handle sends logs into a channel for asynchronous processing.
+// Enabled reports whether the level is at or above the minimum level of the handler.
+func (f *FileHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= f.level.Level()
+}
+
+// Name returns the sink name of the handler.
+func (f *fileSink) Name() string {
+	return SinkFile
+}
+
+// LevelVar returns the minimum level of the handler, which can be changed at runtime.
+func (f *fileSink) LevelVar() *slog.LevelVar {
+	return &f.level
+}
+
+// Handle queues the record for asynchronous processing according to the overflow policy.
 func (f *FileHandler) Handle(ctx context.Context, record slog.Record) error {
-	select {
-	case f.logChan <- record:
-		return nil
-	default:
-		fmt.Println("file log channel is full, dropping log message")
-		return nil
-	}
+	f.queue.push(newLogEntry(ctx, record, f.state, false))
+	return nil
+}
+
+// Stats returns the current queue state.
+func (f *fileSink) Stats() QueueStats {
+	return f.queue.stats(SinkFile)
 }
 
-// WithAttrs adds attributes to the handler.
+// WithAttrs returns a handler that adds attrs to every record.
 func (f *FileHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
-	return f
+	return &FileHandler{fileSink: f.fileSink, state: f.state.withAttrs(attrs)}
 }
 
-// WithGroup adds a group to the handler.
+// WithGroup returns a handler that nests subsequent attributes into the group.
 func (f *FileHandler) WithGroup(name string) slog.Handler {
-	return f
+	return &FileHandler{fileSink: f.fileSink, state: f.state.withGroup(name)}
 }
 
-// Close gracefully shuts down FileHandler.
-func (f *FileHandler) Close() error {
+// Close gracefully shuts down FileHandler. Queued records are written until the
+// drain deadline.
+func (f *fileSink) Close() error {
+	signal.Stop(f.hupChan)
 	close(f.quitChan)
 	f.wg.Wait()
 	return f.file.Close()
 }
 
-// StdoutHandler sends logs to stdout with colored text synchronously.
+// StdoutHandler sends logs to stdout synchronously, by default as text lines
+// with key=value attributes.
 type StdoutHandler struct {
 	writer *os.File
+	format FormatOptions
+	level  *slog.LevelVar
+	state  handlerState
 }
 
 // NewStdoutHandler initializes a new StdoutHandler.
-func NewStdoutHandler() *StdoutHandler {
+func NewStdoutHandler(format FormatOptions) *StdoutHandler {
 	return &StdoutHandler{
 		writer: os.Stdout,
+		format: format,
+		level:  new(slog.LevelVar),
 	}
 }
 
-// Enabled checks if the level is enabled.
+// Enabled reports whether the level is at or above the minimum level of the handler.
 func (s *StdoutHandler) Enabled(ctx context.Context, level slog.Level) bool {
-	return true
+	return level >= s.level.Level()
 }
 
-// Handle processes and outputs the log record to stdout with colors synchronously.
+// Name returns the sink name of the handler.
+func (s *StdoutHandler) Name() string {
+	return SinkStdout
+}
+
+// LevelVar returns the minimum level of the handler, which can be changed at runtime.
+func (s *StdoutHandler) LevelVar() *slog.LevelVar {
+	return s.level
+}
+
+// Handle formats the log record and writes it to stdout synchronously.
 func (s *StdoutHandler) Handle(ctx context.Context, record slog.Record) error {
-	color := ColorReset
-	switch record.Level {
-	case slog.LevelDebug:
-		color = ColorBlue
-	case slog.LevelInfo:
-		color = ColorGreen
-	case slog.LevelWarn:
-		color = ColorYellow
-	case slog.LevelError:
-		color = ColorRed
-	default:
-		color = ColorReset
-	}
-	line := fmt.Sprintf("%s[%s]%s - %s - %s\n",
-		color,
-		record.Level.String(),
-		ColorReset,
-		record.Time.Format("2006-01-02 15:04:05"),
-		record.Message,
-	)
-	_, err := s.writer.Write([]byte(line))
+	traceID, spanID := traceIDs(ctx)
+	line, err := formatLine(s.format, record, s.state.collect(record), traceID, spanID)
+	if err != nil {
+		return err
+	}
+	_, err = s.writer.Write(line)
 	return err
 }
 
-// WithAttrs adds attributes to the handler.
+// WithAttrs returns a handler that adds attrs to every record.
 func (s *StdoutHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
-	return s
+	return &StdoutHandler{writer: s.writer, format: s.format, level: s.level, state: s.state.withAttrs(attrs)}
 }
 
-// WithGroup adds a group to the handler.
+// WithGroup returns a handler that nests subsequent attributes into the group.
 func (s *StdoutHandler) WithGroup(name string) slog.Handler {
-	return s
+	return &StdoutHandler{writer: s.writer, format: s.format, level: s.level, state: s.state.withGroup(name)}
 }
 
 // Close is a no-op for synchronous handler.
@@ -288,6 +508,7 @@ func (s *StdoutHandler) Close() error {
 // MultiHandler combines multiple handlers.
 type MultiHandler struct {
 	handlers []slog.Handler
+	onClose  []func() // Called by CloseAll before the handlers are closed
 }
 
 // NewMultiHandler initializes a new MultiHandler.
@@ -307,10 +528,13 @@ func (m *MultiHandler) Enabled(ctx context.Context, level slog.Level) bool {
 	return false
 }
 
-// Handle adds the record to all handlers.
+// Handle adds the record to all handlers enabled for its level.
 func (m *MultiHandler) Handle(ctx context.Context, record slog.Record) error {
 	var firstErr error
 	for _, h := range m.handlers {
+		if !h.Enabled(ctx, record.Level) {
+			continue
+		}
 		if err := h.Handle(ctx, record); err != nil && firstErr == nil {
 			firstErr = err
 		}
@@ -336,33 +560,162 @@ func (m *MultiHandler) WithGroup(name string) slog.Handler {
 	return NewMultiHandler(handlers...)
 }
 
-// CloseAll closes all handlers that implement the Close method.
-func (m *MultiHandler) CloseAll() {
+// UnwrapHandler returns the handler wrapped by handlers such as RedactHandler and
+// SampleHandler.
+func UnwrapHandler(h slog.Handler) slog.Handler {
+	for {
+		wrapper, ok := h.(interface{ Unwrap() slog.Handler })
+		if !ok {
+			return h
+		}
+		h = wrapper.Unwrap()
+	}
+}
+
+// Stats returns queue stats of all handlers that report them.
+func (m *MultiHandler) Stats() []QueueStats {
+	var stats []QueueStats
 	for _, h := range m.handlers {
-		if closer, ok := h.(interface{ Close() error }); ok {
-			closer.Close()
+		if reporter, ok := UnwrapHandler(h).(interface{ Stats() QueueStats }); ok {
+			stats = append(stats, reporter.Stats())
 		}
 	}
+	return stats
+}
+
+// leveledHandler is a handler whose minimum level can be changed at runtime.
+type leveledHandler interface {
+	Name() string
+	LevelVar() *slog.LevelVar
+}
+
+// LogLevels returns the minimum level of every sink.
+func (m *MultiHandler) LogLevels() map[string]slog.Level {
+	levels := make(map[string]slog.Level)
+	for _, h := range m.handlers {
+		if leveled, ok := UnwrapHandler(h).(leveledHandler); ok {
+			levels[leveled.Name()] = leveled.LevelVar().Level()
+		}
+	}
+	return levels
+}
+
+// SetLogLevel changes the minimum level of the named sink.
+func (m *MultiHandler) SetLogLevel(sink string, level slog.Level) error {
+	for _, h := range m.handlers {
+		if leveled, ok := UnwrapHandler(h).(leveledHandler); ok && leveled.Name() == sink {
+			leveled.LevelVar().Set(level)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown log sink %q", sink)
+}
+
+// CloseAll writes what wrappers such as SampleHandler still hold, then closes all
+// handlers that implement the Close method and returns their errors.
+func (m *MultiHandler) CloseAll() error {
+	for _, fn := range m.onClose {
+		fn()
+	}
+	var errs []error
+	for _, h := range m.handlers {
+		if closer, ok := UnwrapHandler(h).(interface{ Close() error }); ok {
+			errs = append(errs, closer.Close())
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Options configures the handlers created by NewLogger.
+type Options struct {
+	Brokers     []string   // Kafka brokers
+	KafkaTopic  string     // Kafka topic for log records
+	ServiceName string     // Service name, used as the log directory name
+	BufferSize  int        // Queue size of the asynchronous handlers
+	KafkaLevel  slog.Level // Minimum level sent to Kafka
+	FileLevel   slog.Level // Minimum level written to the log file
+	StdoutLevel slog.Level // Minimum level written to stdout
+
+	Overflow     OverflowPolicy  // Full-queue policy of the asynchronous handlers
+	BlockTimeout time.Duration   // Maximum wait for queue room with the Block policy
+	DrainTimeout time.Duration   // Maximum time each asynchronous handler spends on Close
+	FileRotation RotationOptions // Rotation and retention of the log file
+	SpoolMaxSize int64           // Maximum size in bytes of the on-disk spool used while Kafka is unavailable
+	Kafka        KafkaOptions    // Security and tuning of the Kafka producer
+
+	StdoutFormat Format    // Line layout of stdout; empty means FormatText
+	FileFormat   Format    // Line layout of the log file; empty means FormatJSON
+	Color        ColorMode // When text lines on stdout are colored; empty means ColorAuto
+	AddSource    bool      // Add the source file:line of the logging call to every sink
+
+	RedactKeys     []string   // Attribute keys whose values are masked
+	RedactPatterns []string   // Builtin pattern names or regular expressions masked in messages and values
+	KafkaRedact    RedactMode // Redaction before Kafka; empty means RedactFull
+	FileRedact     RedactMode // Redaction before the log file; empty means RedactFull
+	StdoutRedact   RedactMode // Redaction before stdout; empty means RedactFull
+
+	Sampling SampleOptions // Rate limiting of repeated messages across all sinks
 }
 
 // NewLogger initializes the combined logger with Kafka, File, and Stdout handlers.
-func NewLogger(brokers []string, kafkaTopic, serviceName string, bufferSize int) (*slog.Logger, error) {
-	kafkaHandler, err := NewKafkaHandler(brokers, kafkaTopic, bufferSize)
+func NewLogger(opts Options) (*slog.Logger, error) {
+	redactor, err := NewRedactor(opts.RedactKeys, opts.RedactPatterns)
+	if err != nil {
+		return nil, err
+	}
+
+	queue := QueueOptions{
+		Size:         opts.BufferSize,
+		Overflow:     opts.Overflow,
+		BlockTimeout: opts.BlockTimeout,
+		DrainTimeout: opts.DrainTimeout,
+	}
+
+	spool := SpoolOptions{
+		Dir:     filepath.Join("logs", opts.ServiceName, "spool"),
+		MaxSize: opts.SpoolMaxSize,
+	}
+
+	kafka := opts.Kafka
+	kafka.Service = opts.ServiceName
+	kafka.AddSource = opts.AddSource
+
+	kafkaHandler, err := NewKafkaHandler(opts.Brokers, opts.KafkaTopic, kafka, queue, spool)
 	if err != nil {
 		return nil, err
 	}
+	kafkaHandler.LevelVar().Set(opts.KafkaLevel)
 
-	fileHandler, err := NewFileHandler(serviceName, bufferSize)
+	fileHandler, err := NewFileHandler(opts.ServiceName, FormatOptions{Format: opts.FileFormat, AddSource: opts.AddSource}, queue, opts.FileRotation)
 	if err != nil {
 		kafkaHandler.Close()
 		return nil, err
 	}
+	fileHandler.LevelVar().Set(opts.FileLevel)
 
-	stdoutHandler := NewStdoutHandler()
-
-	multiHandler := NewMultiHandler(kafkaHandler, fileHandler, stdoutHandler)
+	stdoutFormat := opts.StdoutFormat
+	if stdoutFormat == "" {
+		stdoutFormat = FormatText
+	}
+	stdoutHandler := NewStdoutHandler(FormatOptions{
+		Format:    stdoutFormat,
+		Color:     stdoutFormat == FormatText && UseColor(opts.Color, os.Stdout),
+		AddSource: opts.AddSource,
+	})
+	stdoutHandler.LevelVar().Set(opts.StdoutLevel)
+
+	multiHandler := NewMultiHandler(
+		NewRedactHandler(kafkaHandler, redactor.ForMode(opts.KafkaRedact)),
+		NewRedactHandler(fileHandler, redactor.ForMode(opts.FileRedact)),
+		NewRedactHandler(stdoutHandler, redactor.ForMode(opts.StdoutRedact)),
+	)
 
-	logger := slog.New(multiHandler)
+	handler := NewSampleHandler(multiHandler, opts.Sampling)
+	if sampler, ok := handler.(*SampleHandler); ok {
+		// The summary of the last interval is written while the sinks are still open
+		multiHandler.onClose = append(multiHandler.onClose, sampler.Flush)
+	}
+	logger := slog.New(handler)
 
 	return logger, nil
 }
@@ -0,0 +1,230 @@
+package logger
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+)
+
+// fakeProducer is an AsyncProducer that accepts messages on its input channel and
+// returns the errors sent to its errors channel.
+type fakeProducer struct {
+	sarama.AsyncProducer
+	input  chan *sarama.ProducerMessage
+	errors chan *sarama.ProducerError
+	closed chan struct{} // Closed by AsyncClose
+}
+
+func newFakeProducer() *fakeProducer {
+	return &fakeProducer{
+		input:  make(chan *sarama.ProducerMessage, 10),
+		errors: make(chan *sarama.ProducerError),
+		closed: make(chan struct{}),
+	}
+}
+
+func (p *fakeProducer) Input() chan<- *sarama.ProducerMessage {
+	return p.input
+}
+
+func (p *fakeProducer) Errors() <-chan *sarama.ProducerError {
+	return p.errors
+}
+
+func (p *fakeProducer) AsyncClose() {
+	close(p.closed)
+}
+
+// newTestKafkaSink creates a sink with a spool in dir and an active fake producer.
+// No goroutines other than the producer error handler are started.
+func newTestKafkaSink(t *testing.T, dir string, spoolBytes int64, drain time.Duration) (*kafkaSink, *fakeProducer) {
+	t.Helper()
+	logSpool, err := openSpool(dir, spoolBytes)
+	if err != nil {
+		t.Fatalf("openSpool: %v", err)
+	}
+	k := &kafkaSink{
+		topic:    "logs",
+		queue:    newLogQueue(QueueOptions{Size: 10, DrainTimeout: drain}),
+		spool:    logSpool,
+		quitChan: make(chan struct{}),
+	}
+	producer := newFakeProducer()
+	k.startProducer(producer)
+	return k, producer
+}
+
+// waitFor polls cond until it holds or the test times out.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); !cond(); time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+	}
+}
+
+// replaySpool removes all spooled records as a successful replay would and returns
+// the messages of their log lines in order.
+func replaySpool(t *testing.T, s *spool) []string {
+	t.Helper()
+	lines, end, err := s.peek(100)
+	if err != nil {
+		t.Fatalf("peek: %v", err)
+	}
+	if err := s.commit(end, len(lines)); err != nil {
+		t.Fatalf("commit: %v", err)
+	}
+	msgs := make([]string, 0, len(lines))
+	for _, line := range lines {
+		var record spoolRecord
+		var payload struct{ Msg string }
+		if err := json.Unmarshal(line, &record); err != nil {
+			t.Fatalf("invalid spool record %q: %v", line, err)
+		}
+		if err := json.Unmarshal(record.Value, &payload); err != nil {
+			t.Fatalf("invalid spooled log line %q: %v", record.Value, err)
+		}
+		msgs = append(msgs, payload.Msg)
+	}
+	return msgs
+}
+
+// assertSentDirectly checks that the next message passed to the producer is msg.
+func assertSentDirectly(t *testing.T, producer *fakeProducer, msg string) {
+	t.Helper()
+	select {
+	case message := <-producer.input:
+		value, _ := message.Value.Encode()
+		if !strings.Contains(string(value), `"msg":"`+msg+`"`) {
+			t.Errorf("producer got %s, want %s", value, msg)
+		}
+	default:
+		t.Errorf("%s was not passed to the producer", msg)
+	}
+}
+
+// assertNotSentDirectly checks that nothing was passed to the producer.
+func assertNotSentDirectly(t *testing.T, producer *fakeProducer) {
+	t.Helper()
+	select {
+	case message := <-producer.input:
+		value, _ := message.Value.Encode()
+		t.Errorf("producer got %s while records are diverted to the spool", value)
+	default:
+	}
+}
+
+func TestProducerErrorDivertsRecordsToSpool(t *testing.T) {
+	k, producer := newTestKafkaSink(t, t.TempDir(), 1<<20, time.Second)
+	defer k.spool.Close()
+
+	k.send(testEntry("a"), nil)
+	failed := <-producer.input
+	producer.errors <- &sarama.ProducerError{Msg: failed, Err: errors.New("broker down")}
+	waitFor(t, "the failed message to be spooled", k.spool.pending)
+
+	// A replay attempt that finds the spool not empty keeps records diverted
+	k.resumeDirectSends()
+	k.send(testEntry("b"), nil)
+	assertNotSentDirectly(t, producer)
+	if !k.Stats().Degraded {
+		t.Error("Degraded = false, want true while records are diverted")
+	}
+
+	if got := replaySpool(t, k.spool); fmt.Sprint(got) != "[a b]" {
+		t.Errorf("replayed %v, want [a b]", got)
+	}
+	k.resumeDirectSends()
+	k.send(testEntry("c"), nil)
+	assertSentDirectly(t, producer, "c")
+	if k.Stats().Degraded {
+		t.Error("Degraded = true, want false after the spool is replayed")
+	}
+}
+
+func TestProducerErrorDivertsRecordsWhenSpoolIsFull(t *testing.T) {
+	// The failed message does not fit into the spool, so the spool stays empty
+	k, producer := newTestKafkaSink(t, t.TempDir(), 1, time.Second)
+	defer k.spool.Close()
+
+	k.send(testEntry("a"), nil)
+	failed := <-producer.input
+	producer.errors <- &sarama.ProducerError{Msg: failed, Err: errors.New("broker down")}
+	waitFor(t, "the failed message to be dropped", func() bool {
+		_, _, dropped := k.spool.stats()
+		return dropped == 1
+	})
+
+	k.send(testEntry("b"), nil)
+	assertNotSentDirectly(t, producer)
+
+	k.resumeDirectSends()
+	k.send(testEntry("c"), nil)
+	assertSentDirectly(t, producer, "c")
+}
+
+func TestKafkaSinkCloseSpoolsErrorsAfterDeadline(t *testing.T) {
+	dir := t.TempDir()
+	k, producer := newTestKafkaSink(t, dir, 1<<20, 10*time.Millisecond)
+
+	// The producer returns an undelivered message only after the drain deadline
+	go func() {
+		<-producer.closed
+		time.Sleep(50 * time.Millisecond)
+		producer.errors <- &sarama.ProducerError{
+			Msg: &sarama.ProducerMessage{Topic: "logs", Value: sarama.ByteEncoder(`{"msg":"late"}`)},
+			Err: errors.New("broker down"),
+		}
+		close(producer.errors)
+	}()
+
+	if err := k.Close(); err == nil || !strings.Contains(err.Error(), "timed out flushing kafka producer") {
+		t.Errorf("Close = %v, want a flush timeout", err)
+	}
+
+	reopened, err := openSpool(dir, 1<<20)
+	if err != nil {
+		t.Fatalf("openSpool: %v", err)
+	}
+	defer reopened.Close()
+	if got := replaySpool(t, reopened); fmt.Sprint(got) != "[late]" {
+		t.Errorf("spool after Close holds %v, want [late]", got)
+	}
+}
+
+func TestKafkaSinkCloseWithoutProducer(t *testing.T) {
+	dir := t.TempDir()
+	logSpool, err := openSpool(filepath.Join(dir, "spool"), 1<<20)
+	if err != nil {
+		t.Fatalf("openSpool: %v", err)
+	}
+	k := &kafkaSink{
+		topic:    "logs",
+		queue:    newLogQueue(QueueOptions{Size: 10, DrainTimeout: time.Second}),
+		spool:    logSpool,
+		quitChan: make(chan struct{}),
+	}
+	k.queue.push(testEntry("a"))
+	k.wg.Add(1)
+	go k.processLogs()
+
+	if err := k.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	reopened, err := openSpool(filepath.Join(dir, "spool"), 1<<20)
+	if err != nil {
+		t.Fatalf("openSpool: %v", err)
+	}
+	defer reopened.Close()
+	if got := replaySpool(t, reopened); fmt.Sprint(got) != "[a]" {
+		t.Errorf("spool after Close holds %v, want [a]", got)
+	}
+}
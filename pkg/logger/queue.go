@@ -0,0 +1,116 @@
+package logger
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// OverflowPolicy selects what an asynchronous handler does when its queue is full.
+type OverflowPolicy string
+
+const (
+	DropNewest OverflowPolicy = "drop-newest" // Discard the record being logged
+	DropOldest OverflowPolicy = "drop-oldest" // Discard the oldest queued record to make room
+	Block      OverflowPolicy = "block"       // Wait up to BlockTimeout for room, then discard the record
+)
+
+// QueueOptions configures the queue of an asynchronous handler.
+type QueueOptions struct {
+	Size         int            // Queue capacity
+	Overflow     OverflowPolicy // Full-queue policy; empty means DropNewest
+	BlockTimeout time.Duration  // Maximum wait for room with the Block policy
+	DrainTimeout time.Duration  // Maximum time Close spends delivering queued records
+}
+
+// logQueue is a bounded queue of log entries with a full-queue policy and drop counters.
+type logQueue struct {
+	entries chan logEntry
+	opts    QueueOptions
+
+	dropped atomic.Uint64 // Records discarded because the queue was full
+	lost    atomic.Uint64 // Records still queued when the drain deadline expired
+}
+
+// newLogQueue creates a queue with the given options.
+func newLogQueue(opts QueueOptions) *logQueue {
+	if opts.Overflow == "" {
+		opts.Overflow = DropNewest
+	}
+	return &logQueue{
+		entries: make(chan logEntry, opts.Size),
+		opts:    opts,
+	}
+}
+
+// push enqueues the entry according to the overflow policy.
+func (q *logQueue) push(entry logEntry) {
+	select {
+	case q.entries <- entry:
+		return
+	default:
+	}
+
+	switch q.opts.Overflow {
+	case DropOldest:
+		// Make room by discarding the oldest entry; if another writer takes the
+		// freed slot first, the new entry is discarded instead
+		select {
+		case <-q.entries:
+			q.dropped.Add(1)
+		default:
+		}
+		select {
+		case q.entries <- entry:
+		default:
+			q.dropped.Add(1)
+		}
+	case Block:
+		timer := time.NewTimer(q.opts.BlockTimeout)
+		defer timer.Stop()
+		select {
+		case q.entries <- entry:
+		case <-timer.C:
+			q.dropped.Add(1)
+		}
+	default:
+		q.dropped.Add(1)
+	}
+}
+
+// drain passes the entries still queued to deliver until the queue is empty or
+// the drain deadline expires. deliver reports false if the deadline expired before
+// the entry was delivered. Entries left after the deadline are counted as lost.
+func (q *logQueue) drain(deliver func(entry logEntry, deadline <-chan time.Time) bool) {
+	deadline := time.NewTimer(q.opts.DrainTimeout)
+	defer deadline.Stop()
+
+	for {
+		select {
+		case <-deadline.C:
+			q.lost.Add(uint64(len(q.entries)))
+			return
+		default:
+		}
+
+		select {
+		case entry := <-q.entries:
+			if !deliver(entry, deadline.C) {
+				q.lost.Add(1 + uint64(len(q.entries)))
+				return
+			}
+		default:
+			return
+		}
+	}
+}
+
+// stats returns the current queue state of the named sink.
+func (q *logQueue) stats(name string) QueueStats {
+	return QueueStats{
+		Name:     name,
+		Length:   len(q.entries),
+		Capacity: cap(q.entries),
+		Dropped:  q.dropped.Load(),
+		Lost:     q.lost.Load(),
+	}
+}
@@ -0,0 +1,183 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testEntry(msg string) logEntry {
+	return logEntry{record: slog.NewRecord(time.Now(), slog.LevelInfo, msg, 0)}
+}
+
+// queuedMessages removes all queued entries and returns their messages in order.
+func queuedMessages(q *logQueue) []string {
+	var msgs []string
+	for {
+		select {
+		case entry := <-q.entries:
+			msgs = append(msgs, entry.record.Message)
+		default:
+			return msgs
+		}
+	}
+}
+
+func TestLogQueueOverflowPolicies(t *testing.T) {
+	tests := []struct {
+		name        string
+		opts        QueueOptions
+		want        []string
+		wantDropped uint64
+	}{
+		{
+			name:        "default drops newest",
+			opts:        QueueOptions{Size: 2},
+			want:        []string{"a", "b"},
+			wantDropped: 1,
+		},
+		{
+			name:        "drop newest",
+			opts:        QueueOptions{Size: 2, Overflow: DropNewest},
+			want:        []string{"a", "b"},
+			wantDropped: 1,
+		},
+		{
+			name:        "drop oldest",
+			opts:        QueueOptions{Size: 2, Overflow: DropOldest},
+			want:        []string{"b", "c"},
+			wantDropped: 1,
+		},
+		{
+			name:        "block times out",
+			opts:        QueueOptions{Size: 2, Overflow: Block, BlockTimeout: 20 * time.Millisecond},
+			want:        []string{"a", "b"},
+			wantDropped: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := newLogQueue(tt.opts)
+			for _, msg := range []string{"a", "b", "c"} {
+				q.push(testEntry(msg))
+			}
+
+			if got := queuedMessages(q); strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("queued = %v, want %v", got, tt.want)
+			}
+			if stats := q.stats("test"); stats.Dropped != tt.wantDropped || stats.Lost != 0 {
+				t.Errorf("dropped = %d, lost = %d, want %d, 0", stats.Dropped, stats.Lost, tt.wantDropped)
+			}
+		})
+	}
+}
+
+func TestLogQueueBlockWaitsForRoom(t *testing.T) {
+	q := newLogQueue(QueueOptions{Size: 1, Overflow: Block, BlockTimeout: 5 * time.Second})
+	q.push(testEntry("a"))
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		<-q.entries
+	}()
+	start := time.Now()
+	q.push(testEntry("b"))
+
+	if elapsed := time.Since(start); elapsed >= 5*time.Second {
+		t.Errorf("push waited %v, want it to return once room is freed", elapsed)
+	}
+	if got := queuedMessages(q); strings.Join(got, ",") != "b" {
+		t.Errorf("queued = %v, want [b]", got)
+	}
+	if dropped := q.stats("test").Dropped; dropped != 0 {
+		t.Errorf("dropped = %d, want 0", dropped)
+	}
+}
+
+func TestLogQueueDrainDeliversBeforeDeadline(t *testing.T) {
+	q := newLogQueue(QueueOptions{Size: 3, DrainTimeout: time.Second})
+	for _, msg := range []string{"a", "b", "c"} {
+		q.push(testEntry(msg))
+	}
+
+	var delivered []string
+	q.drain(func(entry logEntry, _ <-chan time.Time) bool {
+		delivered = append(delivered, entry.record.Message)
+		return true
+	})
+
+	if strings.Join(delivered, ",") != "a,b,c" {
+		t.Errorf("delivered = %v, want [a b c]", delivered)
+	}
+	if lost := q.stats("test").Lost; lost != 0 {
+		t.Errorf("lost = %d, want 0", lost)
+	}
+}
+
+func TestLogQueueDrainCountsLostAfterDeadline(t *testing.T) {
+	q := newLogQueue(QueueOptions{Size: 3, DrainTimeout: 20 * time.Millisecond})
+	for _, msg := range []string{"a", "b", "c"} {
+		q.push(testEntry(msg))
+	}
+
+	// The first record is delivered, the second waits for a destination until the deadline
+	var delivered []string
+	q.drain(func(entry logEntry, deadline <-chan time.Time) bool {
+		if entry.record.Message == "b" {
+			<-deadline
+			return false
+		}
+		delivered = append(delivered, entry.record.Message)
+		return true
+	})
+
+	if strings.Join(delivered, ",") != "a" {
+		t.Errorf("delivered = %v, want [a]", delivered)
+	}
+	if lost := q.stats("test").Lost; lost != 2 {
+		t.Errorf("lost = %d, want 2", lost)
+	}
+}
+
+func TestFileHandlerCloseDrainsQueue(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	file, err := openRotatingFile(path, RotationOptions{})
+	if err != nil {
+		t.Fatalf("openRotatingFile: %v", err)
+	}
+
+	const total = 100
+	handler := &FileHandler{fileSink: &fileSink{
+		file:     file,
+		format:   FormatOptions{Format: FormatJSON},
+		queue:    newLogQueue(QueueOptions{Size: total, DrainTimeout: 5 * time.Second}),
+		quitChan: make(chan struct{}),
+		hupChan:  make(chan os.Signal, 1),
+	}}
+	// Queue every record before the writer starts so that Close has to drain them
+	for i := 0; i < total; i++ {
+		handler.Handle(context.Background(), slog.NewRecord(time.Now(), slog.LevelInfo, "record", 0))
+	}
+	handler.wg.Add(1)
+	go handler.processLogs()
+
+	if err := handler.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	if lines := strings.Count(string(data), "\n"); lines != total {
+		t.Errorf("file has %d records, want %d", lines, total)
+	}
+	if stats := handler.Stats(); stats.Dropped != 0 || stats.Lost != 0 {
+		t.Errorf("dropped = %d, lost = %d, want 0, 0", stats.Dropped, stats.Lost)
+	}
+}
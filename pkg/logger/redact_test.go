@@ -0,0 +1,75 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// stdoutToFile returns a StdoutHandler writing JSON lines to a temporary file and
+// a function returning what has been written.
+func stdoutToFile(t *testing.T) (*StdoutHandler, func() string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "stdout")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("create output: %v", err)
+	}
+	t.Cleanup(func() { file.Close() })
+
+	handler := NewStdoutHandler(FormatOptions{Format: FormatJSON})
+	handler.writer = file
+	return handler, func() string {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("read output: %v", err)
+		}
+		return string(data)
+	}
+}
+
+func TestRedactHandlerRedactsContextFields(t *testing.T) {
+	redactor, err := NewRedactor([]string{"token"}, []string{"email"})
+	if err != nil {
+		t.Fatalf("NewRedactor: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		mode    RedactMode
+		present []string
+		absent  []string
+	}{
+		{"full", RedactFull, []string{`"token":"` + Redacted, `"request_id":"req-1"`}, []string{"secret-value", "user@example.com"}},
+		{"keys", RedactKeys, []string{`"token":"` + Redacted, "user@example.com"}, []string{"secret-value"}},
+		{"off", RedactOff, []string{"secret-value", "user@example.com", `"request_id":"req-1"`}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stdout, output := stdoutToFile(t)
+			logger := slog.New(NewRedactHandler(stdout, redactor.ForMode(tt.mode)))
+
+			ctx := WithRequestID(context.Background(), "req-1")
+			ctx = ContextWith(ctx, slog.String("token", "secret-value"), slog.String("contact", "user@example.com"))
+			logger.InfoContext(ctx, "request handled")
+
+			got := output()
+			if n := strings.Count(got, `"request_id"`); n != 1 {
+				t.Errorf("request_id written %d times, want once: %s", n, got)
+			}
+			for _, want := range tt.present {
+				if !strings.Contains(got, want) {
+					t.Errorf("output %s does not contain %q", got, want)
+				}
+			}
+			for _, unwanted := range tt.absent {
+				if strings.Contains(got, unwanted) {
+					t.Errorf("output %s contains %q", got, unwanted)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,280 @@
+package logger
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// backupTimeFormat is the timestamp format in rotated file names.
+const backupTimeFormat = "20060102T150405"
+
+// RotationOptions configures rotation and retention of the log file.
+type RotationOptions struct {
+	MaxSize    int64         // Rotate when the file would exceed this many bytes; 0 disables size rotation
+	Interval   time.Duration // Rotate at every interval boundary (e.g. 24h rotates at midnight UTC); 0 disables time rotation
+	Compress   bool          // Gzip rotated files
+	MaxBackups int           // Maximum number of rotated files to keep; 0 keeps all
+	MaxAge     time.Duration // Maximum age of rotated files to keep; 0 keeps all
+}
+
+// rotatingFile is an io.Writer that appends to a file and rotates it by size and time.
+// Rotated files are renamed to <name>-<timestamp><ext>, optionally compressed, and
+// pruned by count and age in the background.
+type rotatingFile struct {
+	path string
+	opts RotationOptions
+
+	mu       sync.Mutex
+	file     *os.File
+	size     int64
+	openedAt time.Time
+
+	housekeeping sync.Mutex     // Serializes compression and pruning
+	pending      sync.WaitGroup // Background housekeeping in progress
+}
+
+// openRotatingFile opens the log file for appending, creating it if necessary.
+func openRotatingFile(path string, opts RotationOptions) (*rotatingFile, error) {
+	r := &rotatingFile{path: path, opts: opts}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// open opens the file at r.path. The caller must hold r.mu or own r exclusively.
+func (r *rotatingFile) open() error {
+	file, err := os.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	r.file, r.size, r.openedAt = file, info.Size(), time.Now()
+	if info.Size() > 0 {
+		// The file was written before a restart or reopen: its interval is the one
+		// its content belongs to, not the one it was reopened in
+		r.openedAt = info.ModTime()
+	}
+	return nil
+}
+
+// Write appends p to the file, rotating it first if p would exceed MaxSize or
+// the current interval has ended.
+func (r *rotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.shouldRotate(int64(len(p))) {
+		if err := r.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+// shouldRotate reports whether writing n more bytes requires rotation.
+func (r *rotatingFile) shouldRotate(n int64) bool {
+	if r.size == 0 {
+		return false
+	}
+	if r.opts.MaxSize > 0 && r.size+n > r.opts.MaxSize {
+		return true
+	}
+	if r.opts.Interval > 0 {
+		now := time.Now().UTC()
+		return !now.Truncate(r.opts.Interval).Equal(r.openedAt.UTC().Truncate(r.opts.Interval))
+	}
+	return false
+}
+
+// rotate renames the current file to a backup and opens a new one.
+func (r *rotatingFile) rotate() error {
+	if err := r.file.Close(); err != nil {
+		return fmt.Errorf("failed to close log file: %w", err)
+	}
+
+	backup := r.backupName(time.Now())
+	if err := os.Rename(r.path, backup); err != nil {
+		// Keep writing to the same file rather than losing records
+		if openErr := r.open(); openErr != nil {
+			return fmt.Errorf("failed to reopen log file: %w", openErr)
+		}
+		return fmt.Errorf("failed to rotate log file: %w", err)
+	}
+	if err := r.open(); err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+
+	r.pending.Add(1)
+	go func() {
+		defer r.pending.Done()
+		r.housekeeping.Lock()
+		defer r.housekeeping.Unlock()
+
+		if r.opts.Compress {
+			if err := compressFile(backup); err != nil {
+				fmt.Printf("failed to compress rotated log file: %v\n", err)
+			}
+		}
+		if err := r.prune(); err != nil {
+			fmt.Printf("failed to remove old log files: %v\n", err)
+		}
+	}()
+	return nil
+}
+
+// backupName returns a file name for a backup created at t that does not exist yet.
+func (r *rotatingFile) backupName(t time.Time) string {
+	ext := filepath.Ext(r.path)
+	prefix := strings.TrimSuffix(r.path, ext) + "-" + t.UTC().Format(backupTimeFormat)
+	name := prefix + ext
+	for i := 1; fileExists(name) || fileExists(name+".gz"); i++ {
+		name = fmt.Sprintf("%s.%d%s", prefix, i, ext)
+	}
+	return name
+}
+
+// Reopen closes and reopens the file at the same path. It lets an external
+// logrotate move the file away and have new records written to a fresh file.
+func (r *rotatingFile) Reopen() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.file.Close(); err != nil {
+		return fmt.Errorf("failed to close log file: %w", err)
+	}
+	return r.open()
+}
+
+// Close waits for background housekeeping and closes the file.
+func (r *rotatingFile) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.pending.Wait()
+	return r.file.Close()
+}
+
+// prune removes rotated files beyond MaxBackups and older than MaxAge.
+func (r *rotatingFile) prune() error {
+	if r.opts.MaxBackups <= 0 && r.opts.MaxAge <= 0 {
+		return nil
+	}
+
+	ext := filepath.Ext(r.path)
+	base := filepath.Base(strings.TrimSuffix(r.path, ext))
+	entries, err := os.ReadDir(filepath.Dir(r.path))
+	if err != nil {
+		return err
+	}
+
+	var backups []fs.FileInfo
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || !isBackupName(entry.Name(), base, ext) {
+			continue
+		}
+		if info, err := entry.Info(); err == nil {
+			backups = append(backups, info)
+		}
+	}
+	// Newest first
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].ModTime().After(backups[j].ModTime())
+	})
+
+	cutoff := time.Now().Add(-r.opts.MaxAge)
+	var errs []error
+	for i, info := range backups {
+		expired := r.opts.MaxBackups > 0 && i >= r.opts.MaxBackups
+		if r.opts.MaxAge > 0 && info.ModTime().Before(cutoff) {
+			expired = true
+		}
+		if expired {
+			if err := os.Remove(filepath.Join(filepath.Dir(r.path), info.Name())); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// isBackupName reports whether name is a rotated file named by backupName:
+// <base>-<timestamp>[.N]<ext>, optionally with a .gz suffix.
+func isBackupName(name, base, ext string) bool {
+	rest, ok := strings.CutPrefix(name, base+"-")
+	if !ok {
+		return false
+	}
+	rest = strings.TrimSuffix(rest, ".gz")
+	if rest, ok = strings.CutSuffix(rest, ext); !ok {
+		return false
+	}
+
+	stamp, seq, hasSeq := strings.Cut(rest, ".")
+	if hasSeq {
+		if n, err := strconv.Atoi(seq); err != nil || n < 1 || strconv.Itoa(n) != seq {
+			return false
+		}
+	}
+	_, err := time.Parse(backupTimeFormat, stamp)
+	return err == nil
+}
+
+// compressFile gzips path into path.gz and removes the original.
+func compressFile(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(path+".gz", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+
+	gz := gzip.NewWriter(dst)
+	if _, err := io.Copy(gz, src); err != nil {
+		gz.Close()
+		dst.Close()
+		os.Remove(path + ".gz")
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		dst.Close()
+		os.Remove(path + ".gz")
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		os.Remove(path + ".gz")
+		return err
+	}
+	// Keep the rotation time for retention by age
+	if info, err := src.Stat(); err == nil {
+		os.Chtimes(path+".gz", info.ModTime(), info.ModTime())
+	}
+	return os.Remove(path)
+}
+
+// fileExists reports whether a file exists at path.
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,225 @@
+package logger
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// backupFiles returns the names of rotated files next to path.
+func backupFiles(t *testing.T, path string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatalf("ReadDir: %v", err)
+	}
+	ext := filepath.Ext(path)
+	base := filepath.Base(strings.TrimSuffix(path, ext))
+	var names []string
+	for _, entry := range entries {
+		if isBackupName(entry.Name(), base, ext) {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	return string(data)
+}
+
+func writeLine(t *testing.T, r *rotatingFile, line string) {
+	t.Helper()
+	if _, err := r.Write([]byte(line)); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+}
+
+func TestRotatingFileRotatesBySize(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	r, err := openRotatingFile(path, RotationOptions{MaxSize: 10})
+	if err != nil {
+		t.Fatalf("openRotatingFile: %v", err)
+	}
+
+	writeLine(t, r, "first\n")
+	writeLine(t, r, "second\n") // 13 bytes would exceed MaxSize
+	if err := r.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	if got := readFile(t, path); got != "second\n" {
+		t.Errorf("current file = %q, want %q", got, "second\n")
+	}
+	backups := backupFiles(t, path)
+	if len(backups) != 1 || !strings.HasSuffix(backups[0], ".log") {
+		t.Fatalf("backups = %v, want one uncompressed backup", backups)
+	}
+	if got := readFile(t, filepath.Join(filepath.Dir(path), backups[0])); got != "first\n" {
+		t.Errorf("backup = %q, want %q", got, "first\n")
+	}
+}
+
+func TestRotatingFileCompressesBackup(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	r, err := openRotatingFile(path, RotationOptions{MaxSize: 10, Compress: true})
+	if err != nil {
+		t.Fatalf("openRotatingFile: %v", err)
+	}
+
+	writeLine(t, r, "first\n")
+	writeLine(t, r, "second\n")
+	if err := r.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	backups := backupFiles(t, path)
+	if len(backups) != 1 || !strings.HasSuffix(backups[0], ".log.gz") {
+		t.Fatalf("backups = %v, want only the compressed backup", backups)
+	}
+
+	f, err := os.Open(filepath.Join(filepath.Dir(path), backups[0]))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatalf("gzip.NewReader: %v", err)
+	}
+	data, err := io.ReadAll(gz)
+	if err != nil {
+		t.Fatalf("read gzip: %v", err)
+	}
+	if string(data) != "first\n" {
+		t.Errorf("decompressed backup = %q, want %q", data, "first\n")
+	}
+}
+
+func TestRotatingFileRotatesContentFromPreviousInterval(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	if err := os.WriteFile(path, []byte("yesterday\n"), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	old := time.Now().Add(-48 * time.Hour)
+	if err := os.Chtimes(path, old, old); err != nil {
+		t.Fatalf("Chtimes: %v", err)
+	}
+
+	r, err := openRotatingFile(path, RotationOptions{Interval: 24 * time.Hour})
+	if err != nil {
+		t.Fatalf("openRotatingFile: %v", err)
+	}
+	writeLine(t, r, "today\n")
+	if err := r.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	if got := readFile(t, path); got != "today\n" {
+		t.Errorf("current file = %q, want %q", got, "today\n")
+	}
+	if backups := backupFiles(t, path); len(backups) != 1 {
+		t.Errorf("backups = %v, want the reopened file rotated", backups)
+	}
+}
+
+func TestRotatingFilePrune(t *testing.T) {
+	unrelated := []string{"app-audit.log", "app-old.log", "app-20240101T000000.txt", "app-20240101T000000.x.log", "other.log"}
+
+	tests := []struct {
+		name string
+		opts RotationOptions
+		want []string
+	}{
+		{
+			name: "max backups",
+			opts: RotationOptions{MaxBackups: 2},
+			want: []string{"app-20240103T000000.1.log", "app-20240103T000000.log"},
+		},
+		{
+			name: "max age",
+			opts: RotationOptions{MaxAge: 36 * time.Hour},
+			want: []string{"app-20240102T000000.log.gz", "app-20240103T000000.1.log", "app-20240103T000000.log"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			now := time.Now()
+			files := map[string]time.Time{
+				"app-20240101T000000.log":    now.Add(-72 * time.Hour),
+				"app-20240102T000000.log.gz": now.Add(-24 * time.Hour),
+				"app-20240103T000000.log":    now.Add(-2 * time.Hour),
+				"app-20240103T000000.1.log":  now.Add(-1 * time.Hour),
+			}
+			// Unrelated files are the oldest, so they would be removed first if matched
+			for _, name := range unrelated {
+				files[name] = now.Add(-100 * time.Hour)
+			}
+			for name, mtime := range files {
+				path := filepath.Join(dir, name)
+				if err := os.WriteFile(path, []byte("x"), 0644); err != nil {
+					t.Fatalf("WriteFile: %v", err)
+				}
+				if err := os.Chtimes(path, mtime, mtime); err != nil {
+					t.Fatalf("Chtimes: %v", err)
+				}
+			}
+
+			r := &rotatingFile{path: filepath.Join(dir, "app.log"), opts: tt.opts}
+			if err := r.prune(); err != nil {
+				t.Fatalf("prune: %v", err)
+			}
+
+			if got := backupFiles(t, r.path); strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("backups = %v, want %v", got, tt.want)
+			}
+			for _, name := range unrelated {
+				if !fileExists(filepath.Join(dir, name)) {
+					t.Errorf("unrelated file %s was removed", name)
+				}
+			}
+		})
+	}
+}
+
+func TestRotatingFileReopenAfterExternalRename(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	r, err := openRotatingFile(path, RotationOptions{})
+	if err != nil {
+		t.Fatalf("openRotatingFile: %v", err)
+	}
+
+	writeLine(t, r, "before\n")
+	moved := filepath.Join(dir, "app.log.1")
+	if err := os.Rename(path, moved); err != nil {
+		t.Fatalf("Rename: %v", err)
+	}
+	if err := r.Reopen(); err != nil {
+		t.Fatalf("Reopen: %v", err)
+	}
+	writeLine(t, r, "after\n")
+	if err := r.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	if got := readFile(t, moved); got != "before\n" {
+		t.Errorf("moved file = %q, want %q", got, "before\n")
+	}
+	if got := readFile(t, path); got != "after\n" {
+		t.Errorf("reopened file = %q, want %q", got, "after\n")
+	}
+}
@@ -0,0 +1,203 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// sampleSummaryMessages is the maximum number of messages listed in a sampling summary.
+const sampleSummaryMessages = 10
+
+// SampleOptions configures SampleHandler. Within every interval the first First
+// records with the same message pass, then every Thereafter-th of them. Records
+// of level Error and above are never sampled.
+type SampleOptions struct {
+	Interval   time.Duration // Sampling interval; 0 disables sampling
+	First      int           // Records per message passed in each interval before sampling starts
+	Thereafter int           // Every Thereafter-th record passes after First; 0 drops the rest of the interval
+}
+
+// sampler is the sampling state shared by a SampleHandler and the handlers derived from it.
+type sampler struct {
+	opts SampleOptions
+	root slog.Handler // Handler the summary is written to, without attributes and groups
+
+	mu         sync.Mutex
+	start      time.Time
+	counts     map[string]int // Records per message in the current interval
+	suppressed map[string]int // Suppressed records per message in the current interval
+	timer      *time.Timer    // Writes the summary when the interval is over and no record arrives
+}
+
+// SampleHandler limits the rate of repeated messages passed to the wrapped handler.
+// When an interval with suppressed records is over, a summary record with their
+// counts is written before the next record or, if none arrives, by a timer. Flush
+// writes the summary of the current interval, e.g. before the handlers are closed.
+type SampleHandler struct {
+	next    slog.Handler
+	sampler *sampler
+}
+
+// NewSampleHandler wraps next with sampling. A zero interval returns next unchanged.
+func NewSampleHandler(next slog.Handler, opts SampleOptions) slog.Handler {
+	if opts.Interval <= 0 {
+		return next
+	}
+	return &SampleHandler{
+		next: next,
+		sampler: &sampler{
+			opts:       opts,
+			root:       next,
+			counts:     make(map[string]int),
+			suppressed: make(map[string]int),
+		},
+	}
+}
+
+// Enabled reports whether the wrapped handler handles records of the level.
+func (h *SampleHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+// Handle passes the record to the wrapped handler unless it is sampled out.
+func (h *SampleHandler) Handle(ctx context.Context, record slog.Record) error {
+	pass, summary := h.sampler.sample(record)
+	if summary != nil {
+		// A failed summary must not drop the record itself
+		_ = h.sampler.root.Handle(ctx, *summary)
+	}
+	if !pass {
+		return nil
+	}
+	return h.next.Handle(ctx, record)
+}
+
+// WithAttrs adds attrs to the wrapped handler; the sampling state is shared.
+func (h *SampleHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &SampleHandler{next: h.next.WithAttrs(attrs), sampler: h.sampler}
+}
+
+// WithGroup opens the group in the wrapped handler; the sampling state is shared.
+func (h *SampleHandler) WithGroup(name string) slog.Handler {
+	return &SampleHandler{next: h.next.WithGroup(name), sampler: h.sampler}
+}
+
+// Unwrap returns the wrapped handler.
+func (h *SampleHandler) Unwrap() slog.Handler {
+	return h.next
+}
+
+// Flush writes the summary of the records suppressed in the current interval, if
+// any, and starts a new interval.
+func (h *SampleHandler) Flush() {
+	h.sampler.flush(true)
+}
+
+// sample reports whether the record passes, and returns the summary of the previous
+// interval if it is over and had suppressed records.
+func (s *sampler) sample(record slog.Record) (bool, *slog.Record) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := record.Time
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	var summary *slog.Record
+	if now.Sub(s.start) >= s.opts.Interval {
+		summary = s.rotate(now)
+	}
+
+	if record.Level >= slog.LevelError {
+		return true, summary
+	}
+
+	s.counts[record.Message]++
+	n := s.counts[record.Message]
+	if n <= s.opts.First || (s.opts.Thereafter > 0 && (n-s.opts.First)%s.opts.Thereafter == 0) {
+		return true, summary
+	}
+	if len(s.suppressed) == 0 && s.timer == nil {
+		s.timer = time.AfterFunc(time.Until(s.start.Add(s.opts.Interval)), func() { s.flush(false) })
+	}
+	s.suppressed[record.Message]++
+	return false, summary
+}
+
+// flush writes the summary of the current interval to the root handler if the
+// interval is over or force is set. It is called by the timer armed at the first
+// suppressed record of an interval, which is armed again while the interval lasts.
+func (s *sampler) flush(force bool) {
+	s.mu.Lock()
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+	now := time.Now()
+	var summary *slog.Record
+	if force || now.Sub(s.start) >= s.opts.Interval {
+		summary = s.rotate(now)
+	} else if len(s.suppressed) > 0 {
+		s.timer = time.AfterFunc(s.start.Add(s.opts.Interval).Sub(now), func() { s.flush(false) })
+	}
+	s.mu.Unlock()
+
+	if summary != nil {
+		_ = s.root.Handle(context.Background(), *summary)
+	}
+}
+
+// rotate starts a new interval at now and returns the summary of the previous one.
+// The caller must hold the lock.
+func (s *sampler) rotate(now time.Time) *slog.Record {
+	summary := s.summary(now)
+	s.start = now
+	clear(s.counts)
+	clear(s.suppressed)
+	return summary
+}
+
+// summary returns a record with the suppressed counts of the current interval, or
+// nil if nothing was suppressed. Only the most suppressed messages are listed, as a
+// group keyed by rank from 1 whose entries hold the message under "msg" and its
+// count under "count", so that messages are redacted like any other value.
+func (s *sampler) summary(now time.Time) *slog.Record {
+	if len(s.suppressed) == 0 {
+		return nil
+	}
+
+	messages := make([]string, 0, len(s.suppressed))
+	total := 0
+	for message, n := range s.suppressed {
+		messages = append(messages, message)
+		total += n
+	}
+	sort.Slice(messages, func(i, j int) bool {
+		if s.suppressed[messages[i]] != s.suppressed[messages[j]] {
+			return s.suppressed[messages[i]] > s.suppressed[messages[j]]
+		}
+		return messages[i] < messages[j]
+	})
+	if len(messages) > sampleSummaryMessages {
+		messages = messages[:sampleSummaryMessages]
+	}
+
+	counts := make([]slog.Attr, len(messages))
+	for i, message := range messages {
+		counts[i] = slog.Group(strconv.Itoa(i+1), slog.String("msg", message), slog.Int("count", s.suppressed[message]))
+	}
+
+	record := slog.NewRecord(now, slog.LevelInfo, "log records suppressed by sampling", 0)
+	record.AddAttrs(
+		slog.Int("suppressed", total),
+		slog.Int("suppressed_messages", len(s.suppressed)),
+		slog.Duration("interval", now.Sub(s.start)),
+		slog.Attr{Key: "messages", Value: slog.GroupValue(counts...)},
+	)
+	return &record
+}
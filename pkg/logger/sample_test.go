@@ -0,0 +1,144 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+)
+
+// captureHandler keeps the records it handles.
+type captureHandler struct {
+	mu      sync.Mutex
+	records []slog.Record
+}
+
+func (h *captureHandler) Enabled(context.Context, slog.Level) bool { return true }
+
+func (h *captureHandler) Handle(_ context.Context, record slog.Record) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.records = append(h.records, record.Clone())
+	return nil
+}
+
+func (h *captureHandler) WithAttrs([]slog.Attr) slog.Handler { return h }
+
+func (h *captureHandler) WithGroup(string) slog.Handler { return h }
+
+// summaries returns the sampling summaries handled so far.
+func (h *captureHandler) summaries() []slog.Record {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var summaries []slog.Record
+	for _, record := range h.records {
+		if record.Message == "log records suppressed by sampling" {
+			summaries = append(summaries, record)
+		}
+	}
+	return summaries
+}
+
+// summaryMessages returns the listed messages of a summary with their counts.
+func summaryMessages(t *testing.T, summary slog.Record) map[string]int64 {
+	t.Helper()
+	counts := make(map[string]int64)
+	summary.Attrs(func(attr slog.Attr) bool {
+		if attr.Key != "messages" {
+			return true
+		}
+		for _, entry := range attr.Value.Group() {
+			var (
+				msg   string
+				count int64
+			)
+			for _, field := range entry.Value.Group() {
+				switch field.Key {
+				case "msg":
+					msg = field.Value.String()
+				case "count":
+					count = field.Value.Int64()
+				default:
+					t.Errorf("unexpected summary field %q", field.Key)
+				}
+			}
+			counts[msg] = count
+		}
+		return false
+	})
+	return counts
+}
+
+func TestSampleSummaryListsMessagesAsValues(t *testing.T) {
+	capture := &captureHandler{}
+	redactor, err := NewRedactor(nil, []string{"email"})
+	if err != nil {
+		t.Fatalf("NewRedactor: %v", err)
+	}
+	handler := NewSampleHandler(NewRedactHandler(capture, redactor), SampleOptions{Interval: time.Hour, First: 1})
+	logger := slog.New(handler)
+
+	for i := 0; i < 3; i++ {
+		logger.Info("login by user@example.com")
+	}
+	handler.(*SampleHandler).Flush()
+
+	summaries := capture.summaries()
+	if len(summaries) != 1 {
+		t.Fatalf("got %d summaries, want 1", len(summaries))
+	}
+	got := summaryMessages(t, summaries[0])
+	if want := "login by " + Redacted; got[want] != 2 || len(got) != 1 {
+		t.Errorf("summary messages = %v, want {%q: 2}", got, want)
+	}
+}
+
+func TestSampleSummaryWrittenByTimer(t *testing.T) {
+	capture := &captureHandler{}
+	logger := slog.New(NewSampleHandler(capture, SampleOptions{Interval: 50 * time.Millisecond, First: 1}))
+
+	for i := 0; i < 5; i++ {
+		logger.Info("repeated")
+	}
+
+	// No record follows the burst, so the summary is written by the timer
+	deadline := time.Now().Add(2 * time.Second)
+	for len(capture.summaries()) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("summary was not written after the interval")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := summaryMessages(t, capture.summaries()[0]); got["repeated"] != 4 {
+		t.Errorf("summary messages = %v, want {repeated: 4}", got)
+	}
+
+	// A later record does not repeat the summary
+	time.Sleep(60 * time.Millisecond)
+	logger.Info("another")
+	if n := len(capture.summaries()); n != 1 {
+		t.Errorf("got %d summaries, want 1", n)
+	}
+}
+
+func TestMultiHandlerCloseAllFlushesSummary(t *testing.T) {
+	capture := &captureHandler{}
+	multi := NewMultiHandler(capture)
+	handler := NewSampleHandler(multi, SampleOptions{Interval: time.Hour, First: 1})
+	multi.onClose = append(multi.onClose, handler.(*SampleHandler).Flush)
+
+	logger := slog.New(handler)
+	logger.Info("repeated")
+	logger.Info("repeated")
+	if n := len(capture.summaries()); n != 0 {
+		t.Fatalf("got %d summaries before close, want 0", n)
+	}
+
+	if err := multi.CloseAll(); err != nil {
+		t.Fatalf("CloseAll: %v", err)
+	}
+	if n := len(capture.summaries()); n != 1 {
+		t.Fatalf("got %d summaries after close, want 1", n)
+	}
+}
@@ -0,0 +1,392 @@
+package logger
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/IBM/sarama"
+)
+
+const (
+	spoolReplayInterval = 5 * time.Second // How often reconnection and replay are attempted
+	spoolReplayBatch    = 500             // Records sent to Kafka per replay batch
+)
+
+// errSpoolFull is returned when a record does not fit into the spool.
+var errSpoolFull = errors.New("spool is full")
+
+// SpoolOptions configures the on-disk spool used while Kafka is unavailable.
+type SpoolOptions struct {
+	Dir     string // Directory of the spool files
+	MaxSize int64  // Maximum size in bytes of records not replayed yet; records beyond it are dropped
+}
+
+// spool is an on-disk FIFO of newline-terminated records bounded by the size of
+// records not replayed yet. Records are appended to a single file and read back
+// from a replay offset that is persisted next to it, so pending records survive a
+// restart. Once every record has been replayed the file is truncated; once the
+// replayed prefix reaches half of the limit while records keep arriving, the
+// unreplayed tail is rewritten into a new file, so the file stays below 1.5 times
+// the limit.
+type spool struct {
+	mu         sync.Mutex
+	file       *os.File
+	path       string
+	offsetPath string
+	maxBytes   int64
+	size       int64 // Bytes in the file
+	offset     int64 // Bytes already replayed
+	records    int64 // Records not replayed yet
+	dropped    uint64
+}
+
+// openSpool opens the spool in dir, creating it if necessary, and restores the
+// replay offset of a previous run.
+func openSpool(dir string, maxBytes int64) (*spool, error) {
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return nil, err
+	}
+
+	path := filepath.Join(dir, "kafka.spool")
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	s := &spool{
+		file:       file,
+		path:       path,
+		offsetPath: filepath.Join(dir, "kafka.offset"),
+		maxBytes:   maxBytes,
+		size:       info.Size(),
+	}
+	if data, err := os.ReadFile(s.offsetPath); err == nil {
+		if offset, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64); err == nil && offset >= 0 && offset <= s.size {
+			s.offset = offset
+		}
+	}
+	if err := s.recover(); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// recover counts the records after the replay offset and truncates a partial
+// record left by a crash in the middle of a write.
+func (s *spool) recover() error {
+	position, complete := s.offset, s.offset
+	reader := bufio.NewReader(io.NewSectionReader(s.file, s.offset, s.size-s.offset))
+	for {
+		line, err := reader.ReadSlice('\n')
+		position += int64(len(line))
+		if err == nil {
+			s.records++
+			complete = position
+			continue
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if !errors.Is(err, bufio.ErrBufferFull) {
+			return err
+		}
+	}
+
+	if complete < s.size {
+		if err := s.file.Truncate(complete); err != nil {
+			return fmt.Errorf("failed to truncate partial spool record: %w", err)
+		}
+		s.size = complete
+	}
+	return nil
+}
+
+// append adds a record to the end of the spool. The record must not contain newlines.
+func (s *spool) append(record []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := int64(len(record)) + 1
+	if s.size-s.offset+n > s.maxBytes {
+		s.dropped++
+		return errSpoolFull
+	}
+	if _, err := s.file.Write(append(record, '\n')); err != nil {
+		s.dropped++
+		return err
+	}
+	s.size += n
+	s.records++
+	return nil
+}
+
+// pending reports whether the spool holds records that have not been replayed.
+// While it does, new records must be appended to keep them in order.
+func (s *spool) pending() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.records > 0
+}
+
+// peek returns up to max records after the replay offset and the offset just past them.
+func (s *spool) peek(max int) ([][]byte, int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var records [][]byte
+	end := s.offset
+	reader := bufio.NewReader(io.NewSectionReader(s.file, s.offset, s.size-s.offset))
+	for len(records) < max {
+		line, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			// A partial last line is left for the next call
+			break
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+		end += int64(len(line))
+		records = append(records, bytes.TrimSuffix(line, []byte{'\n'}))
+	}
+	return records, end, nil
+}
+
+// commit marks records up to end as replayed. When nothing is left the spool is
+// truncated, and when the replayed prefix is large the unreplayed tail is compacted.
+// commit, peek and compact are called only by the replay goroutine.
+func (s *spool) commit(end int64, replayed int) error {
+	s.mu.Lock()
+	s.offset = end
+	s.records -= int64(replayed)
+	if s.offset >= s.size {
+		defer s.mu.Unlock()
+		if err := s.file.Truncate(0); err != nil {
+			return fmt.Errorf("failed to truncate spool: %w", err)
+		}
+		s.size, s.offset, s.records = 0, 0, 0
+		return s.writeOffset()
+	}
+	compact := s.offset >= s.maxBytes/2
+	s.mu.Unlock()
+
+	if compact {
+		return s.compact()
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.writeOffset()
+}
+
+// compact rewrites the records after the replay offset into a new spool file. The
+// bulk of the tail is copied without the lock, so appends are blocked only while the
+// records appended meanwhile are copied and the files are swapped. The offset is
+// reset before the new file replaces the old one: a crash in between replays the
+// old file from the start again, which only duplicates records.
+func (s *spool) compact() error {
+	s.mu.Lock()
+	start, end := s.offset, s.size
+	s.mu.Unlock()
+
+	tmpPath := s.path + ".tmp"
+	tmp, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_RDWR|os.O_TRUNC|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to create compacted spool: %w", err)
+	}
+	fail := func(err error) error {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to compact spool: %w", err)
+	}
+	if _, err := io.Copy(tmp, io.NewSectionReader(s.file, start, end-start)); err != nil {
+		return fail(err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := io.Copy(tmp, io.NewSectionReader(s.file, end, s.size-end)); err != nil {
+		return fail(err)
+	}
+	if err := tmp.Sync(); err != nil {
+		return fail(err)
+	}
+	offset := s.offset
+	s.offset = 0
+	if err := s.writeOffset(); err != nil {
+		s.offset = offset
+		return fail(err)
+	}
+	if err := os.Rename(tmpPath, s.path); err != nil {
+		// The old file is kept with its replay offset
+		s.offset = offset
+		s.writeOffset()
+		return fail(err)
+	}
+
+	s.file.Close()
+	s.file = tmp
+	s.size -= start
+	return nil
+}
+
+// writeOffset persists the replay offset. The caller must hold the lock.
+func (s *spool) writeOffset() error {
+	return os.WriteFile(s.offsetPath, []byte(strconv.FormatInt(s.offset, 10)), 0644)
+}
+
+// stats returns the number and size of records not replayed yet and the number
+// of records dropped because the spool was full.
+func (s *spool) stats() (records, size int64, dropped uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.records, s.size - s.offset, s.dropped
+}
+
+// Close closes the spool file. Pending records stay on disk for the next run.
+func (s *spool) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.file.Close()
+}
+
+// spoolRecord is a Kafka message stored in the spool.
+type spoolRecord struct {
+	Key     string            `json:"key,omitempty"`
+	Value   json.RawMessage   `json:"value"`
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+// spoolMessage appends the message to the spool.
+func (k *kafkaSink) spoolMessage(message *sarama.ProducerMessage) {
+	record := spoolRecord{Headers: make(map[string]string, len(message.Headers))}
+	if message.Key != nil {
+		key, _ := message.Key.Encode()
+		record.Key = string(key)
+	}
+	if message.Value != nil {
+		record.Value, _ = message.Value.Encode()
+	}
+	for _, header := range message.Headers {
+		record.Headers[string(header.Key)] = string(header.Value)
+	}
+
+	line, err := json.Marshal(record)
+	if err != nil {
+		fmt.Printf("failed to marshal spool record: %v\n", err)
+		return
+	}
+	if err := k.spool.append(line); err != nil && !errors.Is(err, errSpoolFull) {
+		fmt.Printf("failed to write spool record: %v\n", err)
+	}
+}
+
+// replayLoop periodically reconnects to Kafka and replays the spool until Close.
+func (k *kafkaSink) replayLoop() {
+	defer k.wg.Done()
+
+	var replayer sarama.SyncProducer
+	defer func() {
+		if replayer != nil {
+			replayer.Close()
+		}
+	}()
+
+	ticker := time.NewTicker(spoolReplayInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			replayer = k.replay(replayer)
+		case <-k.quitChan:
+			return
+		}
+	}
+}
+
+// replay reconnects the producer if needed and sends spooled records in order using
+// a synchronous producer, so that a batch is removed from the spool only once Kafka
+// has acknowledged it. A batch that fails partway is sent again, so records may be
+// delivered more than once. Once the spool is empty new records are passed to the
+// producer directly again. It returns the synchronous producer to reuse next time.
+func (k *kafkaSink) replay(replayer sarama.SyncProducer) sarama.SyncProducer {
+	if k.activeProducer() == nil {
+		producer, err := sarama.NewAsyncProducer(k.brokers, k.saramaCfg)
+		if err != nil {
+			return replayer
+		}
+		k.startProducer(producer)
+		fmt.Println("kafka is available again")
+	}
+
+	for k.spool.pending() {
+		select {
+		case <-k.quitChan:
+			return replayer
+		default:
+		}
+
+		if replayer == nil {
+			// Same connection settings as the asynchronous producer, with acknowledgements returned
+			config := *k.saramaCfg
+			config.Producer.Return.Successes = true
+
+			var err error
+			if replayer, err = sarama.NewSyncProducer(k.brokers, &config); err != nil {
+				return nil
+			}
+		}
+
+		lines, end, err := k.spool.peek(spoolReplayBatch)
+		if err != nil {
+			fmt.Printf("failed to read log spool: %v\n", err)
+			return replayer
+		}
+
+		messages := make([]*sarama.ProducerMessage, 0, len(lines))
+		for _, line := range lines {
+			var record spoolRecord
+			if err := json.Unmarshal(line, &record); err != nil {
+				fmt.Printf("skipping malformed spool record: %v\n", err)
+				continue
+			}
+			message := &sarama.ProducerMessage{
+				Topic: k.topic,
+				Value: sarama.ByteEncoder(record.Value),
+			}
+			if record.Key != "" {
+				message.Key = sarama.StringEncoder(record.Key)
+			}
+			for key, value := range record.Headers {
+				message.Headers = append(message.Headers, sarama.RecordHeader{Key: []byte(key), Value: []byte(value)})
+			}
+			messages = append(messages, message)
+		}
+
+		if err := replayer.SendMessages(messages); err != nil {
+			fmt.Printf("failed to replay log spool: %v\n", err)
+			replayer.Close()
+			return nil
+		}
+		if err := k.spool.commit(end, len(lines)); err != nil {
+			fmt.Printf("failed to commit log spool: %v\n", err)
+			return replayer
+		}
+	}
+	k.resumeDirectSends()
+	return replayer
+}
@@ -0,0 +1,155 @@
+package logger
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestSpoolLimitCountsOnlyPendingRecords(t *testing.T) {
+	s, err := openSpool(t.TempDir(), 100)
+	if err != nil {
+		t.Fatalf("openSpool: %v", err)
+	}
+	defer s.Close()
+
+	record := []byte("0123456789abcdefghi") // 20 bytes with the newline
+	for i := 0; i < 5; i++ {
+		if err := s.append(record); err != nil {
+			t.Fatalf("append %d: %v", i, err)
+		}
+	}
+	if err := s.append(record); !errors.Is(err, errSpoolFull) {
+		t.Fatalf("append to a full spool = %v, want errSpoolFull", err)
+	}
+
+	// After replaying two records there is room for two more
+	records, end, err := s.peek(2)
+	if err != nil || len(records) != 2 {
+		t.Fatalf("peek = %d records, %v", len(records), err)
+	}
+	if err := s.commit(end, len(records)); err != nil {
+		t.Fatalf("commit: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := s.append(record); err != nil {
+			t.Fatalf("append after replay %d: %v", i, err)
+		}
+	}
+	if err := s.append(record); !errors.Is(err, errSpoolFull) {
+		t.Fatalf("append to a full spool = %v, want errSpoolFull", err)
+	}
+	if _, _, dropped := s.stats(); dropped != 2 {
+		t.Errorf("dropped = %d, want 2", dropped)
+	}
+}
+
+func TestSpoolAppendWhileReplaying(t *testing.T) {
+	const (
+		maxBytes = 4 << 10
+		total    = 5000
+	)
+	dir := t.TempDir()
+	s, err := openSpool(dir, maxBytes)
+	if err != nil {
+		t.Fatalf("openSpool: %v", err)
+	}
+
+	// The writer retries full appends, so every record eventually gets into the spool
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < total; {
+			err := s.append([]byte(fmt.Sprintf("record-%06d", i)))
+			if errors.Is(err, errSpoolFull) {
+				continue
+			}
+			if err != nil {
+				t.Errorf("append %d: %v", i, err)
+				return
+			}
+			i++
+		}
+	}()
+
+	next := 0
+	maxFileSize := int64(0)
+	for next < total {
+		records, end, err := s.peek(7)
+		if err != nil {
+			t.Fatalf("peek: %v", err)
+		}
+		for _, record := range records {
+			if want := fmt.Sprintf("record-%06d", next); string(record) != want {
+				t.Fatalf("replayed %q, want %q", record, want)
+			}
+			next++
+		}
+		if err := s.commit(end, len(records)); err != nil {
+			t.Fatalf("commit: %v", err)
+		}
+
+		info, err := os.Stat(filepath.Join(dir, "kafka.spool"))
+		if err != nil {
+			t.Fatalf("stat spool: %v", err)
+		}
+		maxFileSize = max(maxFileSize, info.Size())
+	}
+	wg.Wait()
+
+	// The replayed prefix is compacted, so the file never grows far past the limit
+	if limit := int64(maxBytes * 3 / 2); maxFileSize > limit+20 {
+		t.Errorf("spool file grew to %d bytes, want at most about %d", maxFileSize, limit)
+	}
+	if records, size, _ := s.stats(); records != 0 || size != 0 {
+		t.Errorf("stats after replay = %d records, %d bytes; want empty", records, size)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+}
+
+func TestSpoolReopensAfterCompaction(t *testing.T) {
+	dir := t.TempDir()
+	s, err := openSpool(dir, 100)
+	if err != nil {
+		t.Fatalf("openSpool: %v", err)
+	}
+	for i := 0; i < 10; i++ {
+		if err := s.append([]byte("record-" + strconv.Itoa(i))); err != nil {
+			t.Fatalf("append %d: %v", i, err)
+		}
+	}
+	// Replaying most of the records compacts the file, leaving the rest pending
+	records, end, err := s.peek(8)
+	if err != nil {
+		t.Fatalf("peek: %v", err)
+	}
+	if err := s.commit(end, len(records)); err != nil {
+		t.Fatalf("commit: %v", err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	s, err = openSpool(dir, 100)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer s.Close()
+	records, _, err = s.peek(10)
+	if err != nil {
+		t.Fatalf("peek after reopen: %v", err)
+	}
+	if len(records) != 2 || string(records[0]) != "record-8" || string(records[1]) != "record-9" {
+		t.Fatalf("pending after reopen = %q, want record-8, record-9", records)
+	}
+	if s.offset != 0 {
+		t.Errorf("offset after compaction = %d, want 0", s.offset)
+	}
+}
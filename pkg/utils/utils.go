@@ -2,19 +2,34 @@ package utils
 
 import (
 	"fmt"
+	"strings"
+
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 
 	"github.com/watchlist-kata/review/internal/config"
 )
 
-// ConnectToDatabase устанавливает подключение к базе данных PostgreSQL
+// ConnectToDatabase устанавливает подключение к базе данных PostgreSQL.
+// DATABASE_URL, если задан, используется вместо отдельных параметров DB_*.
 func ConnectToDatabase(cfg *config.Config) (*gorm.DB, error) {
-	dsn := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
-		cfg.DBHost, cfg.DBPort, cfg.DBUser, cfg.DBPassword, cfg.DBName, cfg.DBSSLMode)
+	dsn := cfg.DatabaseURL
+	if dsn == "" {
+		dsn = fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
+			cfg.DBHost, cfg.DBPort, cfg.DBUser, quoteDSNValue(cfg.DBPassword), cfg.DBName, cfg.DBSSLMode)
+	}
 	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
 	return db, nil
 }
+
+// quoteDSNValue экранирует значение DSN формата key=value, если оно пустое
+// или содержит пробелы, кавычки или обратную косую черту
+func quoteDSNValue(value string) string {
+	if value != "" && !strings.ContainsAny(value, " '\\") {
+		return value
+	}
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(value) + "'"
+}